@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	nested "github.com/antonfisher/nested-logrus-formatter"
+	foundeationDB "github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.elastic.co/ecslogrus"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/account_manager"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	migrations "github.com/lueurxax/crypto-tweet-sense/internal/repo/migrationFtoR"
+	repo "github.com/lueurxax/crypto-tweet-sense/internal/repo/redis"
+	tweetFinder "github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+var version = "dev"
+
+const (
+	foundationDBVersion = 710
+	pkgKey              = "pkg"
+	timeFormat          = "2006-01-02 15:04"
+
+	usage = `usage: accounts [flags] <command> [login]
+
+commands:
+  list            accounts with the status, cookie age and limiter state
+  enable <login>  make the disabled account active again
+  disable <login> take the account out of rotation
+  remove <login>  delete the account with its cookies
+  test <login>    log the account in and print the resulting status
+  import          add the accounts from -file
+  export          write the accounts with the credentials and cookies to -file
+
+flags:
+`
+)
+
+var (
+	errNoLogin        = errors.New("the command requires the login")
+	errUnknownCommand = errors.New("unknown command")
+)
+
+type config struct {
+	LoggerLevel  logrus.Level `envconfig:"LOG_LEVEL" default:"info"`
+	LogToEcs     bool         `envconfig:"LOG_TO_ECS" default:"false"`
+	DatabasePath string       `default:"/usr/local/etc/foundationdb/fdb.cluster"`
+	RedisAddress string       `envconfig:"REDIS_ADDRESS" default:"localhost:6379"`
+}
+
+func main() {
+	printVersion := flag.Bool("version", false, "print version and exit")
+	format := flag.String("format", account_manager.FormatJSON, "import and export format, json or csv")
+	file := flag.String("file", "-", "import and export file, - is stdin or stdout")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *printVersion {
+		fmt.Println(version)
+		return
+	}
+
+	// init main config
+	cfg := new(config)
+	if err := envconfig.Process("", cfg); err != nil {
+		panic(err)
+	}
+
+	// init logger
+	logrusLogger := logrus.New()
+	logrusLogger.SetLevel(cfg.LoggerLevel)
+	logrusLogger.SetFormatter(&nested.Formatter{
+		FieldsOrder:     []string{pkgKey},
+		TimestampFormat: "01-02|15:04:05",
+	})
+
+	if cfg.LogToEcs {
+		logrusLogger.SetFormatter(&ecslogrus.Formatter{})
+	}
+
+	logger := log.NewLogger(logrusLogger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	foundeationDB.MustAPIVersion(foundationDBVersion)
+
+	db, err := foundeationDB.OpenDatabase(cfg.DatabasePath)
+	if err != nil {
+		panic(err)
+	}
+
+	st := fdb.NewDB(db, logrusLogger.WithField(pkgKey, "fdb"))
+
+	rdb := redis.NewClient(&redis.Options{Addr: cfg.RedisAddress})
+
+	rst, err := repo.WithEncryption(repo.NewDB(rdb, logger.WithField(pkgKey, "repo")), repo.GetEncryptionConfig())
+	if err != nil {
+		panic(err)
+	}
+
+	limitsConfig := tweetFinder.GetLimitsConfig()
+
+	limits, err := tweetFinder.SelectLimitsRepo[tweetFinder.RequestLimitsRepo](limitsConfig, st, rst)
+	if err != nil {
+		panic(err)
+	}
+
+	if limitsConfig.Storage == tweetFinder.StorageRedis {
+		copied, err := migrations.InitRequestLimits(ctx, fdbclient.NewDatabase(db), rdb)
+		if err != nil {
+			panic(err)
+		}
+
+		if copied > 0 {
+			logger.WithField("limits", copied).Info("request limits copied to redis")
+		}
+	}
+
+	admin := account_manager.NewAdmin(
+		account_manager.GetHealthConfig(),
+		rst,
+		limits,
+		tweetFinder.LimiterIntervals,
+		logger.WithField(pkgKey, "account_admin"),
+	)
+
+	if err = run(ctx, admin, flag.Args(), *format, *file); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, admin account_manager.Admin, args []string, format, file string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		return errUnknownCommand
+	}
+
+	command, login := args[0], ""
+	if len(args) > 1 {
+		login = args[1]
+	}
+
+	switch command {
+	case "list":
+		accounts, err := admin.List(ctx)
+		if err != nil {
+			return err
+		}
+
+		return printAccounts(os.Stdout, accounts)
+	case "import":
+		return importAccounts(ctx, admin, format, file)
+	case "export":
+		return exportAccounts(ctx, admin, format, file)
+	}
+
+	if login == "" {
+		return fmt.Errorf("%w: %s", errNoLogin, command)
+	}
+
+	switch command {
+	case "enable":
+		return admin.Enable(ctx, login)
+	case "disable":
+		return admin.Disable(ctx, login)
+	case "remove":
+		return admin.Remove(ctx, login)
+	case "test":
+		account, err := admin.Test(ctx, login)
+		fmt.Printf("%s: %s %s\n", account.Login, account.CurrentStatus(), account.StatusReason)
+
+		return err
+	default:
+		return fmt.Errorf("%w: %s", errUnknownCommand, command)
+	}
+}
+
+func importAccounts(ctx context.Context, admin account_manager.Admin, format, file string) error {
+	var r io.Reader = os.Stdin
+
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		r = f
+	}
+
+	records, err := account_manager.ReadRecords(r, format)
+	if err != nil {
+		return err
+	}
+
+	if err = admin.Import(ctx, records); err != nil {
+		return err
+	}
+
+	fmt.Printf("imported %d accounts\n", len(records))
+
+	return nil
+}
+
+func exportAccounts(ctx context.Context, admin account_manager.Admin, format, file string) error {
+	records, err := admin.Export(ctx)
+	if err != nil {
+		return err
+	}
+
+	if file == "-" {
+		return account_manager.WriteRecords(os.Stdout, format, records)
+	}
+
+	// the export holds the passwords and cookies
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return account_manager.WriteRecords(f, format, records)
+}
+
+func printAccounts(out io.Writer, accounts []account_manager.AccountInfo) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprint(w, "LOGIN\tSTATUS\tSINCE\tRETRY AT\tCOOKIE AGE")
+
+	for _, window := range tweetFinder.LimiterIntervals {
+		fmt.Fprintf(w, "\t%s", window)
+	}
+
+	fmt.Fprintln(w, "\tREASON")
+
+	for _, account := range accounts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s",
+			account.Login,
+			account.CurrentStatus(),
+			formatTime(account.StatusChangedAt),
+			formatTime(account.RetryAt),
+			formatAge(account.CookieAge),
+		)
+
+		for _, limit := range account.Limits {
+			fmt.Fprintf(w, "\t%d/%d", limit.RequestsCount, limit.Threshold)
+		}
+
+		fmt.Fprintf(w, "\t%s\n", account.StatusReason)
+	}
+
+	return w.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format(timeFormat)
+}
+
+func formatAge(age time.Duration) string {
+	if age == 0 {
+		return "-"
+	}
+
+	return age.Truncate(time.Minute).String()
+}
@@ -54,9 +54,19 @@ func main() {
 
 	db := redis.NewClient(&redis.Options{Addr: cfg.RedisAddress})
 
-	st := repo.NewDB(db, logger.WithField(pkgKey, "repo"))
+	st, err := repo.WithEncryption(repo.NewDB(db, logger.WithField(pkgKey, "repo")), repo.GetEncryptionConfig())
+	if err != nil {
+		panic(err)
+	}
+
+	manager := account_manager.NewManager(
+		account_manager.GetHealthConfig(),
+		st,
+		account_manager.NewMetrics("", ""),
+		logger.WithField(pkgKey, "account_manager"),
+	)
 
-	if err := account_manager.NewManager(st, logger.WithField(pkgKey, "account_manager")).AddAccount(ctx, account_manager.GetConfig()); err != nil {
+	if err = manager.AddAccount(ctx, account_manager.GetConfig()); err != nil {
 		panic(err)
 	}
 
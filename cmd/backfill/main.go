@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	nested "github.com/antonfisher/nested-logrus-formatter"
+	foundeationDB "github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.elastic.co/ecslogrus"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/account_manager"
+	"github.com/lueurxax/crypto-tweet-sense/internal/backfill"
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/lease"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	migrations "github.com/lueurxax/crypto-tweet-sense/internal/repo/migrationFtoR"
+	repo "github.com/lueurxax/crypto-tweet-sense/internal/repo/redis"
+	"github.com/lueurxax/crypto-tweet-sense/internal/source"
+	tweetFinder "github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder"
+	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder/proxymanager"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+var version = "dev"
+
+const (
+	foundationDBVersion = 710
+	pkgKey              = "pkg"
+	dateFormat          = "2006-01-02"
+)
+
+type config struct {
+	LoggerLevel  logrus.Level `envconfig:"LOG_LEVEL" default:"info"`
+	LogToEcs     bool         `envconfig:"LOG_TO_ECS" default:"false"`
+	DatabasePath string       `default:"/usr/local/etc/foundationdb/fdb.cluster"`
+	RedisAddress string       `envconfig:"REDIS_ADDRESS" default:"localhost:6379"`
+}
+
+func main() {
+	printVersion := flag.Bool("version", false, "print version and exit")
+	from := flag.String("from", "", "first day of the range, "+dateFormat)
+	to := flag.String("to", time.Now().UTC().Format(dateFormat), "day after the range, "+dateFormat)
+	names := flag.String("queries", "", "comma separated names of the stored queries, all enabled queries by default")
+	flag.Parse()
+
+	if *printVersion {
+		fmt.Println(version)
+		return
+	}
+
+	since, err := time.Parse(dateFormat, *from)
+	if err != nil {
+		panic(fmt.Errorf("wrong -from: %w", err))
+	}
+
+	until, err := time.Parse(dateFormat, *to)
+	if err != nil {
+		panic(fmt.Errorf("wrong -to: %w", err))
+	}
+
+	// init main config
+	cfg := new(config)
+	if err = envconfig.Process("", cfg); err != nil {
+		panic(err)
+	}
+
+	// init logger
+	logrusLogger := logrus.New()
+	logrusLogger.SetLevel(cfg.LoggerLevel)
+	logrusLogger.SetFormatter(&nested.Formatter{
+		FieldsOrder:     []string{pkgKey},
+		TimestampFormat: "01-02|15:04:05",
+	})
+
+	if cfg.LogToEcs {
+		logrusLogger.SetFormatter(&ecslogrus.Formatter{})
+	}
+
+	logger := log.NewLogger(logrusLogger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	foundeationDB.MustAPIVersion(foundationDBVersion)
+
+	db, err := foundeationDB.OpenDatabase(cfg.DatabasePath)
+	if err != nil {
+		panic(err)
+	}
+
+	st := fdb.NewDB(db, logrusLogger.WithField(pkgKey, "fdb"))
+
+	if err = st.Migrate(ctx); err != nil {
+		panic(err)
+	}
+
+	queries, err := selectQueries(ctx, st, *names)
+	if err != nil {
+		panic(err)
+	}
+
+	rdb := redis.NewClient(&redis.Options{Addr: cfg.RedisAddress})
+	rst, err := repo.WithEncryption(repo.NewDB(rdb, logger.WithField(pkgKey, "repo")), repo.GetEncryptionConfig())
+	if err != nil {
+		panic(err)
+	}
+
+	accountManager := account_manager.NewManager(
+		account_manager.GetHealthConfig(),
+		rst,
+		account_manager.NewMetrics("", ""),
+		logger.WithField(pkgKey, "account_manager"),
+	)
+
+	// the metrics are not served, the pool requires them
+	one := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "find_requests_seconds"}, []string{"login", "error"})
+	next := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "find_next_requests_seconds"}, []string{"login", "search", "error"})
+	delay := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "delay_seconds"}, []string{"login"})
+	wait := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "wait_seconds"}, []string{"result"})
+
+	proxies := proxymanager.NewManager(
+		proxymanager.GetConfig(),
+		tweetFinder.GetConfigPool().GetProxies(),
+		rst,
+		proxymanager.NewMetrics("", ""),
+		logger.WithField(pkgKey, "proxy_manager"),
+	)
+
+	go proxies.Run(ctx)
+
+	// the backfill takes its share of the accounts from the running scrappers
+	leases := lease.NewLeaser(lease.GetConfig(), rst, logger.WithField(pkgKey, "leaser"))
+	defer func() {
+		if err := leases.Close(context.Background()); err != nil {
+			logger.WithError(err).Error("account leases release failure")
+		}
+	}()
+
+	limitsConfig := tweetFinder.GetLimitsConfig()
+
+	limits, err := tweetFinder.SelectLimitsRepo[tweetFinder.RequestLimitsRepo](limitsConfig, st, rst)
+	if err != nil {
+		panic(err)
+	}
+
+	if limitsConfig.Storage == tweetFinder.StorageRedis {
+		copied, err := migrations.InitRequestLimits(ctx, fdbclient.NewDatabase(db), rdb)
+		if err != nil {
+			panic(err)
+		}
+
+		if copied > 0 {
+			logger.WithField("limits", copied).Info("request limits copied to redis")
+		}
+	}
+
+	finder, err := tweetFinder.NewPool(
+		tweetFinder.GetSelectionConfig(),
+		limitsConfig,
+		tweetFinder.GetDelayConfig(),
+		tweetFinder.GetWarmUpConfig(),
+		one, next, wait, delay, tweetFinder.NewDelayMetrics("", ""),
+		proxies, leases, accountManager, limits,
+		logger.WithField(pkgKey, "tweet_finder_pool"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	if err = finder.Init(ctx); err != nil {
+		panic(err)
+	}
+
+	// pushed tweets are not kept, so only the pulling sources can backfill
+	sources := make(map[string]source.Source)
+
+	if sourceConfig := source.GetConfig(); sourceConfig.NitterURL != "" {
+		sources[source.Nitter] = source.NewNitter(sourceConfig.NitterURL, &http.Client{Timeout: sourceConfig.NitterTimeout})
+	}
+
+	backfiller := backfill.NewBackfiller(backfill.GetConfig(), tweetFinder.NewRouter(finder, sources), st, logger.WithField(pkgKey, "backfill"))
+
+	if err = backfiller.Run(ctx, queries, since, until); err != nil {
+		if errors.Is(err, backfill.ErrFailed) {
+			logger.WithError(err).Error("backfill finished with failed slices, run it again to retry them")
+			return
+		}
+
+		logger.WithError(err).Error("backfill interrupted, run it again to resume")
+
+		return
+	}
+
+	logger.Info("backfill finished")
+}
+
+// selectQueries returns the stored queries by names, or all enabled ones when names are empty.
+func selectQueries(ctx context.Context, st fdb.DB, names string) ([]common.SearchQuery, error) {
+	queries := make([]common.SearchQuery, 0)
+
+	if names == "" {
+		stored, err := st.GetSearchQueries(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, query := range stored {
+			if query.Enabled {
+				queries = append(queries, query)
+			}
+		}
+	}
+
+	for _, name := range strings.FieldsFunc(names, func(r rune) bool { return r == ',' }) {
+		query, err := st.GetSearchQuery(ctx, strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("query %q: %w", name, err)
+		}
+
+		queries = append(queries, query)
+	}
+
+	for _, query := range queries {
+		if err := query.Validate(); err != nil {
+			return nil, fmt.Errorf("query %q: %w", query.Name, err)
+		}
+	}
+
+	return queries, nil
+}
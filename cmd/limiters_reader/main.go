@@ -2,18 +2,32 @@ package main
 
 import (
 	"context"
+	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	nested "github.com/antonfisher/nested-logrus-formatter"
 	foundeationDB "github.com/apple/foundationdb/bindings/go/src/fdb"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 	"go.elastic.co/ecslogrus"
 
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
 	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	migrations "github.com/lueurxax/crypto-tweet-sense/internal/repo/migrationFtoR"
+	repo "github.com/lueurxax/crypto-tweet-sense/internal/repo/redis"
+	tweetFinder "github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
 )
 
 var version = "dev"
@@ -21,18 +35,48 @@ var version = "dev"
 const (
 	foundationDBVersion = 710
 	pkgKey              = "pkg"
+
+	usage = `usage: limiters_reader [flags] <command> [args]
+
+commands:
+  list                                      every account and window with the count, threshold and temperature
+  show <login> <window>                     the stored limit with its request buckets as JSON
+  reset <login> [window]                    drop the counted requests of the window or of every window
+  set-threshold <login> <window> <value>    set the threshold, lowering it too
+  raise-threshold <login> <window> <value>  raise the threshold, the higher one is kept
+  export [login] [window]                   write the request times to -file as CSV
+  copy-to-redis                             replace the limits of redis with the ones of foundationdb, run it
+                                            when RATE_LIMITS_STORAGE is switched to redis again
+
+the windows are the limiter intervals: 10m, 1h, 24h and 720h, the running scrappers
+pick the changes up within a minute
+
+flags:
+`
+)
+
+// copier copies the request limits to redis and returns their number.
+type copier func(ctx context.Context) (int, error)
+
+var (
+	errNoArgs         = errors.New("not enough arguments")
+	errUnknownCommand = errors.New("unknown command")
 )
 
 type config struct {
-	LoggerLevel  logrus.Level  `envconfig:"LOG_LEVEL" default:"info"`
-	LogToEcs     bool          `envconfig:"LOG_TO_ECS" default:"false"`
-	DatabasePath string        `default:"/usr/local/etc/foundationdb/fdb.cluster"`
-	Login        string        `envconfig:"LOGIN" required:"true"`
-	Window       time.Duration `envconfig:"WINDOW" required:"true"`
+	LoggerLevel  logrus.Level `envconfig:"LOG_LEVEL" default:"info"`
+	LogToEcs     bool         `envconfig:"LOG_TO_ECS" default:"false"`
+	DatabasePath string       `default:"/usr/local/etc/foundationdb/fdb.cluster"`
+	RedisAddress string       `envconfig:"REDIS_ADDRESS" default:"localhost:6379"`
 }
 
 func main() {
 	printVersion := flag.Bool("version", false, "print version and exit")
+	file := flag.String("file", "-", "export file, - is stdout")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
 	if *printVersion {
@@ -58,6 +102,11 @@ func main() {
 		logrusLogger.SetFormatter(&ecslogrus.Formatter{})
 	}
 
+	logger := log.NewLogger(logrusLogger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	foundeationDB.MustAPIVersion(foundationDBVersion)
 
 	db, err := foundeationDB.OpenDatabase(cfg.DatabasePath)
@@ -67,15 +116,215 @@ func main() {
 
 	st := fdb.NewDB(db, logrusLogger.WithField(pkgKey, "fdb"))
 
-	limits, err := st.GetRequestLimitDebug(context.Background(), cfg.Login, cfg.Window)
+	rdb := redis.NewClient(&redis.Options{Addr: cfg.RedisAddress})
+
+	rst, err := repo.WithEncryption(repo.NewDB(rdb, logger.WithField(pkgKey, "repo")), repo.GetEncryptionConfig())
 	if err != nil {
 		panic(err)
 	}
 
-	data, err := jsoniter.MarshalToString(limits)
+	limits, err := tweetFinder.SelectLimitsRepo[tweetFinder.LimitsAdminRepo](tweetFinder.GetLimitsConfig(), st, rst)
 	if err != nil {
 		panic(err)
 	}
 
-	fmt.Println(data)
+	admin := tweetFinder.NewLimitsAdmin(limits, rst, tweetFinder.LimiterIntervals, logger.WithField(pkgKey, "limits_admin"))
+
+	copyLimits := func(ctx context.Context) (int, error) {
+		return migrations.CopyRequestLimits(ctx, fdbclient.NewDatabase(db), rdb)
+	}
+
+	if err = run(ctx, admin, copyLimits, flag.Args(), *file); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, admin tweetFinder.LimitsAdmin, copyLimits copier, args []string, file string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		return errUnknownCommand
+	}
+
+	command, args := args[0], args[1:]
+
+	switch command {
+	case "list":
+		limits, err := admin.List(ctx)
+		if err != nil {
+			return err
+		}
+
+		return printLimits(os.Stdout, limits)
+	case "show":
+		login, window, err := parseLimit(command, args)
+		if err != nil {
+			return err
+		}
+
+		limit, err := admin.Debug(ctx, login, window)
+		if err != nil {
+			return err
+		}
+
+		data, err := jsoniter.MarshalToString(limit)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(data)
+
+		return nil
+	case "reset":
+		return reset(ctx, admin, args)
+	case "set-threshold", "raise-threshold":
+		return setThreshold(ctx, admin, command, args)
+	case "export":
+		return export(ctx, admin, args, file)
+	case "copy-to-redis":
+		count, err := copyLimits(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d request limits copied to redis\n", count)
+
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", errUnknownCommand, command)
+	}
+}
+
+// parseLimit reads the login and window arguments of the command.
+func parseLimit(command string, args []string) (string, time.Duration, error) {
+	if len(args) < 2 {
+		return "", 0, fmt.Errorf("%w: %s requires the login and window", errNoArgs, command)
+	}
+
+	window, err := time.ParseDuration(args[1])
+	if err != nil {
+		return "", 0, err
+	}
+
+	return args[0], window, nil
+}
+
+func reset(ctx context.Context, admin tweetFinder.LimitsAdmin, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: reset requires the login", errNoArgs)
+	}
+
+	if len(args) > 1 {
+		login, window, err := parseLimit("reset", args)
+		if err != nil {
+			return err
+		}
+
+		return admin.Reset(ctx, login, window)
+	}
+
+	// the windows the account has no limits in are skipped
+	for _, window := range tweetFinder.LimiterIntervals {
+		if err := admin.Reset(ctx, args[0], window); err != nil && !errors.Is(err, fdb.ErrRequestLimitsNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setThreshold(ctx context.Context, admin tweetFinder.LimitsAdmin, command string, args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("%w: %s requires the login, window and value", errNoArgs, command)
+	}
+
+	login, window, err := parseLimit(command, args)
+	if err != nil {
+		return err
+	}
+
+	threshold, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	if command == "raise-threshold" {
+		return admin.RaiseThreshold(ctx, login, window, threshold)
+	}
+
+	return admin.SetThreshold(ctx, login, window, threshold)
+}
+
+// export writes the request times of the found limits, the login and window arguments narrow them down.
+func export(ctx context.Context, admin tweetFinder.LimitsAdmin, args []string, file string) error {
+	var window time.Duration
+
+	if len(args) > 1 {
+		var err error
+		if _, window, err = parseLimit("export", args); err != nil {
+			return err
+		}
+	}
+
+	limits, err := admin.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+
+	if file != "-" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		out = f
+	}
+
+	w := csv.NewWriter(out)
+
+	if err = w.Write([]string{"login", "window", "time"}); err != nil {
+		return err
+	}
+
+	for _, limit := range limits {
+		if !limit.Found || (len(args) > 0 && limit.Login != args[0]) || (window != 0 && limit.Window != window) {
+			continue
+		}
+
+		history, err := admin.History(ctx, limit.Login, limit.Window)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range history {
+			if err = w.Write([]string{limit.Login, limit.Window.String(), t.UTC().Format(time.RFC3339)}); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
+
+func printLimits(out io.Writer, limits []tweetFinder.LimitState) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "LOGIN\tWINDOW\tCOUNT\tTHRESHOLD\tTEMP")
+
+	for _, limit := range limits {
+		if !limit.Found {
+			fmt.Fprintf(w, "%s\t%s\t-\t-\t-\n", limit.Login, limit.Window)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%.2f\n", limit.Login, limit.Window, limit.RequestsCount, limit.Threshold, limit.Temp())
+	}
+
+	return w.Flush()
 }
@@ -82,7 +82,10 @@ func main() {
 	st := fdb.NewDB(db, logrusLogger.WithField(pkgKey, "fdb"))
 
 	rdb := redis.NewClient(&redis.Options{Addr: cfg.RedisAddress})
-	rst := repo.NewDB(rdb, logger.WithField(pkgKey, "repo"))
+	rst, err := repo.WithEncryption(repo.NewDB(rdb, logger.WithField(pkgKey, "repo")), repo.GetEncryptionConfig())
+	if err != nil {
+		panic(err)
+	}
 
 	ratingFetcher := ratingCollector.NewFetcher(
 		cfg.AppID,
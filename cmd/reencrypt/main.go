@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	nested "github.com/antonfisher/nested-logrus-formatter"
+	foundeationDB "github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.elastic.co/ecslogrus"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	repo "github.com/lueurxax/crypto-tweet-sense/internal/repo/redis"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/envelope"
+)
+
+var version = "dev"
+
+const (
+	foundationDBVersion = 710
+	pkgKey              = "pkg"
+)
+
+var errNoKeys = errors.New("ENCRYPTION_KEYS or ENCRYPTION_KEYS_FILE must be set")
+
+type config struct {
+	LoggerLevel  logrus.Level `envconfig:"LOG_LEVEL" default:"info"`
+	LogToEcs     bool         `envconfig:"LOG_TO_ECS" default:"false"`
+	RedisAddress string       `envconfig:"REDIS_ADDRESS" default:"localhost:6379"`
+	DatabasePath string       `default:"/usr/local/etc/foundationdb/fdb.cluster"`
+}
+
+// Reencrypt seals the Twitter credentials, cookies and Telegram session with ENCRYPTION_PRIMARY_KEY.
+// To rotate the key add the new one to the keyring, make it primary, run the command and drop the old key after.
+// The plain values are encrypted the same way when the encryption is enabled for the first time.
+// The plain Telegram session left in foundationdb is moved to redis and deleted.
+func main() {
+	printVersion := flag.Bool("version", false, "print version and exit")
+	generate := flag.Bool("generate", false, "print a new random key and exit")
+	flag.Parse()
+
+	if *printVersion {
+		fmt.Println(version)
+		return
+	}
+
+	if *generate {
+		key := make([]byte, envelope.KeySize)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+
+		return
+	}
+
+	// init main config
+	cfg := new(config)
+	if err := envconfig.Process("", cfg); err != nil {
+		panic(err)
+	}
+
+	// init logger
+	logrusLogger := logrus.New()
+	logrusLogger.SetLevel(cfg.LoggerLevel)
+	logrusLogger.SetFormatter(&nested.Formatter{
+		FieldsOrder:     []string{pkgKey},
+		TimestampFormat: "01-02|15:04:05",
+	})
+
+	if cfg.LogToEcs {
+		logrusLogger.SetFormatter(&ecslogrus.Formatter{})
+	}
+
+	logger := log.NewLogger(logrusLogger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	encryptionConfig := repo.GetEncryptionConfig()
+
+	keyring, err := encryptionConfig.Keyring()
+	if err != nil {
+		panic(err)
+	}
+
+	if keyring == nil {
+		panic(errNoKeys)
+	}
+
+	rdb := redis.NewClient(&redis.Options{Addr: cfg.RedisAddress})
+
+	rst, err := repo.WithEncryption(repo.NewDB(rdb, logger.WithField(pkgKey, "repo")), encryptionConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	foundeationDB.MustAPIVersion(foundationDBVersion)
+
+	logger.WithField("cluster_location", cfg.DatabasePath).Info("starting foundationdb")
+
+	db, err := foundeationDB.OpenDatabase(cfg.DatabasePath)
+	if err != nil {
+		panic(err)
+	}
+
+	moved, err := repo.MoveSession(ctx, rst, fdb.NewDB(db, logrusLogger.WithField(pkgKey, "fdb")))
+	if err != nil {
+		panic(err)
+	}
+
+	if moved {
+		logger.Info("plain telegram session deleted from foundationdb")
+	}
+
+	count, err := repo.Reencrypt(ctx, rst)
+	if err != nil {
+		panic(err)
+	}
+
+	logger.WithField("accounts", count).WithField("key", encryptionConfig.PrimaryKey).Info("secrets reencrypted")
+}
@@ -66,7 +66,10 @@ func main() {
 
 	db := redis.NewClient(&redis.Options{Addr: cfg.RedisAddress})
 
-	st := repo.NewDB(db, logger.WithField(pkgKey, "repo"))
+	st, err := repo.WithEncryption(repo.NewDB(db, logger.WithField(pkgKey, "repo")), repo.GetEncryptionConfig())
+	if err != nil {
+		panic(err)
+	}
 
 	data, err := os.ReadFile("./.session.json")
 	if err != nil {
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	nested "github.com/antonfisher/nested-logrus-formatter"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/sirupsen/logrus"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	tweetFinder "github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder"
+	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder/simulation"
+)
+
+var version = "dev"
+
+const pkgKey = "pkg"
+
+type config struct {
+	// the throttles are logged as errors, they are the subject of the report here
+	LoggerLevel logrus.Level `envconfig:"LOG_LEVEL" default:"fatal"`
+}
+
+// Simulate runs the delay manager of one account against the synthetic rate limits in the virtual time and prints
+// the requests served, the throttles hit and the delay over time. The controller is configured by the DELAY_* variables.
+func main() {
+	printVersion := flag.Bool("version", false, "print version and exit")
+	duration := flag.Duration("duration", time.Hour*24, "virtual time to simulate")
+	windows := flag.String("windows", "15m:50,24h:500", "synthetic rate limits, duration:limit pairs")
+	controller := flag.String("controller", "", "delay controller, v2 or aimd, overrides DELAY_CONTROLLER")
+	sample := flag.Duration("sample", time.Hour, "report interval in the virtual time")
+	settle := flag.Duration("settle", time.Microsecond*100, "real time the loops get to catch up after every request")
+	flag.Parse()
+
+	if *printVersion {
+		fmt.Println(version)
+		return
+	}
+
+	// init main config
+	cfg := new(config)
+	if err := envconfig.Process("", cfg); err != nil {
+		panic(err)
+	}
+
+	// init logger
+	logrusLogger := logrus.New()
+	logrusLogger.SetLevel(cfg.LoggerLevel)
+	logrusLogger.SetFormatter(&nested.Formatter{
+		FieldsOrder:     []string{pkgKey},
+		TimestampFormat: "01-02|15:04:05",
+	})
+
+	logger := log.NewLogger(logrusLogger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	limits, err := simulation.ParseWindows(*windows)
+	if err != nil {
+		panic(err)
+	}
+
+	delayConfig := tweetFinder.GetDelayConfig()
+	if *controller != "" {
+		delayConfig.Controller = *controller
+	}
+
+	report, err := simulation.Run(ctx, simulation.Config{
+		Duration:       *duration,
+		Windows:        limits,
+		Delay:          delayConfig,
+		SampleInterval: *sample,
+		Settle:         *settle,
+	}, logger)
+	if err != nil {
+		panic(err)
+	}
+
+	if err = printReport(os.Stdout, delayConfig.Controller, report); err != nil {
+		panic(err)
+	}
+}
+
+func printReport(out io.Writer, controller string, report *simulation.Report) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintln(w, "ELAPSED\tDELAY\tTEMP\tSERVED\tTHROTTLES\t")
+
+	for _, s := range report.Samples {
+		fmt.Fprintf(w, "%s\t%ds\t%.2f\t%d\t%d\t\n", s.Elapsed.Truncate(time.Minute), s.Delay, s.Temp, s.Served, s.Throttles)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(out, "\n%s: served %d, throttled %d\n", controller, report.Served, report.Throttles)
+
+	return err
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	"github.com/valyala/fasthttp"
+)
+
+const bearerPrefix = "Bearer "
+
+var (
+	errNoWebhookToken = errors.New("SOURCE_WEBHOOK_TOKEN is required by the webhook source")
+	errUnauthorized   = errors.New("unauthorized")
+)
+
+// withToken passes only the requests with the bearer token to the handler.
+func withToken(token string, handler fasthttp.RequestHandler) fasthttp.RequestHandler {
+	expected := []byte(bearerPrefix + token)
+
+	return func(ctx *fasthttp.RequestCtx) {
+		if subtle.ConstantTimeCompare(ctx.Request.Header.Peek(fasthttp.HeaderAuthorization), expected) != 1 {
+			ctx.Error(errUnauthorized.Error(), fasthttp.StatusUnauthorized)
+			return
+		}
+
+		handler(ctx)
+	}
+}
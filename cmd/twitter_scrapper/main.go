@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"net/http/pprof"
 	"os"
 	"os/signal"
@@ -13,6 +14,7 @@ import (
 	nested "github.com/antonfisher/nested-logrus-formatter"
 	foundeationDB "github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/buaazp/fasthttprouter"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/kelseyhightower/envconfig"
 	migrations "github.com/lueurxax/crypto-tweet-sense/internal/repo/migrationFtoR"
 	repo "github.com/lueurxax/crypto-tweet-sense/internal/repo/redis"
@@ -26,12 +28,17 @@ import (
 	"go.elastic.co/ecslogrus"
 
 	"github.com/lueurxax/crypto-tweet-sense/internal/account_manager"
+	"github.com/lueurxax/crypto-tweet-sense/internal/lease"
 	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	"github.com/lueurxax/crypto-tweet-sense/internal/predictor"
 	ratingCollector "github.com/lueurxax/crypto-tweet-sense/internal/ratingcollector"
 	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	"github.com/lueurxax/crypto-tweet-sense/internal/source"
 	tweetFinder "github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder"
+	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder/proxymanager"
 	"github.com/lueurxax/crypto-tweet-sense/internal/watcher"
-	"github.com/lueurxax/crypto-tweet-sense/internal/watcher/doubledelayer"
+	"github.com/lueurxax/crypto-tweet-sense/internal/watcher/scheduler"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
 )
 
 var version = "dev"
@@ -40,18 +47,48 @@ const (
 	foundationDBVersion = 710
 	pkgKey              = "pkg"
 	GetMethod           = "GET"
+	PostMethod          = "POST"
+	PutMethod           = "PUT"
+	DeleteMethod        = "DELETE"
 	namespace           = "crypto_tweet_sense"
 	subsystem           = "finder"
+	jsonContentType     = "application/json"
 )
 
 type config struct {
-	LoggerLevel      logrus.Level `envconfig:"LOG_LEVEL" default:"info"`
-	LogToEcs         bool         `envconfig:"LOG_TO_ECS" default:"false"`
-	TopCount         int          `envconfig:"TOP_COUNT" default:"1000"`
-	DatabasePath     string       `default:"/usr/local/etc/foundationdb/fdb.cluster"`
-	RedisAddress     string       `envconfig:"REDIS_ADDRESS" default:"localhost:6379"`
-	MetricsSubsystem string       `envconfig:"METRICS_SUBSYSTEM" default:"crypto_tweet_sense"`
-	DiagHTTPPort     int          `envconfig:"DIAG_HTTP_PORT" default:"8080"`
+	LoggerLevel  logrus.Level `envconfig:"LOG_LEVEL" default:"info"`
+	LogToEcs     bool         `envconfig:"LOG_TO_ECS" default:"false"`
+	TopCount     int          `envconfig:"TOP_COUNT" default:"1000"`
+	DatabasePath string       `default:"/usr/local/etc/foundationdb/fdb.cluster"`
+	// MemoryDatabase keeps the foundationdb data in memory for the local runs without a cluster, nothing is persisted.
+	MemoryDatabase   bool          `envconfig:"MEMORY_DATABASE" default:"false"`
+	RedisAddress     string        `envconfig:"REDIS_ADDRESS" default:"localhost:6379"`
+	MetricsSubsystem string        `envconfig:"METRICS_SUBSYSTEM" default:"crypto_tweet_sense"`
+	DiagHTTPPort     int           `envconfig:"DIAG_HTTP_PORT" default:"8080"`
+	ShutdownTimeout  time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"30s"`
+	// DiagAPIToken is the bearer token of the diag API changes, the queries are read only without it.
+	DiagAPIToken string `envconfig:"DIAG_API_TOKEN"`
+	// FinderRecordPath appends every finder request and response to the NDJSON file.
+	FinderRecordPath string `envconfig:"FINDER_RECORD_PATH"`
+	// FinderReplayPath serves the finder requests from the NDJSON recording instead of the Twitter accounts.
+	FinderReplayPath  string  `envconfig:"FINDER_REPLAY_PATH"`
+	FinderReplaySpeed float64 `envconfig:"FINDER_REPLAY_SPEED" default:"0"`
+}
+
+// openDatabase connects to the foundationdb cluster, the memory database is used when it is configured.
+func openDatabase(cfg *config) fdbclient.Database {
+	if cfg.MemoryDatabase {
+		return fdbclient.NewMemoryDatabase()
+	}
+
+	foundeationDB.MustAPIVersion(foundationDBVersion)
+
+	db, err := foundeationDB.OpenDatabase(cfg.DatabasePath)
+	if err != nil {
+		panic(err)
+	}
+
+	return fdbclient.NewDatabase(db)
 }
 
 func main() {
@@ -86,26 +123,24 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	foundeationDB.MustAPIVersion(foundationDBVersion)
+	db := openDatabase(cfg)
 
-	db, err := foundeationDB.OpenDatabase(cfg.DatabasePath)
-	if err != nil {
-		panic(err)
-	}
-
-	st := fdb.NewDB(db, logrusLogger.WithField(pkgKey, "fdb"))
+	st := fdb.NewDBFromClient(db, logrusLogger.WithField(pkgKey, "fdb"))
 
-	if err = st.Migrate(ctx); err != nil {
+	if err := st.Migrate(ctx); err != nil {
 		panic(err)
 	}
 
-	if err = st.CleanWrongIndexes(ctx); err != nil {
+	if err := st.CleanWrongIndexes(ctx); err != nil {
 		panic(err)
 	}
 
 	rdb := redis.NewClient(&redis.Options{Addr: cfg.RedisAddress})
 
-	rst := repo.NewDB(rdb, logger.WithField(pkgKey, "repo"))
+	rst, err := repo.WithEncryption(repo.NewDB(rdb, logger.WithField(pkgKey, "repo")), repo.GetEncryptionConfig())
+	if err != nil {
+		panic(err)
+	}
 
 	if err = rst.Migrate(ctx); err != nil {
 		panic(err)
@@ -115,12 +150,10 @@ func main() {
 		panic(err)
 	}
 
-	checker := ratingCollector.NewChecker(st, cfg.TopCount)
+	checker := ratingCollector.NewChecker(st, predictor.NewPredictor(predictor.GetConfig()), cfg.TopCount)
 
 	xConfig := tweetFinder.GetConfigPool()
 
-	accountManager := account_manager.NewManager(rst, logger.WithField(pkgKey, "account_manager"))
-
 	next := prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Namespace: namespace,
 		Subsystem: subsystem,
@@ -144,6 +177,14 @@ func main() {
 		Help:      "Requests delay in seconds",
 	}, []string{"login"})
 
+	wait := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "wait_seconds",
+		Help:      "Time the requests wait for the free finder in seconds",
+		Buckets:   []float64{.001, .01, .1, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"result"})
+
 	tweetCounter := prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Subsystem: subsystem,
@@ -151,11 +192,105 @@ func main() {
 		Help:      "Tweets count",
 	}, []string{})
 
-	prometheus.MustRegister(one, next, delay, tweetCounter)
+	prometheus.MustRegister(one, next, delay, wait, tweetCounter)
 
-	finder := tweetFinder.NewPool(one, next, delay, xConfig, accountManager, st, logger.WithField(pkgKey, "tweet_finder_pool"))
-	if err = finder.Init(ctx); err != nil {
-		panic(err)
+	proxyMetrics := proxymanager.NewMetrics(namespace, subsystem)
+	prometheus.MustRegister(proxyMetrics.Collectors()...)
+
+	delayMetrics := tweetFinder.NewDelayMetrics(namespace, subsystem)
+	prometheus.MustRegister(delayMetrics.Collectors()...)
+
+	accountMetrics := account_manager.NewMetrics(namespace, subsystem)
+	prometheus.MustRegister(accountMetrics.Collectors()...)
+
+	accountManager := account_manager.NewManager(
+		account_manager.GetHealthConfig(),
+		rst,
+		accountMetrics,
+		logger.WithField(pkgKey, "account_manager"),
+	)
+
+	sourceConfig := source.GetConfig()
+	sources := make(map[string]source.Source)
+
+	if sourceConfig.NitterURL != "" {
+		sources[source.Nitter] = source.NewNitter(sourceConfig.NitterURL, &http.Client{Timeout: sourceConfig.NitterTimeout})
+	}
+
+	webhook := source.NewWebhook(sourceConfig.WebhookBuffer, st)
+	if sourceConfig.WebhookEnabled {
+		if sourceConfig.WebhookToken == "" {
+			panic(errNoWebhookToken)
+		}
+
+		sources[source.Webhook] = webhook
+	}
+
+	var (
+		finder tweetFinder.Finder
+		leases lease.Leaser
+	)
+
+	if cfg.FinderReplayPath != "" {
+		if finder, err = replayFinder(cfg); err != nil {
+			panic(err)
+		}
+
+		logger.WithField("path", cfg.FinderReplayPath).Info("replaying finder recording")
+	} else {
+		proxies := proxymanager.NewManager(proxymanager.GetConfig(), xConfig.GetProxies(), rst, proxyMetrics, logger.WithField(pkgKey, "proxy_manager"))
+
+		go proxies.Run(ctx)
+
+		leases = lease.NewLeaser(lease.GetConfig(), rst, logger.WithField(pkgKey, "leaser"))
+
+		limitsConfig := tweetFinder.GetLimitsConfig()
+
+		limits, err := tweetFinder.SelectLimitsRepo[tweetFinder.RequestLimitsRepo](limitsConfig, st, rst)
+		if err != nil {
+			panic(err)
+		}
+
+		if limitsConfig.Storage == tweetFinder.StorageRedis {
+			copied, err := migrations.InitRequestLimits(ctx, db, rdb)
+			if err != nil {
+				panic(err)
+			}
+
+			if copied > 0 {
+				logger.WithField("limits", copied).Info("request limits copied to redis")
+			}
+		}
+
+		pool, err := tweetFinder.NewPool(
+			tweetFinder.GetSelectionConfig(),
+			limitsConfig,
+			tweetFinder.GetDelayConfig(),
+			tweetFinder.GetWarmUpConfig(),
+			one, next, wait, delay, delayMetrics,
+			proxies, leases, accountManager, limits,
+			logger.WithField(pkgKey, "tweet_finder_pool"),
+		)
+		if err != nil {
+			panic(err)
+		}
+
+		if err = pool.Init(ctx); err != nil {
+			panic(err)
+		}
+
+		finder = tweetFinder.NewRouter(pool, sources)
+	}
+
+	if cfg.FinderRecordPath != "" {
+		recording, err := os.OpenFile(cfg.FinderRecordPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			panic(err)
+		}
+
+		defer recording.Close()
+
+		finder = tweetFinder.NewRecordMiddleware(recording, finder, logger.WithField(pkgKey, "finder_recorder"))
 	}
 
 	finderWithMetrics := tweetFinder.NewMetricMiddleware(one, next, "pool", finder)
@@ -167,7 +302,7 @@ func main() {
 		finderWithMetrics,
 		st,
 		checker,
-		doubledelayer.NewDelayer(time.Minute, time.Second),
+		scheduler.NewScheduler(scheduler.GetConfig(), st, finderWithMetrics, logger.WithField(pkgKey, "scheduler")),
 		logger.WithField(pkgKey, "watcher"),
 	)
 
@@ -185,6 +320,23 @@ func main() {
 	diagAPIRouter.Handle(GetMethod, "/debug/pprof/block", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Handler("block").ServeHTTP))
 	diagAPIRouter.Handle(GetMethod, "/debug/pprof/mutex", fasthttpadaptor.NewFastHTTPHandlerFunc(pprof.Handler("mutex").ServeHTTP))
 	diagAPIRouter.Handle(GetMethod, "/metrics", fasthttpadaptor.NewFastHTTPHandlerFunc(promhttp.Handler().ServeHTTP))
+	diagAPIRouter.Handle(GetMethod, "/tweets/:id/history", tweetHistoryHandler(st, logger.WithField(pkgKey, "diag_api")))
+
+	queries := &queriesAPI{repo: st, logger: logger.WithField(pkgKey, "queries_api")}
+	diagAPIRouter.Handle(GetMethod, "/queries", queries.list)
+	diagAPIRouter.Handle(GetMethod, "/queries/:name", queries.get)
+
+	if cfg.DiagAPIToken != "" {
+		diagAPIRouter.Handle(PutMethod, "/queries/:name", withToken(cfg.DiagAPIToken, queries.put))
+		diagAPIRouter.Handle(DeleteMethod, "/queries/:name", withToken(cfg.DiagAPIToken, queries.delete))
+	} else {
+		logger.Warn("search queries are read only, DIAG_API_TOKEN is not set")
+	}
+
+	if sourceConfig.WebhookEnabled {
+		diagAPIRouter.Handle(PostMethod, "/ingest/:"+source.QueryParam, withToken(sourceConfig.WebhookToken, webhook.Handler))
+	}
+
 	diagAPIServer := &fasthttp.Server{
 		Handler: diagAPIRouter.Handler,
 	}
@@ -192,7 +344,7 @@ func main() {
 	go func() {
 		logger.WithField("port", cfg.DiagHTTPPort).Info("starting diag API server")
 
-		if err = diagAPIServer.ListenAndServe(fmt.Sprintf(":%d", cfg.DiagHTTPPort)); err != nil {
+		if err := diagAPIServer.ListenAndServe(fmt.Sprintf(":%d", cfg.DiagHTTPPort)); err != nil {
 			logger.WithError(err).Error("diag API server run failure")
 			os.Exit(1)
 		}
@@ -200,4 +352,63 @@ func main() {
 
 	logger.Info("service started")
 	<-ctx.Done()
+
+	logger.Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err = watch.Stop(shutdownCtx); err != nil {
+		logger.WithError(err).Error("watcher graceful shutdown failure")
+	}
+
+	if err = diagAPIServer.ShutdownWithContext(shutdownCtx); err != nil {
+		logger.WithError(err).Error("diag API server shutdown failure")
+	}
+
+	// the other replicas take the accounts over right away instead of waiting for the leases to expire
+	if leases != nil {
+		if err = leases.Close(shutdownCtx); err != nil {
+			logger.WithError(err).Error("account leases release failure")
+		}
+	}
+}
+
+func replayFinder(cfg *config) (tweetFinder.Finder, error) {
+	recording, err := os.Open(cfg.FinderReplayPath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer recording.Close()
+
+	records, err := tweetFinder.ReadRecords(recording)
+	if err != nil {
+		return nil, err
+	}
+
+	return tweetFinder.NewReplayFinder(records, tweetFinder.ReplayConfig{Speed: cfg.FinderReplaySpeed, Rebase: true}), nil
+}
+
+func tweetHistoryHandler(st fdb.DB, logger log.Logger) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		id, _ := ctx.UserValue("id").(string)
+
+		history, err := st.GetTweetHistory(ctx, id)
+		if err != nil {
+			logger.WithError(err).Error("get tweet history")
+			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+
+			return
+		}
+
+		data, err := jsoniter.Marshal(history)
+		if err != nil {
+			ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+			return
+		}
+
+		ctx.SetContentType(jsonContentType)
+		ctx.SetBody(data)
+	}
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+)
+
+const queryNameParam = "name"
+
+// queriesAPI manages the watcher search queries, the watcher picks the changes up on its next sync.
+type queriesAPI struct {
+	repo   fdb.DB
+	logger log.Logger
+}
+
+func (a *queriesAPI) list(ctx *fasthttp.RequestCtx) {
+	queries, err := a.repo.GetSearchQueries(ctx)
+	if err != nil {
+		a.logger.WithError(err).Error("get search queries")
+		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+
+		return
+	}
+
+	a.writeJSON(ctx, queries)
+}
+
+func (a *queriesAPI) get(ctx *fasthttp.RequestCtx) {
+	name, _ := ctx.UserValue(queryNameParam).(string)
+
+	query, err := a.repo.GetSearchQuery(ctx, name)
+	if err != nil {
+		if errors.Is(err, fdb.ErrSearchQueryNotFound) {
+			ctx.Error(err.Error(), fasthttp.StatusNotFound)
+			return
+		}
+
+		a.logger.WithError(err).Error("get search query")
+		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+
+		return
+	}
+
+	a.writeJSON(ctx, query)
+}
+
+func (a *queriesAPI) put(ctx *fasthttp.RequestCtx) {
+	query := common.SearchQuery{}
+	if err := jsoniter.Unmarshal(ctx.PostBody(), &query); err != nil {
+		ctx.Error(err.Error(), fasthttp.StatusBadRequest)
+		return
+	}
+
+	query.Name, _ = ctx.UserValue(queryNameParam).(string)
+
+	if err := query.Validate(); err != nil {
+		ctx.Error(err.Error(), fasthttp.StatusBadRequest)
+		return
+	}
+
+	if err := a.repo.SaveSearchQuery(ctx, query); err != nil {
+		a.logger.WithError(err).Error("save search query")
+		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+
+		return
+	}
+
+	a.logger.WithField("query", query.Name).Info("search query saved")
+	a.writeJSON(ctx, query)
+}
+
+func (a *queriesAPI) delete(ctx *fasthttp.RequestCtx) {
+	name, _ := ctx.UserValue(queryNameParam).(string)
+
+	if err := a.repo.DeleteSearchQuery(ctx, name); err != nil {
+		a.logger.WithError(err).Error("delete search query")
+		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+
+		return
+	}
+
+	a.logger.WithField("query", name).Info("search query deleted")
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+func (a *queriesAPI) writeJSON(ctx *fasthttp.RequestCtx, v interface{}) {
+	data, err := jsoniter.Marshal(v)
+	if err != nil {
+		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType(jsonContentType)
+	ctx.SetBody(data)
+}
@@ -0,0 +1,219 @@
+package account_manager
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	twitterscraper "github.com/lueurxax/twitter-scraper"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+)
+
+const (
+	disabledByAdmin = "disabled by admin"
+	testTimeout     = time.Minute
+)
+
+var ErrAccountDisabled = errors.New("account is disabled")
+
+// Admin manages the stored accounts. The running scrappers evict the disabled accounts on their next pool refresh
+// within a minute and log the enabled ones in on it.
+type Admin interface {
+	List(ctx context.Context) ([]AccountInfo, error)
+	Enable(ctx context.Context, login string) error
+	Disable(ctx context.Context, login string) error
+	// Remove deletes the account with its cookies and proxy assignment.
+	Remove(ctx context.Context, login string) error
+	// Test logs the account in with its saved cookies or credentials and returns the account with the resulting status.
+	Test(ctx context.Context, login string) (common.TwitterAccount, error)
+	// Import adds the accounts, the existing ones are overwritten and enabled.
+	Import(ctx context.Context, records []AccountRecord) error
+	Export(ctx context.Context) ([]AccountRecord, error)
+}
+
+type AccountInfo struct {
+	common.TwitterAccount
+	// CookieAge is zero when the account has no saved cookies.
+	CookieAge time.Duration
+	Limits    []LimitInfo
+}
+
+type LimitInfo struct {
+	Window time.Duration
+	common.RequestLimitData
+}
+
+type adminRepo interface {
+	repo
+	DeleteAccount(ctx context.Context, login string) error
+	GetProxyAssignment(ctx context.Context, login string) (string, error)
+}
+
+type limitsRepo interface {
+	GetRequestLimit(ctx context.Context, id string, window time.Duration) (common.RequestLimitData, error)
+}
+
+type admin struct {
+	*manager
+	repo    adminRepo
+	limits  limitsRepo
+	windows []time.Duration
+}
+
+func (a *admin) List(ctx context.Context) ([]AccountInfo, error) {
+	accounts, err := a.repo.GetAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Login < accounts[j].Login })
+
+	res := make([]AccountInfo, 0, len(accounts))
+
+	for _, account := range accounts {
+		info := AccountInfo{TwitterAccount: account, Limits: make([]LimitInfo, 0, len(a.windows))}
+
+		if !account.CookiesSavedAt.IsZero() {
+			info.CookieAge = time.Since(account.CookiesSavedAt)
+		}
+
+		for _, window := range a.windows {
+			limit, err := a.limits.GetRequestLimit(ctx, account.Login, window)
+			if err != nil && !errors.Is(err, fdb.ErrRequestLimitsNotFound) {
+				return nil, err
+			}
+
+			info.Limits = append(info.Limits, LimitInfo{Window: window, RequestLimitData: limit})
+		}
+
+		res = append(res, info)
+	}
+
+	return res, nil
+}
+
+func (a *admin) Enable(ctx context.Context, login string) error {
+	account, err := a.repo.GetAccount(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	account.Failures = 0
+
+	return a.setStatus(ctx, &account, common.AccountActive, "")
+}
+
+func (a *admin) Disable(ctx context.Context, login string) error {
+	account, err := a.repo.GetAccount(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	return a.setStatus(ctx, &account, common.AccountDisabled, disabledByAdmin)
+}
+
+func (a *admin) Remove(ctx context.Context, login string) error {
+	if _, err := a.repo.GetAccount(ctx, login); err != nil {
+		return err
+	}
+
+	if err := a.repo.DeleteAccount(ctx, login); err != nil {
+		return err
+	}
+
+	a.log.WithField(loginKey, login).Info("account removed")
+
+	return nil
+}
+
+func (a *admin) Test(ctx context.Context, login string) (common.TwitterAccount, error) {
+	account, err := a.repo.GetAccount(ctx, login)
+	if err != nil {
+		return common.TwitterAccount{}, err
+	}
+
+	// the successful login would enable the account behind the admin's back
+	if account.CurrentStatus() == common.AccountDisabled {
+		return account, ErrAccountDisabled
+	}
+
+	scraper := twitterscraper.New()
+	scraper.WithClientTimeout(testTimeout)
+
+	proxy, err := a.repo.GetProxyAssignment(ctx, login)
+	if err != nil {
+		return account, err
+	}
+
+	if proxy != "" {
+		if err = scraper.SetProxy(proxy); err != nil {
+			return account, err
+		}
+	}
+
+	authErr := a.AuthScrapper(ctx, account, scraper)
+
+	if account, err = a.repo.GetAccount(ctx, login); err != nil {
+		return account, err
+	}
+
+	return account, authErr
+}
+
+func (a *admin) Import(ctx context.Context, records []AccountRecord) error {
+	for _, record := range records {
+		account := common.TwitterAccount{
+			Login:        record.Login,
+			AccessToken:  record.Password,
+			Confirmation: record.Confirmation,
+		}
+
+		if err := a.addAccount(ctx, account, record.Cookies); err != nil {
+			return err
+		}
+
+		a.log.WithField(loginKey, record.Login).Info("account imported")
+	}
+
+	return nil
+}
+
+func (a *admin) Export(ctx context.Context) ([]AccountRecord, error) {
+	accounts, err := a.repo.GetAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Login < accounts[j].Login })
+
+	records := make([]AccountRecord, 0, len(accounts))
+
+	for _, account := range accounts {
+		cookies, err := a.repo.GetCookie(ctx, account.Login)
+		if err != nil && !errors.Is(err, fdb.ErrCookieNotFound) {
+			return nil, err
+		}
+
+		records = append(records, AccountRecord{
+			Login:        account.Login,
+			Password:     account.AccessToken,
+			Confirmation: account.Confirmation,
+			Cookies:      cookies,
+		})
+	}
+
+	return records, nil
+}
+
+func NewAdmin(config *HealthConfig, repo adminRepo, limits limitsRepo, windows []time.Duration, logger log.Logger) Admin {
+	return &admin{
+		manager: NewManager(config, repo, NewMetrics("", ""), logger).(*manager),
+		repo:    repo,
+		limits:  limits,
+		windows: windows,
+	}
+}
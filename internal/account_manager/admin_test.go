@@ -0,0 +1,71 @@
+package account_manager
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+)
+
+type memoryLimits map[string]common.RequestLimitData
+
+func (l memoryLimits) GetRequestLimit(_ context.Context, id string, window time.Duration) (common.RequestLimitData, error) {
+	limit, ok := l[id+window.String()]
+	if !ok {
+		return common.RequestLimitData{}, fdb.ErrRequestLimitsNotFound
+	}
+
+	return limit, nil
+}
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRepo()
+	limits := memoryLimits{"alice1h0m0s": {RequestsCount: 10, Threshold: 50}}
+	windows := []time.Duration{time.Minute, time.Hour}
+	a := NewAdmin(newTestConfig(), r, limits, windows, log.NewLogger(logrus.New()))
+
+	records := []AccountRecord{
+		{Login: "bob", Password: "secret"},
+		{Login: "alice", Password: "secret", Confirmation: "alice@example.com", Cookies: []*http.Cookie{{Name: "auth_token", Value: "token"}}},
+	}
+	require.NoError(t, a.Import(ctx, records))
+
+	accounts, err := a.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	require.Equal(t, "alice", accounts[0].Login)
+	require.Equal(t, common.AccountActive, accounts[0].Status)
+	require.NotZero(t, accounts[0].CookieAge)
+	require.Zero(t, accounts[1].CookieAge)
+	require.Equal(t, []LimitInfo{
+		{Window: time.Minute},
+		{Window: time.Hour, RequestLimitData: common.RequestLimitData{RequestsCount: 10, Threshold: 50}},
+	}, accounts[0].Limits)
+
+	exported, err := a.Export(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []AccountRecord{records[1], records[0]}, exported)
+
+	require.NoError(t, a.Disable(ctx, "bob"))
+	require.Equal(t, common.AccountDisabled, r.accounts["bob"].Status)
+	require.Equal(t, disabledByAdmin, r.accounts["bob"].StatusReason)
+
+	_, err = a.Test(ctx, "bob")
+	require.ErrorIs(t, err, ErrAccountDisabled)
+
+	require.NoError(t, a.Enable(ctx, "bob"))
+	require.Equal(t, common.AccountActive, r.accounts["bob"].Status)
+
+	require.NoError(t, a.Remove(ctx, "alice"))
+	require.NotContains(t, r.accounts, "alice")
+	require.NotContains(t, r.cookies, "alice")
+	require.ErrorIs(t, a.Remove(ctx, "alice"), fdb.ErrTwitterAccountNotFound)
+}
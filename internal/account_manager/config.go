@@ -1,6 +1,10 @@
 package account_manager
 
-import "github.com/kelseyhightower/envconfig"
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
 
 type Config struct {
 	Login           string `envconfig:"LOGIN" required:"true"`
@@ -17,3 +21,25 @@ func GetConfig() Config {
 
 	return *cfg
 }
+
+// HealthConfig drives the account status transitions.
+type HealthConfig struct {
+	// RateLimitCoolDown is how long the account stays rate limited without successful requests.
+	RateLimitCoolDown time.Duration `envconfig:"RATE_LIMIT_COOL_DOWN" default:"15m"`
+	// ReauthBackoff is the delay before the second re-login attempt, it doubles on every failed attempt.
+	ReauthBackoff    time.Duration `envconfig:"REAUTH_BACKOFF" default:"5m"`
+	ReauthMaxBackoff time.Duration `envconfig:"REAUTH_MAX_BACKOFF" default:"12h"`
+	// LockedBackoff is the delay before the re-login attempt of the locked account.
+	LockedBackoff time.Duration `envconfig:"LOCKED_BACKOFF" default:"6h"`
+	// MaxReauthAttempts is the number of failed re-login attempts in a row after which the account is disabled.
+	MaxReauthAttempts int `envconfig:"MAX_REAUTH_ATTEMPTS" default:"10"`
+}
+
+func GetHealthConfig() *HealthConfig {
+	cfg := new(HealthConfig)
+	if err := envconfig.Process("ACCOUNT_MANAGER", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"net/http"
 	"os"
+	"sync"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	twitterscraper "github.com/lueurxax/twitter-scraper"
@@ -14,12 +16,26 @@ import (
 	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
 )
 
-const loginKey = "login"
+const (
+	loginKey  = "login"
+	fromKey   = "from"
+	toKey     = "to"
+	reasonKey = "reason"
+)
 
 type Manager interface {
 	AddAccount(ctx context.Context, config Config) error
 	AuthScrapper(ctx context.Context, account common.TwitterAccount, scraper *twitterscraper.Scraper) error
 	SearchUnAuthAccounts(ctx context.Context) ([]common.TwitterAccount, error)
+	// SearchDisabledAccounts forgets the authenticated accounts which are disabled in the store and returns them.
+	SearchDisabledAccounts(ctx context.Context) ([]string, error)
+	// Report moves the authenticated account to the status the finder error tells about and returns the current status.
+	// The account which is no longer usable is forgotten and offered by SearchUnAuthAccounts again when it can retry.
+	Report(ctx context.Context, login string, err error) common.AccountStatus
+	// Forget drops the authenticated account the replica no longer serves.
+	Forget(login string)
+	// AddWarmUpThrottle counts the throttle of the warming up account and returns the throttles counted so far.
+	AddWarmUpThrottle(ctx context.Context, login string) (int, error)
 }
 
 type repo interface {
@@ -32,7 +48,11 @@ type repo interface {
 
 type manager struct {
 	repo
-	authAccounts map[string]struct{}
+	config  *HealthConfig
+	metrics *Metrics
+
+	mu           sync.Mutex
+	authAccounts map[string]common.AccountStatus
 
 	log log.Logger
 }
@@ -43,81 +63,320 @@ func (m *manager) SearchUnAuthAccounts(ctx context.Context) ([]common.TwitterAcc
 		return nil, err
 	}
 
+	m.updateGauge(accounts)
+
 	res := make([]common.TwitterAccount, 0, len(accounts))
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	for _, account := range accounts {
-		if _, ok := m.authAccounts[account.Login]; !ok {
-			res = append(res, account)
+		if _, ok := m.authAccounts[account.Login]; ok {
+			continue
 		}
+
+		if account.CurrentStatus() == common.AccountDisabled || account.RetryAt.After(time.Now()) {
+			continue
+		}
+
+		res = append(res, account)
 	}
 
 	return res, nil
 }
 
-func (m *manager) AuthScrapper(ctx context.Context, account common.TwitterAccount, scraper *twitterscraper.Scraper) error {
-	cookies, err := m.repo.GetCookie(ctx, account.Login)
+func (m *manager) SearchDisabledAccounts(ctx context.Context) ([]string, error) {
+	accounts, err := m.repo.GetAccounts(ctx)
 	if err != nil {
-		if !errors.Is(err, fdb.ErrCookieNotFound) {
-			m.log.WithError(err).WithField(loginKey, account.Login).Error("error on get cookie")
-			return err
-		}
+		return nil, err
+	}
 
-		if err = scrapperLogin(ctx, scraper, account); err != nil {
-			m.log.WithError(err).WithField(loginKey, account.Login).Error("error while login")
-			return err
+	res := make([]string, 0)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, account := range accounts {
+		if _, ok := m.authAccounts[account.Login]; !ok || account.CurrentStatus() != common.AccountDisabled {
+			continue
 		}
+
+		delete(m.authAccounts, account.Login)
+		res = append(res, account.Login)
 	}
 
-	scraper.SetCookies(cookies)
+	return res, nil
+}
 
-	if !scraper.IsLoggedIn(ctx) {
+// AuthScrapper refreshes the saved cookies of the account and logs in again when they are expired.
+// A failed login moves the account to needs_reauth or locked with a growing backoff and disables it after too many attempts.
+func (m *manager) AuthScrapper(ctx context.Context, account common.TwitterAccount, scraper *twitterscraper.Scraper) error {
+	cookies, err := m.repo.GetCookie(ctx, account.Login)
+	if err != nil && !errors.Is(err, fdb.ErrCookieNotFound) {
+		m.log.WithError(err).WithField(loginKey, account.Login).Error("error on get cookie")
+		return err
+	}
+
+	if len(cookies) != 0 {
+		scraper.SetCookies(cookies)
+	}
+
+	if len(cookies) == 0 || !scraper.IsLoggedIn(ctx) {
 		if err = scrapperLogin(ctx, scraper, account); err != nil {
 			m.log.WithError(err).WithField(loginKey, account.Login).Error("error while login")
+			m.loginFailed(ctx, account, err)
+
 			return err
 		}
 	}
 
-	cookies = scraper.GetCookies()
+	if err = m.repo.SaveCookie(ctx, account.Login, scraper.GetCookies()); err != nil {
+		m.log.WithError(err).WithField(loginKey, account.Login).Error("error while saving cookie")
+		return err
+	}
 
-	if err = m.repo.SaveCookie(ctx, account.Login, cookies); err != nil {
-		m.log.WithError(err).WithField(loginKey, account.Login).Error("error while login")
+	account.Failures = 0
+	account.CookiesSavedAt = time.Now()
+
+	if err = m.transit(ctx, &account, common.AccountActive, ""); err != nil {
 		return err
 	}
 
-	m.authAccounts[account.Login] = struct{}{}
+	m.mu.Lock()
+	m.authAccounts[account.Login] = common.AccountActive
+	m.mu.Unlock()
 
 	return nil
 }
 
+func (m *manager) Report(ctx context.Context, login string, err error) common.AccountStatus {
+	status, ok := errorStatus(err)
+
+	m.mu.Lock()
+	current, authenticated := m.authAccounts[login]
+	m.mu.Unlock()
+
+	// the account is already evicted by a concurrent request
+	if !authenticated {
+		return status
+	}
+
+	if !ok || status == current {
+		return current
+	}
+
+	account, getErr := m.repo.GetAccount(ctx, login)
+	if getErr != nil {
+		m.log.WithError(getErr).WithField(loginKey, login).Error("error while getting account")
+		return current
+	}
+
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	}
+
+	err = m.transit(ctx, &account, status, reason)
+
+	switch {
+	case errors.Is(err, ErrAccountDisabled):
+		// the account is disabled by the admin while it was served
+		status = common.AccountDisabled
+	case err != nil:
+		return current
+	}
+
+	m.mu.Lock()
+	if status.Usable() {
+		m.authAccounts[login] = status
+	} else {
+		delete(m.authAccounts, login)
+	}
+	m.mu.Unlock()
+
+	return status
+}
+
+func (m *manager) Forget(login string) {
+	m.mu.Lock()
+	delete(m.authAccounts, login)
+	m.mu.Unlock()
+}
+
+// AddAccount saves the account as active, adding the existing account enables it again.
 func (m *manager) AddAccount(ctx context.Context, config Config) error {
+	cookies := make([]*http.Cookie, 0)
+
+	if config.CookiesFilename != "" {
+		data, err := os.ReadFile(config.CookiesFilename)
+		if err != nil {
+			return err
+		}
+
+		if err = jsoniter.Unmarshal(data, &cookies); err != nil {
+			return err
+		}
+	}
+
 	account := common.TwitterAccount{
-		Login:       config.Login,
-		AccessToken: config.Password,
+		Login:        config.Login,
+		AccessToken:  config.Password,
+		Confirmation: config.Confirmation,
+	}
+
+	return m.addAccount(ctx, account, cookies)
+}
+
+func (m *manager) AddWarmUpThrottle(ctx context.Context, login string) (int, error) {
+	account, err := m.repo.GetAccount(ctx, login)
+	if err != nil {
+		return 0, err
 	}
 
-	if config.Confirmation != "" {
-		account.Confirmation = config.Confirmation
+	account.WarmUpThrottles++
+
+	if err = m.repo.SaveAccount(ctx, account); err != nil {
+		return 0, err
 	}
 
-	if err := m.repo.SaveAccount(ctx, account); err != nil {
+	return account.WarmUpThrottles, nil
+}
+
+func (m *manager) addAccount(ctx context.Context, account common.TwitterAccount, cookies []*http.Cookie) error {
+	now := time.Now()
+
+	// the account added again keeps its age, so its warm-up is not started over
+	stored, err := m.repo.GetAccount(ctx, account.Login)
+
+	switch {
+	case err == nil:
+		account.AddedAt = stored.AddedAt
+		account.WarmUpThrottles = stored.WarmUpThrottles
+	case errors.Is(err, fdb.ErrTwitterAccountNotFound):
+		account.AddedAt = now
+	default:
 		return err
 	}
 
-	if config.CookiesFilename == "" {
-		return nil
+	account.Status = common.AccountActive
+	account.StatusChangedAt = now
+
+	if len(cookies) != 0 {
+		if err := m.repo.SaveCookie(ctx, account.Login, cookies); err != nil {
+			return err
+		}
+
+		account.CookiesSavedAt = now
 	}
 
-	data, err := os.ReadFile(config.CookiesFilename)
-	if err != nil {
-		return err
+	return m.repo.SaveAccount(ctx, account)
+}
+
+func (m *manager) loginFailed(ctx context.Context, account common.TwitterAccount, err error) {
+	account.Failures++
+
+	status := loginErrorStatus(err)
+	if account.Failures >= m.config.MaxReauthAttempts {
+		status = common.AccountDisabled
 	}
 
-	cookies := make([]*http.Cookie, 0)
-	if err = jsoniter.Unmarshal(data, &cookies); err != nil {
+	if err = m.transit(ctx, &account, status, err.Error()); err != nil && !errors.Is(err, ErrAccountDisabled) {
+		m.log.WithError(err).WithField(loginKey, account.Login).Error("error while saving account status")
+	}
+}
+
+// transit moves the account to the status unless the account is disabled in the store, the disabled account
+// is enabled by the admin only. The automatic disabling after the failed logins goes through.
+func (m *manager) transit(ctx context.Context, account *common.TwitterAccount, status common.AccountStatus, reason string) error {
+	if status != common.AccountDisabled {
+		stored, err := m.repo.GetAccount(ctx, account.Login)
+		if err != nil {
+			m.log.WithError(err).WithField(loginKey, account.Login).Error("error while getting account")
+			return err
+		}
+
+		if stored.CurrentStatus() == common.AccountDisabled {
+			return ErrAccountDisabled
+		}
+	}
+
+	return m.setStatus(ctx, account, status, reason)
+}
+
+// setStatus saves the new status of the account, the status kept by the transition only refreshes its retry time.
+func (m *manager) setStatus(ctx context.Context, account *common.TwitterAccount, status common.AccountStatus, reason string) error {
+	from := account.CurrentStatus()
+	now := time.Now()
+
+	if from != status {
+		account.StatusChangedAt = now
+	}
+
+	account.Status = status
+	account.StatusReason = reason
+	account.RetryAt = now.Add(m.retryDelay(*account))
+
+	if err := m.repo.SaveAccount(ctx, *account); err != nil {
+		m.log.WithError(err).WithField(loginKey, account.Login).Error("error while saving account")
 		return err
 	}
 
-	return m.repo.SaveCookie(ctx, config.Login, cookies)
+	if from == status {
+		return nil
+	}
+
+	m.metrics.Transitions.WithLabelValues(string(from), string(status)).Inc()
+	m.metrics.Accounts.WithLabelValues(string(from)).Dec()
+	m.metrics.Accounts.WithLabelValues(string(status)).Inc()
+
+	logger := m.log.WithField(loginKey, account.Login).
+		WithField(fromKey, from).
+		WithField(toKey, status).
+		WithField(reasonKey, reason)
+
+	if status.Usable() {
+		logger.Info("account status changed")
+	} else {
+		logger.Warn("account status changed")
+	}
+
+	return nil
+}
+
+func (m *manager) retryDelay(account common.TwitterAccount) time.Duration {
+	switch account.Status {
+	case common.AccountRateLimited:
+		return m.config.RateLimitCoolDown
+	case common.AccountNeedsReauth:
+		return backoff(m.config.ReauthBackoff, m.config.ReauthMaxBackoff, account.Failures)
+	case common.AccountLocked:
+		// the account locked on a request waits before the first attempt as well
+		return backoff(m.config.LockedBackoff, m.config.ReauthMaxBackoff, max(account.Failures, 1))
+	default:
+		return 0
+	}
+}
+
+func (m *manager) updateGauge(accounts []common.TwitterAccount) {
+	m.metrics.Accounts.Reset()
+
+	for _, account := range accounts {
+		m.metrics.Accounts.WithLabelValues(string(account.CurrentStatus())).Inc()
+	}
+}
+
+// backoff is zero before the first failed attempt, the first retry waits for the base delay.
+func backoff(base, maxDelay time.Duration, failures int) time.Duration {
+	if failures == 0 {
+		return 0
+	}
+
+	delay := base
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxDelay)
 }
 
 func scrapperLogin(ctx context.Context, scraper *twitterscraper.Scraper, account common.TwitterAccount) error {
@@ -128,6 +387,12 @@ func scrapperLogin(ctx context.Context, scraper *twitterscraper.Scraper, account
 	return scraper.Login(ctx, account.Login, account.AccessToken, account.Confirmation)
 }
 
-func NewManager(repo repo, logger log.Logger) Manager {
-	return &manager{repo: repo, authAccounts: make(map[string]struct{}), log: logger}
+func NewManager(config *HealthConfig, repo repo, metrics *Metrics, logger log.Logger) Manager {
+	return &manager{
+		repo:         repo,
+		config:       config,
+		metrics:      metrics,
+		authAccounts: make(map[string]common.AccountStatus),
+		log:          logger,
+	}
 }
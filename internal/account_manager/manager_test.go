@@ -0,0 +1,256 @@
+package account_manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	"github.com/lueurxax/crypto-tweet-sense/internal/source"
+)
+
+type memoryRepo struct {
+	mu       sync.Mutex
+	accounts map[string]common.TwitterAccount
+	cookies  map[string][]*http.Cookie
+}
+
+func (r *memoryRepo) GetAccount(_ context.Context, login string) (common.TwitterAccount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[login]
+	if !ok {
+		return common.TwitterAccount{}, fdb.ErrTwitterAccountNotFound
+	}
+
+	return account, nil
+}
+
+func (r *memoryRepo) SaveAccount(_ context.Context, account common.TwitterAccount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.accounts[account.Login] = account
+
+	return nil
+}
+
+func (r *memoryRepo) SaveCookie(_ context.Context, login string, cookies []*http.Cookie) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cookies[login] = cookies
+
+	return nil
+}
+
+func (r *memoryRepo) GetCookie(_ context.Context, login string) ([]*http.Cookie, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cookies, ok := r.cookies[login]
+	if !ok {
+		return nil, fdb.ErrCookieNotFound
+	}
+
+	return cookies, nil
+}
+
+func (r *memoryRepo) DeleteAccount(_ context.Context, login string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.accounts, login)
+	delete(r.cookies, login)
+
+	return nil
+}
+
+func (r *memoryRepo) GetProxyAssignment(context.Context, string) (string, error) {
+	return "", nil
+}
+
+func (r *memoryRepo) GetAccounts(context.Context) ([]common.TwitterAccount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	accounts := make([]common.TwitterAccount, 0, len(r.accounts))
+	for _, account := range r.accounts {
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+func newTestRepo(accounts ...common.TwitterAccount) *memoryRepo {
+	r := &memoryRepo{accounts: make(map[string]common.TwitterAccount), cookies: make(map[string][]*http.Cookie)}
+	for _, account := range accounts {
+		r.accounts[account.Login] = account
+	}
+
+	return r
+}
+
+func newTestConfig() *HealthConfig {
+	return &HealthConfig{
+		RateLimitCoolDown: time.Minute,
+		ReauthBackoff:     time.Minute,
+		ReauthMaxBackoff:  time.Hour,
+		LockedBackoff:     time.Hour,
+		MaxReauthAttempts: 3,
+	}
+}
+
+func newTestManager(accounts ...common.TwitterAccount) (*manager, *memoryRepo) {
+	r := newTestRepo(accounts...)
+
+	return NewManager(newTestConfig(), r, NewMetrics("", ""), log.NewLogger(logrus.New())).(*manager), r
+}
+
+func TestManager_Report(t *testing.T) {
+	ctx := context.Background()
+	m, r := newTestManager(common.TwitterAccount{Login: "alice"}, common.TwitterAccount{Login: "bob"})
+	m.authAccounts["alice"] = common.AccountActive
+	m.authAccounts["bob"] = common.AccountActive
+
+	// errors which tell nothing about the account keep it active
+	require.Equal(t, common.AccountActive, m.Report(ctx, "alice", errors.New("timeout")))
+	require.Equal(t, common.AccountActive, m.Report(ctx, "alice", nil))
+	require.Equal(t, common.TwitterAccount{Login: "alice"}, r.accounts["alice"])
+
+	// rate limited account stays authenticated and gets back on success
+	require.Equal(t, common.AccountRateLimited, m.Report(ctx, "alice", errors.Join(source.ErrRateLimited, errors.New("429"))))
+	require.Equal(t, common.AccountRateLimited, r.accounts["alice"].Status)
+	require.Equal(t, common.AccountActive, m.Report(ctx, "alice", nil))
+	require.Equal(t, common.AccountActive, r.accounts["alice"].Status)
+	require.Contains(t, m.authAccounts, "alice")
+
+	// unauthorized account is forgotten and can log in again right away
+	require.Equal(t, common.AccountNeedsReauth, m.Report(ctx, "alice", errors.Join(source.ErrUnauthorized, errors.New("401"))))
+	require.NotContains(t, m.authAccounts, "alice")
+
+	// locked account waits for the owner
+	require.Equal(t, common.AccountLocked, m.Report(ctx, "bob", errors.Join(source.ErrLocked, errors.New("326"))))
+	require.True(t, r.accounts["bob"].RetryAt.After(time.Now().Add(time.Minute*59)))
+
+	accounts, err := m.SearchUnAuthAccounts(ctx)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, "alice", accounts[0].Login)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(m.metrics.Transitions.WithLabelValues("active", "rate_limited")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.metrics.Transitions.WithLabelValues("active", "locked")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.metrics.Accounts.WithLabelValues("locked")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.metrics.Accounts.WithLabelValues("needs_reauth")))
+}
+
+func TestManager_ReportDisabled(t *testing.T) {
+	ctx := context.Background()
+	m, r := newTestManager(common.TwitterAccount{Login: "alice"}, common.TwitterAccount{Login: "bob"})
+	m.authAccounts["alice"] = common.AccountActive
+	m.authAccounts["bob"] = common.AccountActive
+
+	// the admin disables the accounts while they are served
+	r.accounts["alice"] = common.TwitterAccount{Login: "alice", Status: common.AccountDisabled, StatusReason: disabledByAdmin}
+	r.accounts["bob"] = common.TwitterAccount{Login: "bob", Status: common.AccountDisabled, StatusReason: disabledByAdmin}
+
+	// the status told by the request does not overwrite the disabled one
+	require.Equal(t, common.AccountDisabled, m.Report(ctx, "alice", errors.Join(source.ErrRateLimited, errors.New("429"))))
+	require.Equal(t, common.AccountDisabled, r.accounts["alice"].Status)
+	require.Equal(t, disabledByAdmin, r.accounts["alice"].StatusReason)
+	require.NotContains(t, m.authAccounts, "alice")
+
+	// the account which keeps succeeding is found by the pool refresh
+	disabled, err := m.SearchDisabledAccounts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob"}, disabled)
+	require.NotContains(t, m.authAccounts, "bob")
+
+	accounts, err := m.SearchUnAuthAccounts(ctx)
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+}
+
+func TestManager_loginFailed(t *testing.T) {
+	ctx := context.Background()
+	m, r := newTestManager(common.TwitterAccount{Login: "alice"})
+	loginErr := errors.New("auth error (32): Could not authenticate you")
+
+	m.loginFailed(ctx, r.accounts["alice"], loginErr)
+	require.Equal(t, common.AccountNeedsReauth, r.accounts["alice"].Status)
+	require.Equal(t, 1, r.accounts["alice"].Failures)
+	require.WithinDuration(t, time.Now().Add(time.Minute), r.accounts["alice"].RetryAt, time.Second)
+
+	m.loginFailed(ctx, r.accounts["alice"], loginErr)
+	require.WithinDuration(t, time.Now().Add(time.Minute*2), r.accounts["alice"].RetryAt, time.Second)
+
+	accounts, err := m.SearchUnAuthAccounts(ctx)
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+
+	m.loginFailed(ctx, r.accounts["alice"], loginErr)
+	require.Equal(t, common.AccountDisabled, r.accounts["alice"].Status)
+	require.Equal(t, loginErr.Error(), r.accounts["alice"].StatusReason)
+
+	// adding the account again enables it
+	require.NoError(t, m.AddAccount(ctx, Config{Login: "alice", Password: "secret"}))
+	require.Equal(t, common.AccountActive, r.accounts["alice"].Status)
+	require.Zero(t, r.accounts["alice"].Failures)
+}
+
+func TestLoginErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want common.AccountStatus
+	}{
+		{name: "wrong password", err: errors.New("auth error (399): Wrong password"), want: common.AccountNeedsReauth},
+		{name: "acid challenge", err: errors.New("auth error: LoginAcid"), want: common.AccountLocked},
+		{name: "denied", err: errors.New("auth error: DenyLoginSubtask"), want: common.AccountLocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, loginErrorStatus(tt.err))
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	require.Zero(t, backoff(time.Minute, time.Hour, 0))
+	require.Equal(t, time.Minute, backoff(time.Minute, time.Hour, 1))
+	require.Equal(t, time.Minute*4, backoff(time.Minute, time.Hour, 3))
+	require.Equal(t, time.Hour, backoff(time.Minute, time.Hour, 100))
+}
+
+func TestManager_WarmUp(t *testing.T) {
+	ctx := context.Background()
+	m, r := newTestManager(common.TwitterAccount{Login: "bob"})
+
+	require.NoError(t, m.AddAccount(ctx, Config{Login: "alice", Password: "secret"}))
+	addedAt := r.accounts["alice"].AddedAt
+	require.NotZero(t, addedAt)
+
+	throttles, err := m.AddWarmUpThrottle(ctx, "alice")
+	require.NoError(t, err)
+	require.Equal(t, 1, throttles)
+
+	// adding the account again keeps its warm-up
+	require.NoError(t, m.AddAccount(ctx, Config{Login: "alice", Password: "secret"}))
+	require.Equal(t, addedAt, r.accounts["alice"].AddedAt)
+	require.Equal(t, 1, r.accounts["alice"].WarmUpThrottles)
+
+	// the accounts added before the warm-up are never warmed up
+	require.NoError(t, m.AddAccount(ctx, Config{Login: "bob", Password: "secret"}))
+	require.Zero(t, r.accounts["bob"].AddedAt)
+}
@@ -0,0 +1,29 @@
+package account_manager
+
+import "github.com/prometheus/client_golang/prometheus"
+
+type Metrics struct {
+	Transitions *prometheus.CounterVec // from, to
+	Accounts    *prometheus.GaugeVec   // status
+}
+
+func NewMetrics(namespace, subsystem string) *Metrics {
+	return &Metrics{
+		Transitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "account_transitions_total",
+			Help:      "Account status transitions",
+		}, []string{"from", "to"}),
+		Accounts: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "accounts",
+			Help:      "Accounts by status",
+		}, []string{"status"}),
+	}
+}
+
+func (m *Metrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.Transitions, m.Accounts}
+}
@@ -0,0 +1,134 @@
+package account_manager
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Formats of the account import and export.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown accounts format")
+	ErrWrongRecord   = errors.New("wrong account record")
+)
+
+// csvHeader is the first line of the CSV, the cookies column holds the JSON array of the cookies.
+var csvHeader = []string{"login", "password", "confirmation", "cookies"}
+
+// AccountRecord is the account with its credentials and cookies as it is imported and exported.
+type AccountRecord struct {
+	Login        string         `json:"login"`
+	Password     string         `json:"password"`
+	Confirmation string         `json:"confirmation,omitempty"`
+	Cookies      []*http.Cookie `json:"cookies,omitempty"`
+}
+
+func ReadRecords(r io.Reader, format string) ([]AccountRecord, error) {
+	var (
+		records []AccountRecord
+		err     error
+	)
+
+	switch format {
+	case FormatJSON:
+		err = jsoniter.NewDecoder(r).Decode(&records)
+	case FormatCSV:
+		records, err = readCSV(r)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, record := range records {
+		if record.Login == "" || record.Password == "" {
+			return nil, fmt.Errorf("%w: record %d has no login or password", ErrWrongRecord, i+1)
+		}
+	}
+
+	return records, nil
+}
+
+func WriteRecords(w io.Writer, format string, records []AccountRecord) error {
+	switch format {
+	case FormatJSON:
+		encoder := jsoniter.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(records)
+	case FormatCSV:
+		return writeCSV(w, records)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+func readCSV(r io.Reader) ([]AccountRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+
+	lines, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]AccountRecord, 0, len(lines))
+
+	for i, line := range lines {
+		if i == 0 && line[0] == csvHeader[0] {
+			continue
+		}
+
+		record := AccountRecord{Login: line[0], Password: line[1], Confirmation: line[2]}
+
+		if line[3] != "" {
+			if err = jsoniter.UnmarshalFromString(line[3], &record.Cookies); err != nil {
+				return nil, fmt.Errorf("%w: cookies of line %d: %w", ErrWrongRecord, i+1, err)
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func writeCSV(w io.Writer, records []AccountRecord) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		cookies := ""
+
+		if len(record.Cookies) != 0 {
+			data, err := jsoniter.MarshalToString(record.Cookies)
+			if err != nil {
+				return err
+			}
+
+			cookies = data
+		}
+
+		if err := writer.Write([]string{record.Login, record.Password, record.Confirmation, cookies}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
@@ -0,0 +1,75 @@
+package account_manager
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecords(t *testing.T) {
+	records := []AccountRecord{
+		{Login: "alice", Password: "secret, with comma", Cookies: []*http.Cookie{{Name: "auth_token", Value: "token"}}},
+		{Login: "bob", Password: "secret", Confirmation: "bob@example.com"},
+	}
+
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			require.NoError(t, WriteRecords(buf, format, records))
+
+			got, err := ReadRecords(buf, format)
+			require.NoError(t, err)
+			require.Equal(t, records, got)
+		})
+	}
+}
+
+func TestReadRecords(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   []AccountRecord
+		err    error
+	}{
+		{
+			name:   "csv without header",
+			format: FormatCSV,
+			data:   "alice,secret,,\n",
+			want:   []AccountRecord{{Login: "alice", Password: "secret"}},
+		},
+		{
+			name:   "csv without password",
+			format: FormatCSV,
+			data:   "login,password,confirmation,cookies\nalice,,,\n",
+			err:    ErrWrongRecord,
+		},
+		{
+			name:   "csv with broken cookies",
+			format: FormatCSV,
+			data:   "alice,secret,,[{\n",
+			err:    ErrWrongRecord,
+		},
+		{
+			name:   "unknown format",
+			format: "yaml",
+			err:    ErrUnknownFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadRecords(strings.NewReader(tt.data), tt.format)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package account_manager
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/source"
+)
+
+// login subtasks and API messages which mean the account has to be unlocked by its owner
+var lockedLoginErrors = []string{"LoginAcid", "DenyLoginSubtask", "temporarily locked"}
+
+// errorStatus maps the finder error to the account status, false means the error tells nothing about the account.
+func errorStatus(err error) (common.AccountStatus, bool) {
+	switch {
+	case err == nil:
+		return common.AccountActive, true
+	case errors.Is(err, source.ErrSuspended):
+		return common.AccountDisabled, true
+	case errors.Is(err, source.ErrLocked):
+		return common.AccountLocked, true
+	case errors.Is(err, source.ErrUnauthorized):
+		return common.AccountNeedsReauth, true
+	case errors.Is(err, source.ErrRateLimited):
+		return common.AccountRateLimited, true
+	default:
+		return "", false
+	}
+}
+
+func loginErrorStatus(err error) common.AccountStatus {
+	for _, message := range lockedLoginErrors {
+		if strings.Contains(err.Error(), message) {
+			return common.AccountLocked
+		}
+	}
+
+	return common.AccountNeedsReauth
+}
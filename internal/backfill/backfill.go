@@ -0,0 +1,247 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+)
+
+const (
+	day        = 24 * time.Hour
+	queryKey   = "query"
+	sinceKey   = "since"
+	untilKey   = "until"
+	savedKey   = "saved"
+	workersKey = "workers"
+)
+
+var (
+	ErrWrongSlice = errors.New("backfill slice must be a positive number of days")
+	ErrWrongRange = errors.New("backfill range must end after it starts")
+	ErrFailed     = errors.New("backfill slices failed")
+)
+
+// Backfiller walks a past date range of the queries slice by slice and stores the found tweets.
+type Backfiller interface {
+	// Run backfills the queries between from and to, the finished slices are skipped after a restart.
+	Run(ctx context.Context, queries []common.SearchQuery, from, to time.Time) error
+}
+
+type finder interface {
+	FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error)
+}
+
+type repo interface {
+	Save(ctx context.Context, tweets []common.TweetSnapshot) error
+	SaveBackfillCheckpoint(ctx context.Context, checkpoint common.BackfillCheckpoint) error
+	GetBackfillCheckpoint(ctx context.Context, query string, since, until time.Time) (common.BackfillCheckpoint, error)
+}
+
+type job struct {
+	query common.SearchQuery
+	since time.Time
+	until time.Time
+}
+
+func (j job) String() string {
+	return j.query.Name + " " + j.since.Format(time.DateOnly) + ".." + j.until.Format(time.DateOnly)
+}
+
+type backfiller struct {
+	finder finder
+	repo   repo
+
+	config *Config
+	logger log.Logger
+}
+
+func (b *backfiller) Run(ctx context.Context, queries []common.SearchQuery, from, to time.Time) error {
+	jobs, err := b.jobs(queries, from, to)
+	if err != nil {
+		return err
+	}
+
+	b.logger.WithField("slices", len(jobs)).WithField(workersKey, b.config.Workers).Info("backfill started")
+
+	ch := make(chan job)
+	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+	failed := make([]string, 0)
+
+	for i := 0; i < b.config.Workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range ch {
+				if err := b.backfill(ctx, j); err != nil && ctx.Err() == nil {
+					mu.Lock()
+					failed = append(failed, j.String())
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, j := range jobs {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case ch <- j:
+		}
+	}
+
+	close(ch)
+	wg.Wait()
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("%w: %s", ErrFailed, strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// jobs slices the range by whole days, the search syntax has no finer date bounds.
+func (b *backfiller) jobs(queries []common.SearchQuery, from, to time.Time) ([]job, error) {
+	if b.config.Slice < day || b.config.Slice%day != 0 {
+		return nil, ErrWrongSlice
+	}
+
+	from = from.UTC().Truncate(day)
+	to = to.UTC()
+
+	if !from.Before(to) {
+		return nil, ErrWrongRange
+	}
+
+	jobs := make([]job, 0)
+
+	for _, query := range queries {
+		for since := from; since.Before(to); since = since.Add(b.config.Slice) {
+			jobs = append(jobs, job{query: query, since: since, until: since.Add(b.config.Slice)})
+		}
+	}
+
+	return jobs, nil
+}
+
+// backfill searches the slice page by page, the cursor is checkpointed after every saved page.
+// The slice is given up after the configured number of failures in a row.
+func (b *backfiller) backfill(ctx context.Context, j job) error {
+	logger := b.logger.WithField(queryKey, j.query.Name).WithField(sinceKey, j.since).WithField(untilKey, j.until)
+	search := j.query.Between(j.since, j.until)
+
+	fresh := common.BackfillCheckpoint{Query: j.query.Name, Search: search.Render(), Since: j.since, Until: j.until}
+
+	checkpoint, err := b.repo.GetBackfillCheckpoint(ctx, j.query.Name, j.since, j.until)
+
+	switch {
+	case errors.Is(err, fdb.ErrBackfillCheckpointNotFound):
+		checkpoint = fresh
+	case err != nil:
+		logger.WithError(err).Error("get backfill checkpoint")
+		return err
+	case checkpoint.Search != fresh.Search:
+		// the cursor of another search would continue a foreign result
+		logger.WithField("search", checkpoint.Search).Warn("restart slice of changed query")
+
+		checkpoint = fresh
+	}
+
+	if checkpoint.Done {
+		logger.Debug("skip finished slice")
+		return nil
+	}
+
+	failures := 0
+
+	for !checkpoint.Done {
+		if failures > b.config.MaxRetries {
+			logger.WithError(err).WithField("failures", failures).Error("slice failed")
+			return err
+		}
+
+		var (
+			tweets     []common.TweetSnapshot
+			nextCursor string
+		)
+
+		tweets, nextCursor, err = b.finder.FindNext(ctx, search, checkpoint.Cursor)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			logger.WithError(err).Error("find tweets")
+			failures++
+			b.wait(ctx)
+
+			continue
+		}
+
+		for i := range tweets {
+			tweets[i].Backfilled = true
+		}
+
+		if err = b.repo.Save(ctx, tweets); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			logger.WithError(err).Error("save tweets")
+			failures++
+			b.wait(ctx)
+
+			continue
+		}
+
+		failures = 0
+
+		checkpoint.Saved += len(tweets)
+		checkpoint.Done = len(tweets) == 0 || nextCursor == "" || nextCursor == checkpoint.Cursor
+		checkpoint.Cursor = nextCursor
+
+		if err = b.repo.SaveBackfillCheckpoint(context.WithoutCancel(ctx), checkpoint); err != nil {
+			logger.WithError(err).Error("save backfill checkpoint")
+		}
+	}
+
+	logger.WithField(savedKey, checkpoint.Saved).Info("slice backfilled")
+
+	return nil
+}
+
+func (b *backfiller) wait(ctx context.Context) {
+	timer := time.NewTimer(b.config.RetryInterval)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// NewBackfiller creates a backfiller, the finder is expected to be the pool which keeps the accounts within their limits.
+func NewBackfiller(config *Config, finder finder, repo repo, logger log.Logger) Backfiller {
+	return &backfiller{
+		finder: finder,
+		repo:   repo,
+		config: config,
+		logger: logger,
+	}
+}
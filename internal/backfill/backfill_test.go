@@ -0,0 +1,152 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+// pagedFinder returns two pages for every search and records the requests.
+type pagedFinder struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *pagedFinder) FindNext(_ context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, query.Render()+" @"+cursor)
+
+	if cursor == "p2" {
+		return nil, "", nil
+	}
+
+	next := "p1"
+	if cursor == "p1" {
+		next = "p2"
+	}
+
+	id := query.Since.Format("0102") + next
+	tweet := common.TweetSnapshot{Tweet: &common.Tweet{ID: id, TimeParsed: query.Since}, CheckedAt: time.Now()}
+
+	return []common.TweetSnapshot{tweet}, next, nil
+}
+
+func TestBackfiller_Run(t *testing.T) {
+	ctx := context.Background()
+	repo := fdb.NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	finder := new(pagedFinder)
+	query := common.KeywordQuery("btc", "bitcoin", time.Minute)
+	day1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(day)
+
+	// the first day was interrupted after a page, the second one is finished
+	require.NoError(t, repo.SaveBackfillCheckpoint(ctx, common.BackfillCheckpoint{
+		Query: "btc", Search: query.Between(day1, day2).Render(), Since: day1, Until: day2, Cursor: "p1", Saved: 1,
+	}))
+	require.NoError(t, repo.SaveBackfillCheckpoint(ctx, common.BackfillCheckpoint{
+		Query: "btc", Search: query.Between(day2, day2.Add(day)).Render(), Since: day2, Until: day2.Add(day), Done: true,
+	}))
+
+	backfiller := NewBackfiller(&Config{Slice: day, Workers: 1, RetryInterval: time.Millisecond}, finder, repo, log.NewLogger(logrus.New()))
+
+	require.NoError(t, backfiller.Run(ctx, []common.SearchQuery{query}, day1.Add(time.Hour), day2.Add(day+time.Hour)))
+	require.Equal(t, []string{
+		"bitcoin -filter:retweets since:2024-01-01 until:2024-01-02 @p1",
+		"bitcoin -filter:retweets since:2024-01-01 until:2024-01-02 @p2",
+		"bitcoin -filter:retweets since:2024-01-03 until:2024-01-04 @",
+		"bitcoin -filter:retweets since:2024-01-03 until:2024-01-04 @p1",
+		"bitcoin -filter:retweets since:2024-01-03 until:2024-01-04 @p2",
+	}, finder.requests)
+
+	checkpoint, err := repo.GetBackfillCheckpoint(ctx, "btc", day1, day2)
+	require.NoError(t, err)
+	require.True(t, checkpoint.Done)
+	require.Equal(t, 2, checkpoint.Saved)
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, count)
+
+	require.ErrorIs(t, backfiller.Run(ctx, []common.SearchQuery{query}, day2, day1), ErrWrongRange)
+
+	backfiller = NewBackfiller(&Config{Slice: time.Hour, Workers: 1}, finder, repo, log.NewLogger(logrus.New()))
+	require.ErrorIs(t, backfiller.Run(ctx, []common.SearchQuery{query}, day1, day2), ErrWrongSlice)
+}
+
+func TestBackfiller_RunChangedSlices(t *testing.T) {
+	ctx := context.Background()
+	repo := fdb.NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	finder := new(pagedFinder)
+	query := common.KeywordQuery("btc", "bitcoin", time.Minute)
+	day1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(day)
+
+	// the finished day slice is not taken for the two days slice, the changed query restarts the slice
+	require.NoError(t, repo.SaveBackfillCheckpoint(ctx, common.BackfillCheckpoint{
+		Query: "btc", Search: query.Between(day1, day2).Render(), Since: day1, Until: day2, Done: true,
+	}))
+	require.NoError(t, repo.SaveBackfillCheckpoint(ctx, common.BackfillCheckpoint{
+		Query: "btc", Search: "btc since:2024-01-01 until:2024-01-03", Since: day1, Until: day2.Add(day), Cursor: "p1",
+	}))
+
+	backfiller := NewBackfiller(&Config{Slice: 2 * day, Workers: 1, RetryInterval: time.Millisecond}, finder, repo, log.NewLogger(logrus.New()))
+
+	require.NoError(t, backfiller.Run(ctx, []common.SearchQuery{query}, day1, day2.Add(day)))
+	require.Equal(t, []string{
+		"bitcoin -filter:retweets since:2024-01-01 until:2024-01-03 @",
+		"bitcoin -filter:retweets since:2024-01-01 until:2024-01-03 @p1",
+		"bitcoin -filter:retweets since:2024-01-01 until:2024-01-03 @p2",
+	}, finder.requests)
+}
+
+// failingFinder fails every search of the query with the keyword.
+type failingFinder struct {
+	pagedFinder
+	keyword string
+}
+
+func (f *failingFinder) FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
+	if query.Keywords[0] == f.keyword {
+		return nil, "", errors.New("search failed")
+	}
+
+	return f.pagedFinder.FindNext(ctx, query, cursor)
+}
+
+func TestBackfiller_RunFailedSlices(t *testing.T) {
+	ctx := context.Background()
+	repo := fdb.NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	finder := &failingFinder{keyword: "ethereum"}
+	day1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	backfiller := NewBackfiller(
+		&Config{Slice: day, Workers: 2, RetryInterval: time.Millisecond, MaxRetries: 2},
+		finder,
+		repo,
+		log.NewLogger(logrus.New()),
+	)
+
+	err := backfiller.Run(ctx, []common.SearchQuery{
+		common.KeywordQuery("btc", "bitcoin", time.Minute),
+		common.KeywordQuery("eth", "ethereum", time.Minute),
+	}, day1, day1.Add(2*day))
+	require.ErrorIs(t, err, ErrFailed)
+	require.ErrorContains(t, err, "eth 2024-01-01..2024-01-02, eth 2024-01-02..2024-01-03")
+	require.NotContains(t, err.Error(), "btc")
+
+	checkpoint, err := repo.GetBackfillCheckpoint(ctx, "btc", day1.Add(day), day1.Add(2*day))
+	require.NoError(t, err)
+	require.True(t, checkpoint.Done)
+}
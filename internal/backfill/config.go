@@ -0,0 +1,25 @@
+package backfill
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Config struct {
+	// Slice is the date range of one search, the search syntax bounds it by days.
+	Slice         time.Duration `envconfig:"SLICE" default:"24h"`
+	Workers       int           `envconfig:"WORKERS" default:"2"`
+	RetryInterval time.Duration `envconfig:"RETRY_INTERVAL" default:"1m"`
+	// MaxRetries is the number of failures in a row the slice is given up after.
+	MaxRetries int `envconfig:"MAX_RETRIES" default:"5"`
+}
+
+func GetConfig() *Config {
+	cfg := new(Config)
+	if err := envconfig.Process("BACKFILL", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
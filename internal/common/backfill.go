@@ -0,0 +1,16 @@
+package common
+
+import "time"
+
+// BackfillCheckpoint is the progress of a backfill of the query between Since and Until.
+// Cursor continues the slice search after a restart, Done slices are skipped.
+// Search is the rendered search of the slice, the checkpoint of a changed query is not resumed.
+type BackfillCheckpoint struct {
+	Query  string
+	Search string
+	Since  time.Time
+	Until  time.Time
+	Cursor string
+	Saved  int
+	Done   bool
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"math"
+	"time"
+)
+
+// RatingCurve describes the expected rating of a tweet depending on its age.
+// Speed is the linear growth in rating per second, it is used alone when Limit is not set.
+// Otherwise the rating saturates as Limit * (1 - exp(-age/Tau)).
+type RatingCurve struct {
+	Speed float64
+	Limit float64
+	Tau   time.Duration
+}
+
+func LinearCurve(speed float64) RatingCurve {
+	return RatingCurve{Speed: speed}
+}
+
+func (c RatingCurve) IsSaturating() bool {
+	return c.Limit > 0 && c.Tau > 0
+}
+
+// Predict returns the expected rating at the given age.
+func (c RatingCurve) Predict(age time.Duration) float64 {
+	if age <= 0 {
+		return 0
+	}
+
+	if !c.IsSaturating() {
+		return c.Speed * age.Seconds()
+	}
+
+	return c.Limit * (1 - math.Exp(-age.Seconds()/c.Tau.Seconds()))
+}
+
+// TimeToTop returns how long after the given age the rating is expected to reach top.
+// The second value is false when the top is unreachable.
+func (c RatingCurve) TimeToTop(age time.Duration, top float64) (time.Duration, bool) {
+	var reachedAt float64
+
+	switch {
+	case c.IsSaturating():
+		if c.Limit <= top {
+			return 0, false
+		}
+
+		reachedAt = -c.Tau.Seconds() * math.Log(1-top/c.Limit)
+	case c.Speed > 0:
+		reachedAt = top / c.Speed
+	default:
+		return 0, false
+	}
+
+	left := time.Duration(reachedAt*float64(time.Second)) - age
+	if left < 0 {
+		return 0, true
+	}
+
+	return left, true
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRatingCurve_TimeToTop(t *testing.T) {
+	curve := RatingCurve{Speed: 1, Limit: 1000, Tau: time.Hour}
+
+	left, ok := curve.TimeToTop(0, 500)
+	require.True(t, ok)
+	require.InDelta(t, time.Hour.Seconds()*math.Ln2, left.Seconds(), 1)
+	require.InDelta(t, 500, curve.Predict(left), 0.1)
+
+	left, ok = curve.TimeToTop(2*time.Hour, 500)
+	require.True(t, ok)
+	require.Zero(t, left)
+
+	_, ok = curve.TimeToTop(0, 1000)
+	require.False(t, ok)
+}
@@ -0,0 +1,14 @@
+package common
+
+import "time"
+
+// SearchCursor is the progress of a deep search which walks the query results from Cursor back to Start.
+// End is the time the cursor was taken at, the next cursor of the query starts from it.
+// Search keeps the query the cursor was taken for, cursors are not valid for other searches.
+type SearchCursor struct {
+	Query  string
+	Search SearchQuery
+	Start  time.Time
+	End    time.Time
+	Cursor string
+}
@@ -0,0 +1,190 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const searchDateFormat = "2006-01-02"
+
+var (
+	ErrEmptySearchQueryName  = errors.New("search query name must be set")
+	ErrEmptySearchQuery      = errors.New("search query must have keywords, terms or users")
+	ErrEmptySearchTerm       = errors.New("search query terms must not be empty")
+	ErrWrongSearchUsername   = errors.New("wrong username in search query")
+	ErrWrongSearchLanguage   = errors.New("wrong language in search query")
+	ErrNegativeSearchMinimum = errors.New("search query minimums must not be negative")
+	ErrWrongSearchDates      = errors.New("search query since must be before until")
+
+	usernameRegexp = regexp.MustCompile(`^[A-Za-z0-9_]{1,15}$`)
+	languageRegexp = regexp.MustCompile(`^[a-z]{2,3}$`)
+)
+
+// SearchQuery is a search watched by the scrapper, it is rendered to the Twitter search syntax.
+// Every one of Keywords must match and any one of AnyTerms must match. From and To are the lists of users,
+// the tweet must be written by any one of From and address any one of To.
+// Replies, Retweets and Quotes include the corresponding tweets, they are filtered out otherwise.
+// Zero Since and Until are not bounded. Source selects the ingestion backend, empty is the scraper.
+type SearchQuery struct {
+	Name           string
+	Enabled        bool
+	SearchInterval time.Duration
+	Source         string
+
+	Keywords    []string
+	AnyTerms    []string
+	From        []string
+	To          []string
+	Lang        string
+	MinFaves    int
+	MinRetweets int
+	Replies     bool
+	Retweets    bool
+	Quotes      bool
+	Since       time.Time
+	Until       time.Time
+}
+
+// KeywordQuery builds an enabled query searching for the words of the text, replies and quotes are included.
+func KeywordQuery(name, text string, interval time.Duration) SearchQuery {
+	return SearchQuery{
+		Name:           name,
+		Enabled:        true,
+		SearchInterval: interval,
+		Keywords:       strings.Fields(text),
+		Replies:        true,
+		Quotes:         true,
+	}
+}
+
+// Render returns the query in the Twitter search syntax.
+func (q SearchQuery) Render() string {
+	parts := make([]string, 0, len(q.Keywords)+8) //nolint:gomnd
+
+	for _, keyword := range q.Keywords {
+		parts = append(parts, quoteTerm(keyword))
+	}
+
+	parts = appendAlternatives(parts, "", q.AnyTerms)
+	parts = appendAlternatives(parts, "from:", q.From)
+	parts = appendAlternatives(parts, "to:", q.To)
+
+	if q.Lang != "" {
+		parts = append(parts, "lang:"+q.Lang)
+	}
+
+	if q.MinFaves > 0 {
+		parts = append(parts, fmt.Sprintf("min_faves:%d", q.MinFaves))
+	}
+
+	if q.MinRetweets > 0 {
+		parts = append(parts, fmt.Sprintf("min_retweets:%d", q.MinRetweets))
+	}
+
+	if !q.Replies {
+		parts = append(parts, "-filter:replies")
+	}
+
+	if !q.Retweets {
+		parts = append(parts, "-filter:retweets")
+	}
+
+	if !q.Quotes {
+		parts = append(parts, "-filter:quote")
+	}
+
+	if !q.Since.IsZero() {
+		parts = append(parts, "since:"+q.Since.UTC().Format(searchDateFormat))
+	}
+
+	if !q.Until.IsZero() {
+		parts = append(parts, "until:"+q.Until.UTC().Format(searchDateFormat))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Between returns the copy of the query bounded by the dates, zero values keep the query bounds.
+func (q SearchQuery) Between(since, until time.Time) SearchQuery {
+	if !since.IsZero() {
+		q.Since = since
+	}
+
+	if !until.IsZero() {
+		q.Until = until
+	}
+
+	return q
+}
+
+func (q SearchQuery) Validate() error {
+	if q.Name == "" {
+		return ErrEmptySearchQueryName
+	}
+
+	if len(q.Keywords)+len(q.AnyTerms)+len(q.From)+len(q.To) == 0 {
+		return ErrEmptySearchQuery
+	}
+
+	for _, term := range append(append([]string{}, q.Keywords...), q.AnyTerms...) {
+		if strings.TrimSpace(term) == "" {
+			return ErrEmptySearchTerm
+		}
+	}
+
+	for _, username := range append(append([]string{}, q.From...), q.To...) {
+		if !usernameRegexp.MatchString(username) {
+			return fmt.Errorf("%w: %q", ErrWrongSearchUsername, username)
+		}
+	}
+
+	if q.Lang != "" && !languageRegexp.MatchString(q.Lang) {
+		return fmt.Errorf("%w: %q", ErrWrongSearchLanguage, q.Lang)
+	}
+
+	if q.MinFaves < 0 || q.MinRetweets < 0 {
+		return ErrNegativeSearchMinimum
+	}
+
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return ErrWrongSearchDates
+	}
+
+	return nil
+}
+
+// Equal compares queries by value, queries are not comparable with == because of the slices.
+func (q SearchQuery) Equal(other SearchQuery) bool {
+	return q.Name == other.Name &&
+		q.Enabled == other.Enabled &&
+		q.SearchInterval == other.SearchInterval &&
+		q.Source == other.Source &&
+		q.Render() == other.Render()
+}
+
+func quoteTerm(term string) string {
+	if strings.ContainsAny(term, " \t") {
+		return `"` + term + `"`
+	}
+
+	return term
+}
+
+func appendAlternatives(parts []string, prefix string, values []string) []string {
+	switch len(values) {
+	case 0:
+		return parts
+	case 1:
+		return append(parts, prefix+quoteTerm(values[0]))
+	}
+
+	alternatives := make([]string, 0, len(values))
+	for _, value := range values {
+		alternatives = append(alternatives, prefix+quoteTerm(value))
+	}
+
+	return append(parts, "("+strings.Join(alternatives, " OR ")+")")
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchQuery_Render(t *testing.T) {
+	day := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  string
+	}{
+		{
+			name:  "keywords",
+			query: KeywordQuery("btc", "bitcoin", time.Minute),
+			want:  "bitcoin -filter:retweets",
+		},
+		{
+			name: "all filters",
+			query: SearchQuery{
+				Keywords:    []string{"bitcoin", "spot etf"},
+				AnyTerms:    []string{"sec", "approval"},
+				From:        []string{"elonmusk", "saylor"},
+				To:          []string{"cz_binance"},
+				Lang:        "en",
+				MinFaves:    100,
+				MinRetweets: 10,
+				Retweets:    true,
+			},
+			want: `bitcoin "spot etf" (sec OR approval) (from:elonmusk OR from:saylor) to:cz_binance lang:en ` +
+				"min_faves:100 min_retweets:10 -filter:replies -filter:quote",
+		},
+		{
+			name:  "since keeps the filters",
+			query: KeywordQuery("btc", "bitcoin", time.Minute).Between(day, day.Add(24*time.Hour)),
+			want:  "bitcoin -filter:retweets since:2024-01-02 until:2024-01-03",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.query.Render())
+		})
+	}
+}
+
+func TestSearchQuery_Validate(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  error
+	}{
+		{name: "valid", query: SearchQuery{Name: "btc", Keywords: []string{"bitcoin"}, Lang: "en"}},
+		{name: "users only", query: SearchQuery{Name: "musk", From: []string{"elonmusk"}}},
+		{name: "no name", query: SearchQuery{Keywords: []string{"bitcoin"}}, want: ErrEmptySearchQueryName},
+		{name: "no terms", query: SearchQuery{Name: "btc"}, want: ErrEmptySearchQuery},
+		{name: "blank term", query: SearchQuery{Name: "btc", AnyTerms: []string{" "}}, want: ErrEmptySearchTerm},
+		{name: "wrong username", query: SearchQuery{Name: "btc", From: []string{"@elon musk"}}, want: ErrWrongSearchUsername},
+		{name: "wrong language", query: SearchQuery{Name: "btc", Keywords: []string{"bitcoin"}, Lang: "English"}, want: ErrWrongSearchLanguage},
+		{name: "negative minimum", query: SearchQuery{Name: "btc", Keywords: []string{"bitcoin"}, MinFaves: -1}, want: ErrNegativeSearchMinimum},
+		{
+			name:  "until before since",
+			query: SearchQuery{Name: "btc", Keywords: []string{"bitcoin"}, Since: day, Until: day.Add(-time.Hour)},
+			want:  ErrWrongSearchDates,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorIs(t, tt.query.Validate(), tt.want)
+		})
+	}
+}
@@ -40,20 +40,63 @@ type Video struct {
 type TweetSnapshot struct {
 	*Tweet
 	RatingGrowSpeed float64
+	RatingCurve     RatingCurve
 	CheckedAt       time.Time
+	// Backfilled tweets are found by the backfill, they are kept past the cleaning of the too old tweets.
+	Backfilled bool
 }
 
 type TweetSnapshotIndex struct {
 	ID              string
 	Key             fdb.Key `json:"-"`
 	RatingGrowSpeed float64
+	RatingCurve     RatingCurve
 	CreatedAt       time.Time
 	CheckedAt       time.Time
 }
 
+// Curve returns the stored rating curve, indexes written before curves existed fall back to the linear speed.
+func (t *TweetSnapshotIndex) Curve() RatingCurve {
+	if t.RatingCurve == (RatingCurve{}) {
+		return LinearCurve(t.RatingGrowSpeed)
+	}
+
+	return t.RatingCurve
+}
+
 func (t TweetSnapshot) String() string {
 	return fmt.Sprintf(
 		"TweetSnapshot{ID: %s, CreatedAt: %s, RatingGrowSpeed: %0.3f, CheckedAt: %s}",
 		t.ID, t.TimeParsed.Format(time.RFC3339), t.RatingGrowSpeed, t.CheckedAt.Format(time.RFC3339),
 	)
 }
+
+// TweetEngagement is a single point of the tweet engagement history.
+type TweetEngagement struct {
+	CheckedAt       time.Time
+	Likes           int
+	Retweets        int
+	Replies         int
+	Views           int
+	RatingGrowSpeed float64
+}
+
+func (t TweetSnapshot) Engagement() TweetEngagement {
+	return TweetEngagement{
+		CheckedAt:       t.CheckedAt,
+		Likes:           t.Likes,
+		Retweets:        t.Retweets,
+		Replies:         t.Replies,
+		Views:           t.Views,
+		RatingGrowSpeed: t.RatingGrowSpeed,
+	}
+}
+
+// HistoryRetention describes how the engagement history is compacted.
+// Points younger than KeepRaw are kept as is, older ones are thinned to one point per Step,
+// and points older than MaxAge are removed.
+type HistoryRetention struct {
+	KeepRaw time.Duration
+	Step    time.Duration
+	MaxAge  time.Duration
+}
@@ -1,7 +1,46 @@
 package common
 
+import "time"
+
+// AccountStatus is the health of the Twitter account, the empty status of the accounts saved before is active.
+type AccountStatus string
+
+const (
+	AccountActive      AccountStatus = "active"
+	AccountRateLimited AccountStatus = "rate_limited"
+	AccountNeedsReauth AccountStatus = "needs_reauth"
+	AccountLocked      AccountStatus = "locked"
+	AccountDisabled    AccountStatus = "disabled"
+)
+
 type TwitterAccount struct {
 	Login        string
 	AccessToken  string
 	Confirmation string
+
+	Status          AccountStatus
+	StatusReason    string
+	StatusChangedAt time.Time
+	// Failures counts the failed re-login attempts in a row.
+	Failures int
+	// RetryAt is the time when the account can be authenticated again.
+	RetryAt        time.Time
+	CookiesSavedAt time.Time
+	// AddedAt starts the warm-up of the account, it is zero for the accounts added before the warm-up.
+	AddedAt time.Time
+	// WarmUpThrottles counts the throttles of the warming up account, every one of them moves its ramp back.
+	WarmUpThrottles int
+}
+
+func (a TwitterAccount) CurrentStatus() AccountStatus {
+	if a.Status == "" {
+		return AccountActive
+	}
+
+	return a.Status
+}
+
+// Usable tells whether the account can serve requests, rate limited accounts are slowed down by their delay managers.
+func (s AccountStatus) Usable() bool {
+	return s == "" || s == AccountActive || s == AccountRateLimited
 }
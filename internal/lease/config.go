@@ -0,0 +1,36 @@
+package lease
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Config struct {
+	// TTL is how long the lease of the dead replica keeps its accounts.
+	TTL               time.Duration `envconfig:"TTL" default:"30s"`
+	HeartbeatInterval time.Duration `envconfig:"HEARTBEAT_INTERVAL" default:"10s"`
+	// ReplicaID is the hostname with a random suffix by default, so the processes on one host never share the leases.
+	ReplicaID string `envconfig:"REPLICA_ID"`
+}
+
+func GetConfig() *Config {
+	cfg := new(Config)
+	if err := envconfig.Process("LEASE", cfg); err != nil {
+		panic(err)
+	}
+
+	if cfg.ReplicaID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			panic(err)
+		}
+
+		cfg.ReplicaID = fmt.Sprintf("%s-%08x", hostname, rand.Uint32()) //nolint:gosec
+	}
+
+	return cfg
+}
@@ -0,0 +1,229 @@
+package lease
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+)
+
+const (
+	loginKey   = "login"
+	replicaKey = "replica"
+)
+
+// Leaser splits the Twitter accounts between the scrapper replicas. Every replica holds at most its fair share
+// of the accounts, the leases of the dead replica expire and are claimed by the others.
+type Leaser interface {
+	// Claim leases the free accounts out of the candidates up to the fair share and returns the leased ones,
+	// the candidates already leased by the replica are returned as well.
+	Claim(ctx context.Context, candidates []common.TwitterAccount) ([]common.TwitterAccount, error)
+	// Release gives up the lease, so another replica can take the account.
+	Release(ctx context.Context, login string)
+	// Run heartbeats the replica and renews the leases until the context is done. The leases which are lost
+	// or given up for the rebalance are passed to the callback.
+	Run(ctx context.Context, lost func(login string))
+	// Close releases all leases on shutdown.
+	Close(ctx context.Context) error
+}
+
+type repo interface {
+	GetAccounts(ctx context.Context) ([]common.TwitterAccount, error)
+	AcquireLease(ctx context.Context, login, owner string, ttl time.Duration) (bool, error)
+	RenewLease(ctx context.Context, login, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, login, owner string) error
+	Heartbeat(ctx context.Context, replica string, ttl time.Duration) error
+	DeleteReplica(ctx context.Context, replica string) error
+	CountReplicas(ctx context.Context) (int, error)
+}
+
+type leaser struct {
+	config *Config
+	repo   repo
+
+	mu   sync.Mutex
+	held map[string]struct{}
+
+	log log.Logger
+}
+
+func (l *leaser) Claim(ctx context.Context, candidates []common.TwitterAccount) ([]common.TwitterAccount, error) {
+	if err := l.repo.Heartbeat(ctx, l.config.ReplicaID, l.config.TTL); err != nil {
+		return nil, err
+	}
+
+	share, err := l.share(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	res := make([]common.TwitterAccount, 0, len(candidates))
+
+	for _, account := range candidates {
+		if _, ok := l.held[account.Login]; ok {
+			res = append(res, account)
+			continue
+		}
+
+		if len(l.held) >= share {
+			continue
+		}
+
+		acquired, err := l.repo.AcquireLease(ctx, account.Login, l.config.ReplicaID, l.config.TTL)
+		if err != nil {
+			return nil, err
+		}
+
+		if !acquired {
+			continue
+		}
+
+		l.held[account.Login] = struct{}{}
+		res = append(res, account)
+
+		l.log.WithField(loginKey, account.Login).Info("account leased")
+	}
+
+	return res, nil
+}
+
+func (l *leaser) Release(ctx context.Context, login string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.release(ctx, login)
+}
+
+func (l *leaser) Run(ctx context.Context, lost func(login string)) {
+	ticker := time.NewTicker(l.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, login := range l.renew(ctx) {
+				lost(login)
+			}
+		}
+	}
+}
+
+func (l *leaser) Close(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for login := range l.held {
+		l.release(ctx, login)
+	}
+
+	return l.repo.DeleteReplica(ctx, l.config.ReplicaID)
+}
+
+// renew keeps the replica and its leases alive and returns the lost leases and the ones over the fair share.
+func (l *leaser) renew(ctx context.Context) []string {
+	if err := l.repo.Heartbeat(ctx, l.config.ReplicaID, l.config.TTL); err != nil {
+		l.log.WithError(err).Error("error while heartbeat")
+	}
+
+	share, err := l.share(ctx)
+	if err != nil {
+		l.log.WithError(err).Error("error while counting the fair share")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	logins := make([]string, 0, len(l.held))
+	for login := range l.held {
+		logins = append(logins, login)
+	}
+
+	sort.Strings(logins)
+
+	lost := make([]string, 0)
+
+	for _, login := range logins {
+		renewed, err := l.repo.RenewLease(ctx, login, l.config.ReplicaID, l.config.TTL)
+		if err != nil {
+			// the lease is kept until it is lost for sure, the next renew may succeed before it expires
+			l.log.WithError(err).WithField(loginKey, login).Error("error while renewing lease")
+			continue
+		}
+
+		if !renewed {
+			delete(l.held, login)
+			lost = append(lost, login)
+
+			l.log.WithField(loginKey, login).Warn("account lease lost")
+		}
+	}
+
+	// the new replica gets its share from the ones holding more
+	for i := len(logins) - 1; share > 0 && len(l.held) > share && i >= 0; i-- {
+		if _, ok := l.held[logins[i]]; !ok {
+			continue
+		}
+
+		l.release(ctx, logins[i])
+		lost = append(lost, logins[i])
+	}
+
+	return lost
+}
+
+func (l *leaser) release(ctx context.Context, login string) {
+	if _, ok := l.held[login]; !ok {
+		return
+	}
+
+	delete(l.held, login)
+
+	if err := l.repo.ReleaseLease(ctx, login, l.config.ReplicaID); err != nil {
+		l.log.WithError(err).WithField(loginKey, login).Error("error while releasing lease")
+		return
+	}
+
+	l.log.WithField(loginKey, login).Info("account lease released")
+}
+
+// share is the number of accounts the replica may hold, the disabled accounts are not leased by anyone.
+func (l *leaser) share(ctx context.Context) (int, error) {
+	accounts, err := l.repo.GetAccounts(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+
+	for _, account := range accounts {
+		if account.CurrentStatus() != common.AccountDisabled {
+			total++
+		}
+	}
+
+	replicas, err := l.repo.CountReplicas(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	replicas = max(replicas, 1)
+
+	return (total + replicas - 1) / replicas, nil
+}
+
+func NewLeaser(config *Config, repo repo, logger log.Logger) Leaser {
+	return &leaser{
+		config: config,
+		repo:   repo,
+		held:   make(map[string]struct{}),
+		log:    logger.WithField(replicaKey, config.ReplicaID),
+	}
+}
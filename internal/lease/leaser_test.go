@@ -0,0 +1,144 @@
+package lease
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+)
+
+// memoryRepo does not expire anything, the tests drop the leases and replicas instead.
+type memoryRepo struct {
+	mu       sync.Mutex
+	accounts []common.TwitterAccount
+	leases   map[string]string
+	replicas map[string]struct{}
+}
+
+func (r *memoryRepo) GetAccounts(context.Context) ([]common.TwitterAccount, error) {
+	return r.accounts, nil
+}
+
+func (r *memoryRepo) AcquireLease(_ context.Context, login, owner string, _ time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.leases[login]; ok {
+		return false, nil
+	}
+
+	r.leases[login] = owner
+
+	return true, nil
+}
+
+func (r *memoryRepo) RenewLease(_ context.Context, login, owner string, _ time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.leases[login] == owner, nil
+}
+
+func (r *memoryRepo) ReleaseLease(_ context.Context, login, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.leases[login] == owner {
+		delete(r.leases, login)
+	}
+
+	return nil
+}
+
+func (r *memoryRepo) Heartbeat(_ context.Context, replica string, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.replicas[replica] = struct{}{}
+
+	return nil
+}
+
+func (r *memoryRepo) DeleteReplica(_ context.Context, replica string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.replicas, replica)
+
+	return nil
+}
+
+func (r *memoryRepo) CountReplicas(context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.replicas), nil
+}
+
+func logins(accounts []common.TwitterAccount) []string {
+	res := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		res = append(res, account.Login)
+	}
+
+	return res
+}
+
+func TestLeaser(t *testing.T) {
+	ctx := context.Background()
+	accounts := []common.TwitterAccount{
+		{Login: "alice"},
+		{Login: "bob"},
+		{Login: "carol"},
+		{Login: "dave"},
+		{Login: "eve", Status: common.AccountDisabled},
+	}
+	repo := &memoryRepo{accounts: accounts, leases: make(map[string]string), replicas: make(map[string]struct{})}
+	logger := log.NewLogger(logrus.New())
+	first := NewLeaser(&Config{ReplicaID: "first"}, repo, logger).(*leaser)
+	second := NewLeaser(&Config{ReplicaID: "second"}, repo, logger).(*leaser)
+
+	// the only replica takes all accounts
+	claimed, err := first.Claim(ctx, accounts[:4])
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice", "bob", "carol", "dave"}, logins(claimed))
+
+	// the new replica waits for the rebalance
+	claimed, err = second.Claim(ctx, accounts[:4])
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+	require.ElementsMatch(t, []string{"carol", "dave"}, first.renew(ctx))
+
+	claimed, err = second.Claim(ctx, accounts[:4])
+	require.NoError(t, err)
+	require.Equal(t, []string{"carol", "dave"}, logins(claimed))
+
+	// the account is offered to its holder again after the eviction
+	claimed, err = first.Claim(ctx, accounts[:1])
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice"}, logins(claimed))
+
+	// the expired lease taken by another replica is lost
+	repo.leases["bob"] = "third"
+	require.Equal(t, []string{"bob"}, first.renew(ctx))
+
+	// the leases of the closed replica are free for the others
+	require.NoError(t, second.Close(ctx))
+	require.Equal(t, map[string]string{"alice": "first", "bob": "third"}, repo.leases)
+
+	delete(repo.leases, "bob")
+
+	claimed, err = first.Claim(ctx, accounts[1:4])
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob", "carol", "dave"}, logins(claimed))
+
+	first.Release(ctx, "dave")
+	require.NotContains(t, repo.leases, "dave")
+	require.Empty(t, first.renew(ctx))
+}
@@ -0,0 +1,27 @@
+package predictor
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+const (
+	ModelLinear     = "linear"
+	ModelSaturating = "saturating"
+)
+
+type Config struct {
+	Model  string        `envconfig:"MODEL" default:"saturating"`
+	MinTau time.Duration `envconfig:"MIN_TAU" default:"5m"`
+	MaxTau time.Duration `envconfig:"MAX_TAU" default:"72h"`
+}
+
+func GetConfig() *Config {
+	cfg := new(Config)
+	if err := envconfig.Process("PREDICTOR", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
@@ -0,0 +1,62 @@
+package predictor
+
+import (
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+)
+
+// Point is the rating of a tweet observed at the given age.
+type Point struct {
+	Age    time.Duration
+	Rating float64
+}
+
+// Predictor estimates the rating curve of a tweet from its observed history.
+type Predictor interface {
+	Fit(points []Point) common.RatingCurve
+}
+
+// NewPredictor returns the predictor for the configured model.
+func NewPredictor(cfg *Config) Predictor {
+	if cfg.Model == ModelLinear {
+		return NewLinear()
+	}
+
+	return NewSaturating(cfg.MinTau, cfg.MaxTau)
+}
+
+type linear struct{}
+
+// Fit uses the latest point only, it matches the original likes per second rule.
+func (l linear) Fit(points []Point) common.RatingCurve {
+	last, ok := latest(points)
+	if !ok {
+		return common.RatingCurve{}
+	}
+
+	return common.LinearCurve(last.Rating / last.Age.Seconds())
+}
+
+func NewLinear() Predictor {
+	return linear{}
+}
+
+func latest(points []Point) (Point, bool) {
+	var (
+		res Point
+		ok  bool
+	)
+
+	for _, point := range points {
+		if point.Age <= 0 {
+			continue
+		}
+
+		if !ok || point.Age > res.Age {
+			res, ok = point, true
+		}
+	}
+
+	return res, ok
+}
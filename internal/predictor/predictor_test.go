@@ -0,0 +1,82 @@
+package predictor
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaturating_Fit(t *testing.T) {
+	p := NewSaturating(time.Minute, 72*time.Hour)
+
+	tests := []struct {
+		name          string
+		points        []Point
+		wantLinear    bool
+		wantSpeed     float64
+		wantLimit     float64
+		wantTauAround time.Duration
+	}{
+		{
+			name:       "single point falls back to linear",
+			points:     []Point{{Age: 100 * time.Second, Rating: 50}},
+			wantLinear: true,
+			wantSpeed:  0.5,
+		},
+		{
+			name:       "no likes",
+			points:     []Point{{Age: time.Minute}, {Age: 2 * time.Minute}},
+			wantLinear: true,
+		},
+		{
+			name:          "saturating growth",
+			points:        saturatingPoints(1000, time.Hour, 10*time.Minute, 20*time.Minute, 40*time.Minute, 80*time.Minute),
+			wantSpeed:     saturatingValue(1000, time.Hour, 80*time.Minute) / (80 * time.Minute).Seconds(),
+			wantLimit:     1000,
+			wantTauAround: time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curve := p.Fit(tt.points)
+
+			assert.InDelta(t, tt.wantSpeed, curve.Speed, 1e-9)
+
+			if tt.wantLinear {
+				require.False(t, curve.IsSaturating())
+				return
+			}
+
+			require.True(t, curve.IsSaturating())
+			assert.InEpsilon(t, tt.wantLimit, curve.Limit, 0.05)
+			assert.InEpsilon(t, tt.wantTauAround.Seconds(), curve.Tau.Seconds(), 0.1)
+		})
+	}
+}
+
+func TestLinear_Fit(t *testing.T) {
+	curve := NewLinear().Fit([]Point{{Age: 10 * time.Second, Rating: 1}, {Age: 20 * time.Second, Rating: 10}})
+	assert.InDelta(t, 0.5, curve.Speed, 1e-9)
+	assert.False(t, curve.IsSaturating())
+
+	left, ok := curve.TimeToTop(20*time.Second, 100)
+	require.True(t, ok)
+	assert.Equal(t, 180*time.Second, left)
+}
+
+func saturatingPoints(limit float64, tau time.Duration, ages ...time.Duration) []Point {
+	res := make([]Point, 0, len(ages))
+	for _, age := range ages {
+		res = append(res, Point{Age: age, Rating: saturatingValue(limit, tau, age)})
+	}
+
+	return res
+}
+
+func saturatingValue(limit float64, tau, age time.Duration) float64 {
+	return limit * (1 - math.Exp(-age.Seconds()/tau.Seconds()))
+}
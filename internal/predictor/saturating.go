@@ -0,0 +1,90 @@
+package predictor
+
+import (
+	"math"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+)
+
+const (
+	tauGridSize = 64
+	minPoints   = 2
+)
+
+type saturating struct {
+	linear
+
+	taus []time.Duration
+}
+
+// Fit searches the time constant on a log grid and solves the limit in closed form for each candidate,
+// keeping the pair with the least squared error. With a single point it falls back to the linear rule.
+func (s *saturating) Fit(points []Point) common.RatingCurve {
+	curve := s.linear.Fit(points)
+
+	usable := make([]Point, 0, len(points))
+	for _, point := range points {
+		if point.Age > 0 {
+			usable = append(usable, point)
+		}
+	}
+
+	if len(usable) < minPoints || curve.Speed <= 0 {
+		return curve
+	}
+
+	bestErr := math.Inf(1)
+
+	for _, tau := range s.taus {
+		limit, sqErr := fitLimit(usable, tau)
+		if limit <= 0 || sqErr >= bestErr {
+			continue
+		}
+
+		bestErr = sqErr
+		curve.Limit = limit
+		curve.Tau = tau
+	}
+
+	return curve
+}
+
+// fitLimit returns the least squares limit for the fixed tau and the residual error.
+func fitLimit(points []Point, tau time.Duration) (float64, float64) {
+	var num, den float64
+
+	basis := make([]float64, len(points))
+
+	for i, point := range points {
+		basis[i] = 1 - math.Exp(-point.Age.Seconds()/tau.Seconds())
+		num += basis[i] * point.Rating
+		den += basis[i] * basis[i]
+	}
+
+	if den == 0 {
+		return 0, math.Inf(1)
+	}
+
+	limit := num / den
+
+	var sqErr float64
+
+	for i, point := range points {
+		diff := point.Rating - limit*basis[i]
+		sqErr += diff * diff
+	}
+
+	return limit, sqErr
+}
+
+func NewSaturating(minTau, maxTau time.Duration) Predictor {
+	taus := make([]time.Duration, tauGridSize)
+	step := math.Pow(maxTau.Seconds()/minTau.Seconds(), 1/float64(tauGridSize-1))
+
+	for i := range taus {
+		taus[i] = time.Duration(minTau.Seconds() * math.Pow(step, float64(i)) * float64(time.Second))
+	}
+
+	return &saturating{taus: taus}
+}
@@ -5,47 +5,82 @@ import (
 	"errors"
 
 	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/predictor"
 )
 
 type RatingChecker interface {
-	Check(ctx context.Context, tweet *common.TweetSnapshot) (bool, float64, error)
+	Check(ctx context.Context, tweet *common.TweetSnapshot) (bool, common.RatingCurve, error)
 	CurrentTop() float64
 }
 
+type checkerRepo interface {
+	GetRating(ctx context.Context, username string) (common.Rating, error)
+	GetTweetHistory(ctx context.Context, id string) ([]common.TweetEngagement, error)
+}
+
 type checker struct {
-	repo
-	topCount int
+	repo      checkerRepo
+	predictor predictor.Predictor
+	topCount  int
 }
 
 func (c *checker) CurrentTop() float64 {
 	return float64(c.topCount)
 }
 
-func (c *checker) Check(ctx context.Context, tweet *common.TweetSnapshot) (bool, float64, error) {
-	liveDuration := tweet.CheckedAt.Sub(tweet.TimeParsed).Seconds()
-
-	likes := float64(tweet.Likes)
+func (c *checker) Check(ctx context.Context, tweet *common.TweetSnapshot) (bool, common.RatingCurve, error) {
+	var userRating *common.Rating
 
 	rating, err := c.repo.GetRating(ctx, tweet.Username)
+	switch {
+	case err == nil:
+		userRating = &rating
+	case !errors.Is(err, common.ErrRatingNotFound):
+		return false, common.RatingCurve{}, err
+	}
+
+	history, err := c.repo.GetTweetHistory(ctx, tweet.ID)
 	if err != nil {
-		if errors.Is(err, common.ErrRatingNotFound) {
-			return tweet.Likes > c.topCount, likes / liveDuration, nil
+		return false, common.RatingCurve{}, err
+	}
+
+	points := make([]predictor.Point, 0, len(history)+1)
+
+	for _, point := range history {
+		if !point.CheckedAt.Before(tweet.CheckedAt) {
+			continue
 		}
 
-		return false, 0, err
+		points = append(points, predictor.Point{
+			Age:    point.CheckedAt.Sub(tweet.TimeParsed),
+			Rating: rate(point.Likes, userRating),
+		})
+	}
+
+	current := rate(tweet.Likes, userRating)
+	points = append(points, predictor.Point{Age: tweet.CheckedAt.Sub(tweet.TimeParsed), Rating: current})
+
+	return current > float64(c.topCount), c.predictor.Fit(points), nil
+}
+
+// rate weights likes by the author rating, authors without rating are taken as is.
+func rate(likes int, rating *common.Rating) float64 {
+	if rating == nil {
+		return float64(likes)
 	}
 
-	rate := likes * (1.0 + float64(rating.Likes-rating.Dislikes)/10.0)
-	if rate == 0 && rating.Likes > rating.Dislikes {
-		rate = float64(rating.Likes-rating.Dislikes) * 10
+	res := float64(likes) * (1.0 + float64(rating.Likes-rating.Dislikes)/10.0)
+	if res == 0 && rating.Likes > rating.Dislikes {
+		res = float64(rating.Likes-rating.Dislikes) * 10
 	}
 
-	return rate > float64(c.topCount), rate / liveDuration, nil
+	return res
 }
 
-func NewChecker(db repo, topCount int) RatingChecker {
+func NewChecker(db checkerRepo, predictor predictor.Predictor, topCount int) RatingChecker {
 	return &checker{
-		repo:     db,
-		topCount: topCount,
+		repo:      db,
+		predictor: predictor,
+		topCount:  topCount,
 	}
 }
@@ -0,0 +1,54 @@
+package fdb
+
+import (
+	"context"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+)
+
+type backfillRepo interface {
+	SaveBackfillCheckpoint(ctx context.Context, checkpoint common.BackfillCheckpoint) error
+	GetBackfillCheckpoint(ctx context.Context, query string, since, until time.Time) (common.BackfillCheckpoint, error)
+}
+
+func (d *db) SaveBackfillCheckpoint(ctx context.Context, checkpoint common.BackfillCheckpoint) error {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := jsoniter.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tr.Set(d.keyBuilder.BackfillCheckpoint(checkpoint.Query, checkpoint.Since, checkpoint.Until), data)
+
+	return tr.Commit()
+}
+
+func (d *db) GetBackfillCheckpoint(ctx context.Context, query string, since, until time.Time) (common.BackfillCheckpoint, error) {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return common.BackfillCheckpoint{}, err
+	}
+
+	data, err := tr.Get(d.keyBuilder.BackfillCheckpoint(query, since, until))
+	if err != nil {
+		return common.BackfillCheckpoint{}, err
+	}
+
+	if data == nil {
+		return common.BackfillCheckpoint{}, ErrBackfillCheckpointNotFound
+	}
+
+	checkpoint := common.BackfillCheckpoint{}
+	if err = jsoniter.Unmarshal(data, &checkpoint); err != nil {
+		return common.BackfillCheckpoint{}, err
+	}
+
+	return checkpoint, tr.Commit()
+}
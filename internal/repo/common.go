@@ -9,3 +9,5 @@ var ErrRequestLimitsUnmarshallingError = errors.New("request limits unmarshallin
 var ErrAlreadyExists = errors.New("already exists")
 var ErrTwitterAccountNotFound = errors.New("no twitter account found")
 var ErrCookieNotFound = errors.New("no cookie found")
+var ErrSearchQueryNotFound = errors.New("no search query found")
+var ErrBackfillCheckpointNotFound = errors.New("no backfill checkpoint found")
@@ -2,12 +2,12 @@ package fdb
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/dgraph-io/ristretto"
 	"github.com/eko/gocache/lib/v4/cache"
 	ristrettoStore "github.com/eko/gocache/store/ristretto/v4"
-	"github.com/gotd/td/telegram"
 	"github.com/lueurxax/crypto-tweet-sense/internal/repo/model"
 	"github.com/sirupsen/logrus"
 
@@ -20,9 +20,14 @@ type DB interface {
 	Migrate(ctx context.Context) error
 	version
 	tweetRepo
+	tweetHistoryRepo
+	recheckQueueRepo
+	searchCursorsRepo
+	searchQueriesRepo
+	backfillRepo
 	requestLimiter
 	ratingRepo
-	telegram.SessionStorage
+	sessionRepo
 	editingTweetsRepo
 	twitterAccountsRepo
 }
@@ -32,12 +37,19 @@ type db struct {
 	db            fdbclient.Database
 	requestsCache *cache.Cache[*model.RequestLimitsV2]
 
-	tweetsCounter *int32
+	// tweetsCounter is loaded by the first Count, the saved and deleted tweets are counted after
+	tweetsCounter atomic.Pointer[int32]
 
 	log *logrus.Entry
 }
 
 func NewDB(fdb fdb.Database, log *logrus.Entry) DB {
+	return NewDBFromClient(fdbclient.NewDatabase(fdb), log)
+}
+
+// NewDBFromClient builds the repository on top of any fdbclient.Database implementation,
+// e.g. fdbclient.NewMemoryDatabase for tests and local runs.
+func NewDBFromClient(client fdbclient.Database, log *logrus.Entry) DB {
 	ristrettoCache, err := ristretto.NewCache(&ristretto.Config{
 		NumCounters: 1000,
 		MaxCost:     100000000,
@@ -49,7 +61,7 @@ func NewDB(fdb fdb.Database, log *logrus.Entry) DB {
 
 	return &db{
 		keyBuilder:    keys.NewBuilder(),
-		db:            fdbclient.NewDatabase(fdb),
+		db:            client,
 		requestsCache: cache.New[*model.RequestLimitsV2](ristrettoStore.NewRistretto(ristrettoCache)),
 		log:           log,
 	}
@@ -67,16 +79,43 @@ func (d *db) Migrate(ctx context.Context) error {
 	for _, el := range m {
 		d.log.WithField("version", el.Version()).Info("migrating to version")
 
+		if err = d.up(ctx, el); err != nil {
+			return err
+		}
+
+		if err = d.WriteVersion(ctx, el.Version()); err != nil {
+			return err
+		}
+
+		d.log.WithField("version", el.Version()).Info("migrated to version")
+	}
+
+	return nil
+}
+
+// up runs the migration in one transaction, the batch migrations run a transaction per batch.
+func (d *db) up(ctx context.Context, m migrations.Migration) error {
+	batched, ok := m.(migrations.BatchMigration)
+	if !ok {
 		tr, err := d.db.NewTransaction(ctx)
 		if err != nil {
 			return err
 		}
 
-		if err = el.Up(ctx, tr); err != nil {
+		if err = m.Up(ctx, tr); err != nil {
 			return err
 		}
 
-		if err = d.WriteVersion(ctx, el.Version()); err != nil {
+		return tr.Commit()
+	}
+
+	for from, batches := []byte(nil), 0; ; batches++ {
+		tr, err := d.db.NewTransaction(ctx)
+		if err != nil {
+			return err
+		}
+
+		if from, err = batched.UpBatch(ctx, tr, from); err != nil {
 			return err
 		}
 
@@ -84,10 +123,11 @@ func (d *db) Migrate(ctx context.Context) error {
 			return err
 		}
 
-		d.log.WithField("version", el.Version()).Info("migrated to version")
+		if from == nil {
+			d.log.WithField("version", m.Version()).WithField("batches", batches+1).Debug("migrated batches")
+			return nil
+		}
 	}
-
-	return nil
 }
 
 func (d *db) migratePrefix(ctx context.Context, prefix string, prefix2 keys.Prefix) error {
@@ -10,6 +10,8 @@ import (
 
 type Builder interface {
 	Version() []byte
+	MigrationFtoRVersion() []byte
+	MigrationFDBVersion() []byte
 	Tweets() []byte
 	Tweet(id string) []byte
 	TweetRatingIndexes() []byte
@@ -18,6 +20,12 @@ type Builder interface {
 	RequestLimits(id string, window time.Duration) []byte
 	Requests(id string, window time.Duration, start time.Time) []byte
 	RequestsByRequestLimits(id string, window time.Duration) []byte
+	AllRequestLimits() []byte
+	AllRequests() []byte
+	AllRequestBuckets() []byte
+	RequestBuckets(id string, window time.Duration) []byte
+	RequestBucket(id string, window time.Duration, start time.Time) []byte
+	RequestBucketsUntil(id string, window time.Duration, until time.Time) fdb.KeyRange
 	TweetUsernameRatingKey(username string) []byte
 	TweetRatings() []byte
 	SentTweet(link string) []byte
@@ -31,6 +39,26 @@ type Builder interface {
 	Cookie(login string) []byte
 	TweetCreationIndex(createdAt time.Time, id string) []byte
 	TweetUntil(createdAt time.Time) fdb.KeyRange
+	TweetsHistory() []byte
+	TweetHistory(id string) []byte
+	TweetHistoryPoint(id string, checkedAt time.Time) []byte
+	RecheckQueue() []byte
+	RecheckQueueItem(at time.Time, id string) []byte
+	RecheckQueueUntil(at time.Time) fdb.KeyRange
+	RechecksByTweet() []byte
+	RecheckByTweet(id string) []byte
+	SearchCursors() []byte
+	SearchCursor(query string, start time.Time) []byte
+	SearchWatermark(query string) []byte
+	SearchQueries() []byte
+	SearchQuery(name string) []byte
+	SearchQueriesSeeded() []byte
+	BackfillCheckpoints() []byte
+	BackfillCheckpoint(query string, since, until time.Time) []byte
+	ProxyAssignment(login string) []byte
+	AccountLease(login string) []byte
+	Replicas() []byte
+	Replica(id string) []byte
 }
 
 type builder struct {
@@ -100,10 +128,52 @@ func (b builder) RequestsByRequestLimits(id string, window time.Duration) []byte
 	return binary.LittleEndian.AppendUint16(slice, uint16(window.Seconds()))
 }
 
+func (b builder) AllRequestLimits() []byte {
+	return requestLimitPrefix[:]
+}
+
+func (b builder) AllRequests() []byte {
+	return requestsPrefix[:]
+}
+
+func (b builder) AllRequestBuckets() []byte {
+	return requestBucketPrefix[:]
+}
+
+// RequestBuckets is the prefix of the request time buckets of the limit, the buckets are ordered by their start.
+func (b builder) RequestBuckets(id string, window time.Duration) []byte {
+	slice := append(append(requestBucketPrefix[:], []byte(id)...), 0x00)
+	return binary.BigEndian.AppendUint16(slice, uint16(window.Seconds()))
+}
+
+func (b builder) RequestBucket(id string, window time.Duration, start time.Time) []byte {
+	return binary.BigEndian.AppendUint64(b.RequestBuckets(id, window), uint64(start.Unix()))
+}
+
+// RequestBucketsUntil is the range of the buckets started before the time.
+func (b builder) RequestBucketsUntil(id string, window time.Duration, until time.Time) fdb.KeyRange {
+	return fdb.KeyRange{
+		Begin: fdb.Key(b.RequestBuckets(id, window)),
+		End:   fdb.Key(b.RequestBucket(id, window, until)),
+	}
+}
+
 func (b builder) Version() []byte {
 	return versionPrefix[:]
 }
 
+// MigrationFtoRVersion is the version of the migrations from foundationdb to redis,
+// it is kept apart from the version of the foundationdb migrations.
+func (b builder) MigrationFtoRVersion() []byte {
+	return migrationFtoRVersionPrefix[:]
+}
+
+// MigrationFDBVersion is the version of the foundationdb migrations,
+// it is kept apart from the version of the migrations from foundationdb to redis.
+func (b builder) MigrationFDBVersion() []byte {
+	return migrationFDBVersionPrefix[:]
+}
+
 func (b builder) Tweets() []byte {
 	return tweetPrefix[:]
 }
@@ -146,6 +216,98 @@ func (b builder) TweetUntil(createdAt time.Time) fdb.KeyRange {
 	}
 }
 
+func (b builder) TweetsHistory() []byte {
+	return tweetHistoryPrefix[:]
+}
+
+// TweetHistory returns the prefix of all history points of the tweet, the separator keeps "1" apart from "12".
+func (b builder) TweetHistory(id string) []byte {
+	return append(append(tweetHistoryPrefix[:], []byte(id)...), 0x00)
+}
+
+func (b builder) TweetHistoryPoint(id string, checkedAt time.Time) []byte {
+	return binary.BigEndian.AppendUint64(b.TweetHistory(id), uint64(checkedAt.UTC().UnixNano()))
+}
+
+func (b builder) RecheckQueue() []byte {
+	return recheckQueuePrefix[:]
+}
+
+// RecheckQueueItem keys are ordered by the recheck time, so the due items are a prefix of the queue.
+func (b builder) RecheckQueueItem(at time.Time, id string) []byte {
+	return append(binary.BigEndian.AppendUint64(recheckQueuePrefix[:], uint64(at.UTC().UnixNano())), []byte(id)...)
+}
+
+func (b builder) RecheckQueueUntil(at time.Time) fdb.KeyRange {
+	return fdb.KeyRange{
+		Begin: fdb.Key(recheckQueuePrefix[:]),
+		End:   fdb.Key(binary.BigEndian.AppendUint64(recheckQueuePrefix[:], uint64(at.UTC().UnixNano()))),
+	}
+}
+
+func (b builder) RechecksByTweet() []byte {
+	return recheckByTweetPrefix[:]
+}
+
+func (b builder) RecheckByTweet(id string) []byte {
+	return append(recheckByTweetPrefix[:], []byte(id)...)
+}
+
+func (b builder) SearchCursors() []byte {
+	return searchCursorPrefix[:]
+}
+
+func (b builder) SearchCursor(query string, start time.Time) []byte {
+	slice := append(append(searchCursorPrefix[:], []byte(query)...), 0x00)
+	return binary.BigEndian.AppendUint64(slice, uint64(start.UTC().UnixNano()))
+}
+
+// SearchWatermark is the time the query is searched until.
+func (b builder) SearchWatermark(query string) []byte {
+	return append(searchWatermarkPrefix[:], []byte(query)...)
+}
+
+func (b builder) SearchQueries() []byte {
+	return searchQueryPrefix[:]
+}
+
+func (b builder) SearchQuery(name string) []byte {
+	return append(searchQueryPrefix[:], []byte(name)...)
+}
+
+// SearchQueriesSeeded is set once the queries of the config are stored.
+func (b builder) SearchQueriesSeeded() []byte {
+	return searchQueriesSeededPrefix[:]
+}
+
+func (b builder) BackfillCheckpoints() []byte {
+	return backfillCheckpointPrefix[:]
+}
+
+// BackfillCheckpoint is keyed by both bounds of the slice, so the slices of another size never share the checkpoint.
+func (b builder) BackfillCheckpoint(query string, since, until time.Time) []byte {
+	slice := append(append(backfillCheckpointPrefix[:], []byte(query)...), 0x00)
+	slice = binary.BigEndian.AppendUint64(slice, uint64(since.UTC().UnixNano()))
+
+	return binary.BigEndian.AppendUint64(slice, uint64(until.UTC().UnixNano()))
+}
+
+func (b builder) ProxyAssignment(login string) []byte {
+	return append(proxyAssignmentPrefix[:], []byte(login)...)
+}
+
+func (b builder) AccountLease(login string) []byte {
+	return append(accountLeasePrefix[:], []byte(login)...)
+}
+
+func (b builder) Replicas() []byte {
+	return replicaPrefix[:]
+}
+
+func (b builder) Replica(id string) []byte {
+	return append(replicaPrefix[:], []byte(id)...)
+}
+
 func (b builder) Tweet(id string) []byte {
 	return append(tweetPrefix[:], []byte(id)...)
 }
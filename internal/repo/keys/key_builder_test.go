@@ -1,6 +1,7 @@
 package keys
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
@@ -15,6 +16,21 @@ func Test_builder_RequestLimits(t *testing.T) {
 	})
 }
 
+func Test_builder_RequestBucket(t *testing.T) {
+	b := builder{}
+	start := time.Unix(256, 0)
+
+	got := b.RequestBucket("test", time.Minute, start)
+	assert.Equal(t, append(requestBucketPrefix[:], []byte{'t', 'e', 's', 't', 0, 0, 0x3c, 0, 0, 0, 0, 0, 0, 1, 0}...), got)
+
+	// the buckets are ordered by the start, so the expired ones are cleared by the range
+	later := b.RequestBucket("test", time.Minute, start.Add(time.Second))
+	assert.Negative(t, bytes.Compare(got, later))
+
+	until := b.RequestBucketsUntil("test", time.Minute, start.Add(time.Second))
+	assert.Equal(t, later, []byte(until.End.FDBKey()))
+}
+
 func Test_builder_Tweet(t *testing.T) {
 	tests := []struct {
 		name string
@@ -17,4 +17,18 @@ var (
 	requestsPrefix               Prefix = [2]byte{0x00, 0x1b}
 	tweetRatingIndexPrefix       Prefix = [2]byte{0x00, 0x12}
 	tweetCreationIndexPrefix     Prefix = [2]byte{0x00, 0x14}
+	tweetHistoryPrefix           Prefix = [2]byte{0x00, 0x15}
+	recheckQueuePrefix           Prefix = [2]byte{0x00, 0x16}
+	recheckByTweetPrefix         Prefix = [2]byte{0x00, 0x17}
+	searchCursorPrefix           Prefix = [2]byte{0x00, 0x18}
+	searchQueryPrefix            Prefix = [2]byte{0x00, 0x19}
+	backfillCheckpointPrefix     Prefix = [2]byte{0x00, 0x1c}
+	proxyAssignmentPrefix        Prefix = [2]byte{0x00, 0x1d}
+	accountLeasePrefix           Prefix = [2]byte{0x00, 0x1e}
+	replicaPrefix                Prefix = [2]byte{0x00, 0x1f}
+	requestBucketPrefix          Prefix = [2]byte{0x00, 0x20}
+	migrationFtoRVersionPrefix   Prefix = [2]byte{0x00, 0x21}
+	searchWatermarkPrefix        Prefix = [2]byte{0x00, 0x22}
+	migrationFDBVersionPrefix    Prefix = [2]byte{0x00, 0x23}
+	searchQueriesSeededPrefix    Prefix = [2]byte{0x00, 0x24}
 )
@@ -0,0 +1,179 @@
+package migrations
+
+import (
+	"context"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/keys"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/model"
+	rdb "github.com/lueurxax/crypto-tweet-sense/internal/repo/redis"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+const (
+	// the request limit key ends with the window seconds truncated to two bytes, so the window is read from the record
+	windowKeySuffix = 2
+	scanCount       = 100
+)
+
+// RequestLimits copied the request limits once, the copy runs on the switch of the storage by CopyRequestLimits now.
+// It is kept so the versions of the migrations stay in order.
+type RequestLimits struct{}
+
+func (i *RequestLimits) Up(context.Context, fdbclient.Transaction, redis.Pipeliner) error {
+	return nil
+}
+
+func (i *RequestLimits) Down(context.Context, fdbclient.Transaction, redis.Pipeliner) error {
+	return nil
+}
+
+func (i *RequestLimits) Version() uint32 {
+	return 5
+}
+
+// InitRequestLimits copies the request limits to redis unless redis keeps some already,
+// so the limiters keep their state on the first start after the storage is switched to redis.
+// It returns the number of the copied limits.
+func InitRequestLimits(ctx context.Context, database fdbclient.Database, client *redis.Client) (int, error) {
+	prefix := string(keys.NewBuilder().AllRequestLimits())
+
+	// the limits of redis are found by the prefix of their keys
+	iter := client.Scan(ctx, 0, prefix+"*", scanCount).Iterator()
+	if iter.Next(ctx) {
+		return 0, nil
+	}
+
+	if err := iter.Err(); err != nil {
+		return 0, err
+	}
+
+	return CopyRequestLimits(ctx, database, client)
+}
+
+// CopyRequestLimits copies the thresholds and the requests within the window of every request limit to redis,
+// the request limits of redis with the same ids and windows are replaced. It returns the number of the copied limits.
+func CopyRequestLimits(ctx context.Context, database fdbclient.Database, client *redis.Client) (int, error) {
+	ftr, err := database.NewTransaction(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	rtr := client.TxPipeline()
+
+	count, err := copyRequestLimits(ctx, ftr, rtr)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = ftr.Commit(); err != nil {
+		return 0, err
+	}
+
+	if _, err = rtr.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func copyRequestLimits(ctx context.Context, ftr fdbclient.Transaction, rtr redis.Pipeliner) (int, error) {
+	keyBuilder := keys.NewBuilder()
+
+	limits, err := getRequestLimits(ftr, keyBuilder)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, limit := range limits {
+		pr, err := fdb.PrefixRange(keyBuilder.RequestBuckets(limit.id, limit.window))
+		if err != nil {
+			return 0, err
+		}
+
+		kvs, err := ftr.GetRange(pr)
+		if err != nil {
+			return 0, err
+		}
+
+		since := time.Now().Add(-limit.window)
+		members := make([]redis.Z, 0)
+
+		for _, kv := range kvs {
+			bucket := model.RequestsV2{}
+			if err = bucket.Unmarshal(kv.Value); err != nil {
+				return 0, err
+			}
+
+			for _, t := range bucket.Times() {
+				if t.After(since) {
+					members = append(members, redis.Z{Score: rdb.RequestScore(t), Member: rdb.RequestMember(t, uint64(len(members)))})
+				}
+			}
+		}
+
+		key := string(keyBuilder.RequestLimits(limit.id, limit.window))
+		requestsKey := string(keyBuilder.RequestsByRequestLimits(limit.id, limit.window))
+
+		if err = rtr.Del(ctx, key, requestsKey).Err(); err != nil {
+			return 0, err
+		}
+
+		if err = rtr.HSet(ctx, key, rdb.RequestLimitWindowField, limit.WindowSeconds, rdb.RequestLimitThresholdField, limit.Threshold).Err(); err != nil {
+			return 0, err
+		}
+
+		if len(members) == 0 {
+			continue
+		}
+
+		if err = rtr.ZAdd(ctx, requestsKey, members...).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(limits), nil
+}
+
+type requestLimit struct {
+	model.RequestLimitsV2
+	id     string
+	window time.Duration
+}
+
+func getRequestLimits(ftr fdbclient.Transaction, keyBuilder keys.Builder) ([]requestLimit, error) {
+	prefix := keyBuilder.AllRequestLimits()
+
+	pr, err := fdb.PrefixRange(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := ftr.GetRange(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make([]requestLimit, 0, len(kvs))
+
+	for _, kv := range kvs {
+		if len(kv.Key) < len(prefix)+windowKeySuffix {
+			continue
+		}
+
+		limit := requestLimit{id: string(kv.Key[len(prefix) : len(kv.Key)-windowKeySuffix])}
+
+		if err = limit.Unmarshal(kv.Value); err != nil {
+			return nil, err
+		}
+
+		limit.window = time.Duration(limit.WindowSeconds) * time.Second
+
+		limits = append(limits, limit)
+	}
+
+	return limits, nil
+}
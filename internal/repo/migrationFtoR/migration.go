@@ -34,6 +34,7 @@ func Migrations(version uint32) []Migration {
 		&Accounts{},
 		&Cookies{},
 		&SessionStorage{},
+		&RequestLimits{},
 	}
 
 	result := make([]Migration, 0, len(migrations))
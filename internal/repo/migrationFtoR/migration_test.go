@@ -1,8 +1,17 @@
 package migrations
 
 import (
+	"context"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/keys"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/model"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
 )
 
 func TestMigrations(t *testing.T) {
@@ -20,9 +29,29 @@ func TestMigrations(t *testing.T) {
 				version: 0,
 			},
 			want: []Migration{
+				&Empty{},
+				&Accounts{},
 				&Cookies{},
+				&SessionStorage{},
+				&RequestLimits{},
 			},
 		},
+		{
+			name: "4",
+			args: args{
+				version: 4,
+			},
+			want: []Migration{
+				&RequestLimits{},
+			},
+		},
+		{
+			name: "5",
+			args: args{
+				version: 5,
+			},
+			want: []Migration{},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -32,3 +61,75 @@ func TestMigrations(t *testing.T) {
 		})
 	}
 }
+
+// recordingPipeliner keeps the request limits written by the migration instead of sending them.
+type recordingPipeliner struct {
+	redis.Pipeliner
+	hashes  map[string][]interface{}
+	sets    map[string][]redis.Z
+	deleted []string
+}
+
+func (p *recordingPipeliner) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	p.deleted = append(p.deleted, keys...)
+	return redis.NewIntCmd(ctx)
+}
+
+func (p *recordingPipeliner) HSet(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	p.hashes[key] = values
+	return redis.NewIntCmd(ctx)
+}
+
+func (p *recordingPipeliner) ZAdd(ctx context.Context, key string, members ...redis.Z) *redis.IntCmd {
+	p.sets[key] = append(p.sets[key], members...)
+	return redis.NewIntCmd(ctx)
+}
+
+func TestCopyRequestLimits(t *testing.T) {
+	ctx := context.Background()
+	db := fdbclient.NewMemoryDatabase()
+	builder := keys.NewBuilder()
+	window := time.Hour * 24
+	now := time.Now().Truncate(time.Second)
+
+	tr, err := db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	limit := model.RequestLimitsV2{WindowSeconds: uint64(window.Seconds()), Threshold: 50}
+	data, err := limit.Marshal()
+	require.NoError(t, err)
+
+	tr.Set(builder.RequestLimits("alice", window), data)
+
+	expired := now.Add(-window - time.Hour)
+	for _, times := range [][]time.Time{{expired}, {now.Add(-time.Minute), now}} {
+		bucket := model.NewRequestsV2(model.BucketStart(window, times[0]), times)
+
+		data, err = bucket.Marshal()
+		require.NoError(t, err)
+
+		tr.Set(builder.RequestBucket("alice", window, bucket.Start), data)
+	}
+
+	rtr := &recordingPipeliner{hashes: make(map[string][]interface{}), sets: make(map[string][]redis.Z)}
+	count, err := copyRequestLimits(ctx, tr, rtr)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	// the limits copied before are replaced
+	require.Equal(t, []string{
+		string(builder.RequestLimits("alice", window)),
+		string(builder.RequestsByRequestLimits("alice", window)),
+	}, rtr.deleted)
+
+	require.Equal(t, map[string][]interface{}{
+		string(builder.RequestLimits("alice", window)): {"window_seconds", uint64(86400), "threshold", uint64(50)},
+	}, rtr.hashes)
+
+	// the expired requests are not copied
+	members := rtr.sets[string(builder.RequestsByRequestLimits("alice", window))]
+	require.Len(t, members, 2)
+	require.Equal(t, float64(now.Add(-time.Minute).UnixMilli()), members[0].Score)
+	require.Equal(t, float64(now.UnixMilli()), members[1].Score)
+	require.NotEqual(t, members[0].Member, members[1].Member)
+}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"encoding/binary"
 
-	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/lueurxax/crypto-tweet-sense/internal/log"
 	"github.com/lueurxax/crypto-tweet-sense/internal/repo/keys"
 	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
 	"github.com/redis/go-redis/v9"
 )
 
-const versionKey = "version"
+const (
+	versionKey = "version"
+	// sharedVersion is the last migration written to the version key of the foundationdb migrations
+	sharedVersion = 4
+)
 
 type Migrator interface {
 	Migrate(ctx context.Context) error
@@ -73,16 +76,26 @@ func (m *migrator) GetVersion(ctx context.Context) (uint32, error) {
 		return 0, err
 	}
 
-	data, err := tr.Get(m.keyBuilder.Version())
+	data, err := tr.Get(m.keyBuilder.MigrationFtoRVersion())
 	if err != nil {
 		return 0, err
 	}
 
+	if data != nil {
+		return binary.BigEndian.Uint32(data), nil
+	}
+
+	// the migrations were counted by the version key of the foundationdb migrations before,
+	// the shared version is trusted up to the last migration written there
+	if data, err = tr.Get(m.keyBuilder.Version()); err != nil {
+		return 0, err
+	}
+
 	if data == nil {
 		return 0, nil
 	}
 
-	return binary.BigEndian.Uint32(data), nil
+	return min(binary.BigEndian.Uint32(data), sharedVersion), nil
 }
 
 func (m *migrator) WriteVersion(ctx context.Context, version uint32) error {
@@ -94,15 +107,15 @@ func (m *migrator) WriteVersion(ctx context.Context, version uint32) error {
 	data := make([]byte, binary.Size(version))
 	binary.BigEndian.PutUint32(data, version)
 
-	tr.Set(m.keyBuilder.Version(), data)
+	tr.Set(m.keyBuilder.MigrationFtoRVersion(), data)
 
 	return tr.Commit()
 }
 
-func NewMigrator(fdb fdb.Database, rdb *redis.Client, log log.Logger) Migrator {
+func NewMigrator(fdb fdbclient.Database, rdb *redis.Client, log log.Logger) Migrator {
 	return &migrator{
 		keyBuilder: keys.NewBuilder(),
-		fdb:        fdbclient.NewDatabase(fdb),
+		fdb:        fdb,
 		rdb:        rdb,
 		log:        log,
 	}
@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"context"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/keys"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+// recheckQueueBatch is the number of tweets queued in one transaction.
+const recheckQueueBatch = 1000
+
+// RecheckQueue puts every tracked tweet into the recheck queue, the scheduler spreads them after the first check.
+type RecheckQueue struct{}
+
+func (r *RecheckQueue) Up(ctx context.Context, tr fdbclient.Transaction) error {
+	from, err := r.UpBatch(ctx, tr, nil)
+	for from != nil && err == nil {
+		from, err = r.UpBatch(ctx, tr, from)
+	}
+
+	return err
+}
+
+// UpBatch queues the tracked tweets which are not queued yet, so the batch is repeated safely after a failure.
+func (r *RecheckQueue) UpBatch(_ context.Context, tr fdbclient.Transaction, from []byte) ([]byte, error) {
+	builder := keys.NewBuilder()
+	now := time.Now()
+
+	pr := builder.TweetRatingPositiveIndexes()
+	if from != nil {
+		pr.Begin = fdb.Key(from)
+	}
+
+	opts := new(fdbclient.RangeOptions)
+	opts.SetLimit(recheckQueueBatch)
+
+	kvs, err := tr.GetRange(pr, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, kv := range kvs {
+		index := new(common.TweetSnapshotIndex)
+		if err = jsoniter.Unmarshal(kv.Value, index); err != nil {
+			return nil, err
+		}
+
+		queued, err := tr.Get(builder.RecheckByTweet(index.ID))
+		if err != nil {
+			return nil, err
+		}
+
+		if queued != nil {
+			continue
+		}
+
+		key := builder.RecheckQueueItem(now, index.ID)
+
+		tr.Set(key, []byte(index.ID))
+		tr.Set(builder.RecheckByTweet(index.ID), key)
+	}
+
+	if len(kvs) < recheckQueueBatch {
+		return nil, nil
+	}
+
+	return append(append([]byte{}, kvs[len(kvs)-1].Key...), 0x00), nil
+}
+
+func (r *RecheckQueue) Down(_ context.Context, tr fdbclient.Transaction) error {
+	builder := keys.NewBuilder()
+
+	if err := tr.ClearRange(builder.RecheckQueue()); err != nil {
+		return err
+	}
+
+	return tr.ClearRange(builder.RechecksByTweet())
+}
+
+func (r *RecheckQueue) Version() uint32 {
+	return 2
+}
@@ -0,0 +1,161 @@
+package migrations
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/keys"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+const retweetsFilter = "-filter:retweets"
+
+type rawSearchQuery struct {
+	Name           string
+	Query          string
+	Enabled        bool
+	SearchInterval time.Duration
+	Retweets       bool
+}
+
+type rawSearchCursor struct {
+	Query  string
+	Search string
+	Start  time.Time
+	End    time.Time
+	Cursor string
+}
+
+// TypedSearchQueries converts the raw query strings of the stored queries and cursors to the typed query model.
+// The words of a raw query become keywords, so the converted query renders to the same search.
+type TypedSearchQueries struct{}
+
+func (m *TypedSearchQueries) Up(_ context.Context, tr fdbclient.Transaction) error {
+	builder := keys.NewBuilder()
+
+	if err := rewrite(tr, builder.SearchQueries(), typedSearchQuery); err != nil {
+		return err
+	}
+
+	return rewrite(tr, builder.SearchCursors(), typedSearchCursor)
+}
+
+func (m *TypedSearchQueries) Down(_ context.Context, tr fdbclient.Transaction) error {
+	builder := keys.NewBuilder()
+
+	if err := rewrite(tr, builder.SearchQueries(), rawQuery); err != nil {
+		return err
+	}
+
+	return rewrite(tr, builder.SearchCursors(), rawCursor)
+}
+
+func (m *TypedSearchQueries) Version() uint32 {
+	return 3
+}
+
+func typedSearchQuery(data []byte) (any, error) {
+	raw := rawSearchQuery{}
+	if err := jsoniter.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	query := common.KeywordQuery(raw.Name, raw.Query, raw.SearchInterval)
+	query.Enabled = raw.Enabled
+	query.Retweets = raw.Retweets
+
+	return query, nil
+}
+
+// typedSearchCursor parses the rendered search back, the raw model knew only the retweets filter.
+func typedSearchCursor(data []byte) (any, error) {
+	raw := rawSearchCursor{}
+	if err := jsoniter.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(raw.Search)
+	words := make([]string, 0, len(fields))
+	retweets := true
+
+	for _, field := range fields {
+		if field == retweetsFilter {
+			retweets = false
+			continue
+		}
+
+		words = append(words, field)
+	}
+
+	search := common.KeywordQuery(raw.Query, strings.Join(words, " "), 0)
+	search.Retweets = retweets
+
+	return common.SearchCursor{Query: raw.Query, Search: search, Start: raw.Start, End: raw.End, Cursor: raw.Cursor}, nil
+}
+
+func rawQuery(data []byte) (any, error) {
+	query := common.SearchQuery{}
+	if err := jsoniter.Unmarshal(data, &query); err != nil {
+		return nil, err
+	}
+
+	retweets := query.Retweets
+	// the raw model appends the retweets filter on its own
+	query.Retweets = true
+
+	return rawSearchQuery{
+		Name:           query.Name,
+		Query:          query.Render(),
+		Enabled:        query.Enabled,
+		SearchInterval: query.SearchInterval,
+		Retweets:       retweets,
+	}, nil
+}
+
+func rawCursor(data []byte) (any, error) {
+	cursor := common.SearchCursor{}
+	if err := jsoniter.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	return rawSearchCursor{
+		Query:  cursor.Query,
+		Search: cursor.Search.Render(),
+		Start:  cursor.Start,
+		End:    cursor.End,
+		Cursor: cursor.Cursor,
+	}, nil
+}
+
+func rewrite(tr fdbclient.Transaction, prefix []byte, convert func([]byte) (any, error)) error {
+	pr, err := fdb.PrefixRange(prefix)
+	if err != nil {
+		return err
+	}
+
+	kvs, err := tr.GetRange(pr)
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range kvs {
+		value, err := convert(kv.Value)
+		if err != nil {
+			return err
+		}
+
+		data, err := jsoniter.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		tr.Set(kv.Key, data)
+	}
+
+	return nil
+}
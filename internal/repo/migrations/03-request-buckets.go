@@ -0,0 +1,169 @@
+package migrations
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/keys"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/model"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+// the request batch key ends with the little endian window seconds and start
+const requestsKeySuffix = 2 + 8
+
+type requestLimitKey struct {
+	id     string
+	window time.Duration
+}
+
+// requestBucketsBatch is the number of request batches moved in one transaction.
+const requestBucketsBatch = 1000
+
+// RequestBuckets moves the request batches of the request limits to the time buckets ordered by their start,
+// so the expired requests are cleared by the key range instead of rewriting the batches.
+type RequestBuckets struct{}
+
+func (m *RequestBuckets) Up(ctx context.Context, tr fdbclient.Transaction) error {
+	from, err := m.UpBatch(ctx, tr, nil)
+	for from != nil && err == nil {
+		from, err = m.UpBatch(ctx, tr, from)
+	}
+
+	return err
+}
+
+// UpBatch moves the request batches in the order of their limit keys and clears them. The batches of one limit
+// may be split between the transactions, so the times are merged into the bucket written by the previous batch.
+func (m *RequestBuckets) UpBatch(_ context.Context, tr fdbclient.Transaction, from []byte) ([]byte, error) {
+	builder := keys.NewBuilder()
+	prefix := builder.AllRequests()
+
+	pr, err := fdb.PrefixRange(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if from != nil {
+		pr.Begin = fdb.Key(from)
+	}
+
+	opts := new(fdbclient.RangeOptions)
+	opts.SetLimit(requestBucketsBatch)
+
+	kvs, err := tr.GetRange(pr, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make(map[requestLimitKey]map[time.Time][]time.Time)
+
+	for _, kv := range kvs {
+		if len(kv.Key) < len(prefix)+requestsKeySuffix {
+			continue
+		}
+
+		suffix := kv.Key[len(kv.Key)-requestsKeySuffix:]
+		limit := requestLimitKey{
+			id:     string(kv.Key[len(prefix) : len(kv.Key)-requestsKeySuffix]),
+			window: time.Duration(binary.LittleEndian.Uint16(suffix)) * time.Second,
+		}
+
+		batch := model.RequestsV2{}
+		if err = batch.Unmarshal(kv.Value); err != nil {
+			return nil, err
+		}
+
+		if buckets[limit] == nil {
+			buckets[limit] = make(map[time.Time][]time.Time)
+		}
+
+		for _, t := range batch.Times() {
+			start := model.BucketStart(limit.window, t)
+			buckets[limit][start] = append(buckets[limit][start], t)
+		}
+	}
+
+	for limit, byStart := range buckets {
+		for start, times := range byStart {
+			key := builder.RequestBucket(limit.id, limit.window, start)
+
+			stored, err := tr.Get(key)
+			if err != nil {
+				return nil, err
+			}
+
+			if stored != nil {
+				bucket := model.RequestsV2{}
+				if err = bucket.Unmarshal(stored); err != nil {
+					return nil, err
+				}
+
+				times = append(times, bucket.Times()...)
+			}
+
+			bucket := model.NewRequestsV2(start, times)
+
+			data, err := bucket.Marshal()
+			if err != nil {
+				return nil, err
+			}
+
+			tr.Set(key, data)
+		}
+	}
+
+	if len(kvs) < requestBucketsBatch {
+		tr.ClearKeyRange(pr)
+
+		return nil, nil
+	}
+
+	next := append(append([]byte{}, kvs[len(kvs)-1].Key...), 0x00)
+	tr.ClearKeyRange(fdb.KeyRange{Begin: pr.Begin, End: fdb.Key(next)})
+
+	return next, nil
+}
+
+// Down writes every bucket back as the request batch, the batch is keyed by the bucket start.
+func (m *RequestBuckets) Down(_ context.Context, tr fdbclient.Transaction) error {
+	builder := keys.NewBuilder()
+	prefix := builder.AllRequestBuckets()
+
+	kvs, err := getPrefix(tr, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, kv := range kvs {
+		// the bucket key is the id, the zero separator, the big endian window seconds and start
+		if len(kv.Key) < len(prefix)+1+requestsKeySuffix {
+			continue
+		}
+
+		suffix := kv.Key[len(kv.Key)-requestsKeySuffix:]
+		id := string(kv.Key[len(prefix) : len(kv.Key)-requestsKeySuffix-1])
+		window := time.Duration(binary.BigEndian.Uint16(suffix)) * time.Second
+		start := time.Unix(int64(binary.BigEndian.Uint64(suffix[2:])), 0)
+
+		tr.Set(builder.Requests(id, window, start), kv.Value)
+	}
+
+	return tr.ClearRange(prefix)
+}
+
+func (m *RequestBuckets) Version() uint32 {
+	return 4
+}
+
+func getPrefix(tr fdbclient.Transaction, prefix []byte) ([]fdb.KeyValue, error) {
+	pr, err := fdb.PrefixRange(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.GetRange(pr)
+}
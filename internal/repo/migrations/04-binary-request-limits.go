@@ -0,0 +1,141 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/keys"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/model"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+// recodeBatch is the number of values rewritten in one transaction.
+const recodeBatch = 1000
+
+// BinaryRequestLimits rewrites the JSON request limits and request buckets with the binary codec.
+type BinaryRequestLimits struct{}
+
+func (m *BinaryRequestLimits) Up(ctx context.Context, tr fdbclient.Transaction) error {
+	from, err := m.UpBatch(ctx, tr, nil)
+	for from != nil && err == nil {
+		from, err = m.UpBatch(ctx, tr, from)
+	}
+
+	return err
+}
+
+// UpBatch rewrites the request limits and then the request buckets, the start of the next batch is in either prefix.
+func (m *BinaryRequestLimits) UpBatch(_ context.Context, tr fdbclient.Transaction, from []byte) ([]byte, error) {
+	builder := keys.NewBuilder()
+	limits := builder.AllRequestLimits()
+
+	if from == nil || bytes.HasPrefix(from, limits) {
+		next, err := recodePage(tr, limits, from, binaryLimit)
+		if err != nil || next != nil {
+			return next, err
+		}
+
+		return builder.AllRequestBuckets(), nil
+	}
+
+	return recodePage(tr, builder.AllRequestBuckets(), from, binaryBucket)
+}
+
+func (m *BinaryRequestLimits) Down(_ context.Context, tr fdbclient.Transaction) error {
+	builder := keys.NewBuilder()
+
+	if err := recode(tr, builder.AllRequestLimits(), jsonLimit); err != nil {
+		return err
+	}
+
+	return recode(tr, builder.AllRequestBuckets(), jsonBucket)
+}
+
+func (m *BinaryRequestLimits) Version() uint32 {
+	return 5
+}
+
+func binaryLimit(data []byte) ([]byte, error) {
+	limit := model.RequestLimitsV2{}
+	if err := limit.Unmarshal(data); err != nil {
+		return nil, err
+	}
+
+	return limit.MarshalBinary()
+}
+
+func binaryBucket(data []byte) ([]byte, error) {
+	bucket := model.RequestsV2{}
+	if err := bucket.Unmarshal(data); err != nil {
+		return nil, err
+	}
+
+	return bucket.MarshalBinary()
+}
+
+func jsonLimit(data []byte) ([]byte, error) {
+	limit := model.RequestLimitsV2{}
+	if err := limit.Unmarshal(data); err != nil {
+		return nil, err
+	}
+
+	return jsoniter.Marshal(limit)
+}
+
+func jsonBucket(data []byte) ([]byte, error) {
+	bucket := model.RequestsV2{}
+	if err := bucket.Unmarshal(data); err != nil {
+		return nil, err
+	}
+
+	return jsoniter.Marshal(bucket)
+}
+
+// recode rewrites every value under the prefix, the decoders read both codecs, so the values are converted once.
+func recode(tr fdbclient.Transaction, prefix []byte, convert func([]byte) ([]byte, error)) error {
+	from, err := recodePage(tr, prefix, nil, convert)
+	for from != nil && err == nil {
+		from, err = recodePage(tr, prefix, from, convert)
+	}
+
+	return err
+}
+
+// recodePage rewrites the values under the prefix starting from the key and returns the start of the next page,
+// nil after the last one.
+func recodePage(tr fdbclient.Transaction, prefix, from []byte, convert func([]byte) ([]byte, error)) ([]byte, error) {
+	pr, err := fdb.PrefixRange(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if from != nil {
+		pr.Begin = fdb.Key(from)
+	}
+
+	opts := new(fdbclient.RangeOptions)
+	opts.SetLimit(recodeBatch)
+
+	kvs, err := tr.GetRange(pr, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, kv := range kvs {
+		data, err := convert(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		tr.Set(kv.Key, data)
+	}
+
+	if len(kvs) < recodeBatch {
+		return nil, nil
+	}
+
+	return append(append([]byte{}, kvs[len(kvs)-1].Key...), 0x00), nil
+}
@@ -12,9 +12,20 @@ type Migration interface {
 	Version() uint32
 }
 
+// BatchMigration migrates the whole table, the migrator commits every batch in its own transaction.
+type BatchMigration interface {
+	Migration
+	// UpBatch migrates the batch starting from the key and returns the start of the next batch, nil after the last one.
+	UpBatch(ctx context.Context, tr fdbclient.Transaction, from []byte) ([]byte, error)
+}
+
 func Migrations(version uint32) []Migration {
 	migrations := []Migration{
 		&Init{},
+		&RecheckQueue{},
+		&TypedSearchQueries{},
+		&RequestBuckets{},
+		&BinaryRequestLimits{},
 	}
 
 	result := make([]Migration, 0, len(migrations))
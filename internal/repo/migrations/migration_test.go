@@ -1,8 +1,20 @@
 package migrations
 
 import (
+	"context"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/keys"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/model"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
 )
 
 func TestMigrations(t *testing.T) {
@@ -21,6 +33,52 @@ func TestMigrations(t *testing.T) {
 			},
 			want: []Migration{
 				&Init{},
+				&RecheckQueue{},
+				&TypedSearchQueries{},
+				&RequestBuckets{},
+				&BinaryRequestLimits{},
+			},
+		},
+		{
+			name: "1",
+			args: args{
+				version: 1,
+			},
+			want: []Migration{
+				&RecheckQueue{},
+				&TypedSearchQueries{},
+				&RequestBuckets{},
+				&BinaryRequestLimits{},
+			},
+		},
+		{
+			name: "2",
+			args: args{
+				version: 2,
+			},
+			want: []Migration{
+				&TypedSearchQueries{},
+				&RequestBuckets{},
+				&BinaryRequestLimits{},
+			},
+		},
+		{
+			name: "3",
+			args: args{
+				version: 3,
+			},
+			want: []Migration{
+				&RequestBuckets{},
+				&BinaryRequestLimits{},
+			},
+		},
+		{
+			name: "4",
+			args: args{
+				version: 4,
+			},
+			want: []Migration{
+				&BinaryRequestLimits{},
 			},
 		},
 	}
@@ -32,3 +90,309 @@ func TestMigrations(t *testing.T) {
 		})
 	}
 }
+
+func TestRecheckQueue(t *testing.T) {
+	ctx := context.Background()
+	db := fdbclient.NewMemoryDatabase()
+	builder := keys.NewBuilder()
+	tweets := recheckQueueBatch + recheckQueueBatch/2
+
+	tr, err := db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	for i := 0; i < tweets; i++ {
+		id := strconv.Itoa(i)
+
+		data, err := jsoniter.Marshal(common.TweetSnapshotIndex{ID: id, RatingGrowSpeed: 1})
+		require.NoError(t, err)
+
+		tr.Set(builder.TweetRatingIndex(1, id), data)
+	}
+
+	// the tweet queued already keeps its position
+	queued := builder.RecheckQueueItem(time.Now().Add(time.Hour), "0")
+	tr.Set(queued, []byte("0"))
+	tr.Set(builder.RecheckByTweet("0"), queued)
+	require.NoError(t, tr.Commit())
+
+	batches := 0
+
+	for from := []byte(nil); batches == 0 || from != nil; batches++ {
+		tr, err = db.NewTransaction(ctx)
+		require.NoError(t, err)
+
+		from, err = (&RecheckQueue{}).UpBatch(ctx, tr, from)
+		require.NoError(t, err)
+		require.NoError(t, tr.Commit())
+	}
+
+	require.Equal(t, 2, batches)
+
+	// the repeated migration queues nothing twice
+	tr, err = db.NewTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, (&RecheckQueue{}).Up(ctx, tr))
+	require.NoError(t, tr.Commit())
+
+	tr, err = db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	pr, err := fdb.PrefixRange(builder.RecheckQueue())
+	require.NoError(t, err)
+
+	kvs, err := tr.GetRange(pr)
+	require.NoError(t, err)
+	require.Len(t, kvs, tweets)
+
+	key, err := tr.Get(builder.RecheckByTweet("0"))
+	require.NoError(t, err)
+	require.Equal(t, queued, key)
+}
+
+func TestTypedSearchQueries(t *testing.T) {
+	ctx := context.Background()
+	db := fdbclient.NewMemoryDatabase()
+	builder := keys.NewBuilder()
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tr, err := db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	tr.Set(builder.SearchQuery("btc"), []byte(`{"Name":"btc","Query":"bitcoin","Enabled":true,"SearchInterval":60000000000}`))
+	tr.Set(builder.SearchCursor("btc", start), []byte(`{"Query":"btc","Search":"bitcoin -filter:retweets","Cursor":"a"}`))
+	require.NoError(t, (&TypedSearchQueries{}).Up(ctx, tr))
+	require.NoError(t, tr.Commit())
+
+	tr, err = db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	data, err := tr.Get(builder.SearchQuery("btc"))
+	require.NoError(t, err)
+
+	query := common.SearchQuery{}
+	require.NoError(t, jsoniter.Unmarshal(data, &query))
+	require.Equal(t, common.KeywordQuery("btc", "bitcoin", time.Minute), query)
+
+	data, err = tr.Get(builder.SearchCursor("btc", start))
+	require.NoError(t, err)
+
+	cursor := common.SearchCursor{}
+	require.NoError(t, jsoniter.Unmarshal(data, &cursor))
+	require.Equal(t, "bitcoin -filter:retweets", cursor.Search.Render())
+	require.Equal(t, "a", cursor.Cursor)
+
+	require.NoError(t, (&TypedSearchQueries{}).Down(ctx, tr))
+
+	data, err = tr.Get(builder.SearchCursor("btc", start))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Query":"btc","Search":"bitcoin -filter:retweets","Start":"0001-01-01T00:00:00Z","End":"0001-01-01T00:00:00Z","Cursor":"a"}`, string(data))
+}
+
+func TestRequestBuckets(t *testing.T) {
+	ctx := context.Background()
+	db := fdbclient.NewMemoryDatabase()
+	builder := keys.NewBuilder()
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	batch := model.RequestsV2{Data: []uint32{0, 5, 10, 3600}, Start: start}
+	data, err := jsoniter.Marshal(batch)
+	require.NoError(t, err)
+
+	tr, err := db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	tr.Set(builder.Requests("alice", time.Hour, start), data)
+	require.NoError(t, (&RequestBuckets{}).Up(ctx, tr))
+	require.NoError(t, tr.Commit())
+
+	tr, err = db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	pr, err := fdb.PrefixRange(builder.RequestBuckets("alice", time.Hour))
+	require.NoError(t, err)
+
+	kvs, err := tr.GetRange(pr)
+	require.NoError(t, err)
+	require.Len(t, kvs, 2)
+
+	times := make([]time.Time, 0, len(batch.Data))
+
+	for _, kv := range kvs {
+		bucket := model.RequestsV2{}
+		require.NoError(t, bucket.Unmarshal(kv.Value))
+		require.Equal(t, builder.RequestBucket("alice", time.Hour, bucket.Start), []byte(kv.Key))
+
+		times = append(times, bucket.Times()...)
+	}
+
+	require.Equal(t, batch.Times(), times)
+
+	pr, err = fdb.PrefixRange(builder.AllRequests())
+	require.NoError(t, err)
+
+	kvs, err = tr.GetRange(pr)
+	require.NoError(t, err)
+	require.Empty(t, kvs)
+
+	require.NoError(t, (&RequestBuckets{}).Down(ctx, tr))
+
+	pr, err = fdb.PrefixRange(builder.RequestsByRequestLimits("alice", time.Hour))
+	require.NoError(t, err)
+
+	kvs, err = tr.GetRange(pr)
+	require.NoError(t, err)
+	require.Len(t, kvs, 2)
+}
+
+func TestRequestBuckets_UpBatch(t *testing.T) {
+	ctx := context.Background()
+	db := fdbclient.NewMemoryDatabase()
+	builder := keys.NewBuilder()
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	requests := requestBucketsBatch + requestBucketsBatch/2
+
+	tr, err := db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	// every request is in its own batch, the bucket of a minute is split between the transactions
+	for i := 0; i < requests; i++ {
+		at := start.Add(time.Duration(i) * 10 * time.Second)
+		batch := model.NewRequestsV2(at, []time.Time{at})
+
+		data, err := jsoniter.Marshal(batch)
+		require.NoError(t, err)
+
+		tr.Set(builder.Requests("alice", time.Hour, at), data)
+	}
+	require.NoError(t, tr.Commit())
+
+	batches := 0
+
+	for from := []byte(nil); batches == 0 || from != nil; batches++ {
+		tr, err = db.NewTransaction(ctx)
+		require.NoError(t, err)
+
+		from, err = (&RequestBuckets{}).UpBatch(ctx, tr, from)
+		require.NoError(t, err)
+		require.NoError(t, tr.Commit())
+	}
+
+	require.Equal(t, 2, batches)
+
+	tr, err = db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	pr, err := fdb.PrefixRange(builder.RequestBuckets("alice", time.Hour))
+	require.NoError(t, err)
+
+	kvs, err := tr.GetRange(pr)
+	require.NoError(t, err)
+	require.Len(t, kvs, requests/6)
+
+	times := 0
+
+	for _, kv := range kvs {
+		bucket := model.RequestsV2{}
+		require.NoError(t, bucket.Unmarshal(kv.Value))
+		require.Len(t, bucket.Times(), 6)
+
+		times += len(bucket.Times())
+	}
+
+	require.Equal(t, requests, times)
+
+	pr, err = fdb.PrefixRange(builder.AllRequests())
+	require.NoError(t, err)
+
+	kvs, err = tr.GetRange(pr)
+	require.NoError(t, err)
+	require.Empty(t, kvs)
+}
+
+func TestBinaryRequestLimits(t *testing.T) {
+	ctx := context.Background()
+	db := fdbclient.NewMemoryDatabase()
+	builder := keys.NewBuilder()
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tr, err := db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	tr.Set(builder.RequestLimits("alice", time.Hour), []byte(`{"window_seconds":3600,"requests_count":3,"threshold":50}`))
+	tr.Set(builder.RequestBucket("alice", time.Hour, start), []byte(`{"data":[1,2,3],"start":"2024-01-02T00:00:00Z"}`))
+	require.NoError(t, (&BinaryRequestLimits{}).Up(ctx, tr))
+
+	data, err := tr.Get(builder.RequestLimits("alice", time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x01, 0x90, 0x1c, 0x03, 0x32}, data)
+
+	limit := model.RequestLimitsV2{}
+	require.NoError(t, limit.Unmarshal(data))
+	require.Equal(t, model.RequestLimitsV2{WindowSeconds: 3600, RequestsCount: 3, Threshold: 50}, limit)
+
+	data, err = tr.Get(builder.RequestBucket("alice", time.Hour, start))
+	require.NoError(t, err)
+
+	bucket := model.RequestsV2{}
+	require.NoError(t, bucket.Unmarshal(data))
+	require.Equal(t, model.RequestsV2{Data: []uint32{1, 2, 3}, Start: start}, bucket)
+
+	require.NoError(t, (&BinaryRequestLimits{}).Down(ctx, tr))
+
+	data, err = tr.Get(builder.RequestBucket("alice", time.Hour, start))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data":[1,2,3],"start":"2024-01-02T00:00:00Z"}`, string(data))
+}
+
+func TestBinaryRequestLimits_UpBatch(t *testing.T) {
+	ctx := context.Background()
+	db := fdbclient.NewMemoryDatabase()
+	builder := keys.NewBuilder()
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	limits := recodeBatch + 1
+
+	tr, err := db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	for i := 0; i < limits; i++ {
+		tr.Set(builder.RequestLimits(strconv.Itoa(i), time.Hour), []byte(`{"window_seconds":3600,"requests_count":3,"threshold":50}`))
+	}
+
+	tr.Set(builder.RequestBucket("alice", time.Hour, start), []byte(`{"data":[1,2,3],"start":"2024-01-02T00:00:00Z"}`))
+	require.NoError(t, tr.Commit())
+
+	batches := 0
+
+	for from := []byte(nil); batches == 0 || from != nil; batches++ {
+		tr, err = db.NewTransaction(ctx)
+		require.NoError(t, err)
+
+		from, err = (&BinaryRequestLimits{}).UpBatch(ctx, tr, from)
+		require.NoError(t, err)
+		require.NoError(t, tr.Commit())
+	}
+
+	// two pages of the limits and one of the buckets
+	require.Equal(t, 3, batches)
+
+	tr, err = db.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	pr, err := fdb.PrefixRange(builder.AllRequestLimits())
+	require.NoError(t, err)
+
+	kvs, err := tr.GetRange(pr)
+	require.NoError(t, err)
+	require.Len(t, kvs, limits)
+
+	for _, kv := range kvs {
+		require.Equal(t, []byte{0x01, 0x90, 0x1c, 0x03, 0x32}, kv.Value)
+	}
+
+	data, err := tr.Get(builder.RequestBucket("alice", time.Hour, start))
+	require.NoError(t, err)
+
+	expected, err := (&model.RequestsV2{Data: []uint32{1, 2, 3}, Start: start}).MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// The records are stored in the binary form: the codec version byte and the varint encoded fields.
+// The records written before the codec are JSON objects, they are read as is until the migration rewrites them.
+const (
+	codecBinaryV1 byte = 0x01
+	codecJSON     byte = '{'
+)
+
+var (
+	ErrUnknownCodec = errors.New("unknown request limits codec")
+	ErrMalformed    = errors.New("malformed request limits record")
+)
+
+// MarshalBinary encodes the limit as the version byte and the window seconds, requests count and threshold varints.
+func (r *RequestLimitsV2) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+3*binary.MaxVarintLen64)
+	buf = append(buf, codecBinaryV1)
+	buf = binary.AppendUvarint(buf, r.WindowSeconds)
+	buf = binary.AppendUvarint(buf, uint64(r.RequestsCount))
+	buf = binary.AppendUvarint(buf, r.Threshold)
+
+	return buf, nil
+}
+
+func (r *RequestLimitsV2) UnmarshalBinary(data []byte) error {
+	if isJSON(data) {
+		return jsoniter.Unmarshal(data, r)
+	}
+
+	d, err := newDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	r.WindowSeconds = d.uvarint()
+	r.RequestsCount = uint32(d.uvarint())
+	r.Threshold = d.uvarint()
+
+	return d.close()
+}
+
+// MarshalBinary encodes the batch as the version byte, the start unix nanoseconds, the requests number
+// and the delta encoded requests, the deltas of the packed batch are mostly a single byte.
+func (r *RequestsV2) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 1+3*binary.MaxVarintLen64+len(r.Data)*2)
+	buf = append(buf, codecBinaryV1)
+	buf = binary.AppendVarint(buf, r.Start.UnixNano())
+	buf = binary.AppendUvarint(buf, uint64(len(r.Data)))
+
+	for _, v := range r.Data {
+		buf = binary.AppendUvarint(buf, uint64(v))
+	}
+
+	return buf, nil
+}
+
+func (r *RequestsV2) UnmarshalBinary(data []byte) error {
+	if isJSON(data) {
+		return jsoniter.Unmarshal(data, r)
+	}
+
+	d, err := newDecoder(data)
+	if err != nil {
+		return err
+	}
+
+	r.Start = time.Unix(0, d.varint()).UTC()
+
+	size := d.uvarint()
+	// every request takes a byte at least, so the broken size never allocates more than the record holds
+	if size > uint64(len(d.data)) {
+		return fmt.Errorf("%w: %d requests in %d bytes", ErrMalformed, size, len(d.data))
+	}
+
+	r.Data = make([]uint32, 0, size)
+
+	for i := uint64(0); i < size && d.err == nil; i++ {
+		r.Data = append(r.Data, uint32(d.uvarint()))
+	}
+
+	return d.close()
+}
+
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func isJSON(data []byte) bool {
+	return len(data) > 0 && data[0] == codecJSON
+}
+
+func newDecoder(data []byte) (*decoder, error) {
+	if len(data) == 0 {
+		return nil, ErrMalformed
+	}
+
+	if data[0] != codecBinaryV1 {
+		return nil, fmt.Errorf("%w: %#x", ErrUnknownCodec, data[0])
+	}
+
+	return &decoder{data: data[1:]}, nil
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err = ErrMalformed
+		return 0
+	}
+
+	d.data = d.data[n:]
+
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.err = ErrMalformed
+		return 0
+	}
+
+	d.data = d.data[n:]
+
+	return v
+}
+
+func (d *decoder) close() error {
+	if d.err != nil {
+		return d.err
+	}
+
+	if len(d.data) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrMalformed, len(d.data))
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestsV2_MarshalBinary(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	tests := []struct {
+		name  string
+		batch RequestsV2
+	}{
+		{name: "empty", batch: RequestsV2{Data: []uint32{}, Start: start}},
+		{name: "small deltas", batch: RequestsV2{Data: []uint32{0, 1, 2, 127}, Start: start}},
+		{name: "large deltas", batch: RequestsV2{Data: []uint32{128, 1 << 20, 1<<32 - 1}, Start: start}},
+		{name: "before epoch", batch: RequestsV2{Data: []uint32{5}, Start: time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.batch.Marshal()
+			require.NoError(t, err)
+
+			got := RequestsV2{}
+			require.NoError(t, got.Unmarshal(data))
+			require.Equal(t, tt.batch, got)
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		got := RequestsV2{}
+		require.NoError(t, got.Unmarshal([]byte(`{"data":[1,2],"start":"2024-01-02T03:04:05.000000006Z"}`)))
+		require.Equal(t, RequestsV2{Data: []uint32{1, 2}, Start: start}, got)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		data, err := (&RequestsV2{Data: []uint32{1, 300}, Start: start}).Marshal()
+		require.NoError(t, err)
+
+		require.ErrorIs(t, new(RequestsV2).Unmarshal(nil), ErrMalformed)
+		require.ErrorIs(t, new(RequestsV2).Unmarshal(data[:len(data)-1]), ErrMalformed)
+		require.ErrorIs(t, new(RequestsV2).Unmarshal(append(data, 0)), ErrMalformed)
+		require.ErrorIs(t, new(RequestsV2).Unmarshal([]byte{0x01, 0x00, 0xff, 0x01}), ErrMalformed)
+		require.ErrorIs(t, new(RequestsV2).Unmarshal([]byte{0x02}), ErrUnknownCodec)
+	})
+}
+
+func TestRequestLimitsV2_MarshalBinary(t *testing.T) {
+	limit := RequestLimitsV2{WindowSeconds: 2592000, RequestsCount: 5183, Threshold: 172800}
+
+	data, err := limit.Marshal()
+	require.NoError(t, err)
+
+	got := RequestLimitsV2{}
+	require.NoError(t, got.Unmarshal(data))
+	require.Equal(t, limit, got)
+
+	got = RequestLimitsV2{}
+	require.NoError(t, got.Unmarshal([]byte(`{"window_seconds":2592000,"requests_count":5183,"threshold":172800}`)))
+	require.Equal(t, limit, got)
+}
+
+func benchmarkData(b *testing.B) (*RequestLimits, []RequestsV2) {
+	b.Helper()
+
+	limits := new(RequestLimits)
+	require.NoError(b, jsoniter.UnmarshalFromString(testData, limits))
+
+	return limits, limits.ToV2().Requests
+}
+
+// BenchmarkCodec_Marshal compares the gzipped JSON of the first model, the JSON batches and the binary batches
+// on the same requests history.
+func BenchmarkCodec_Marshal(b *testing.B) {
+	limits, batches := benchmarkData(b)
+
+	b.Run("gzip json", func(b *testing.B) {
+		size := 0
+
+		for i := 0; i < b.N; i++ {
+			data, err := limits.Marshal()
+			require.NoError(b, err)
+
+			size = len(data)
+		}
+
+		b.ReportMetric(float64(size), "bytes/record")
+	})
+
+	b.Run("json", func(b *testing.B) {
+		benchmarkMarshal(b, batches, func(batch *RequestsV2) ([]byte, error) { return jsoniter.Marshal(batch) })
+	})
+
+	b.Run("binary", func(b *testing.B) {
+		benchmarkMarshal(b, batches, (*RequestsV2).MarshalBinary)
+	})
+}
+
+func BenchmarkCodec_Unmarshal(b *testing.B) {
+	limits, batches := benchmarkData(b)
+
+	b.Run("gzip json", func(b *testing.B) {
+		data, err := limits.Marshal()
+		require.NoError(b, err)
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, new(RequestLimits).Unmarshal(data))
+		}
+	})
+
+	b.Run("json", func(b *testing.B) {
+		benchmarkUnmarshal(b, batches, func(batch *RequestsV2) ([]byte, error) { return jsoniter.Marshal(batch) })
+	})
+
+	b.Run("binary", func(b *testing.B) {
+		benchmarkUnmarshal(b, batches, (*RequestsV2).MarshalBinary)
+	})
+}
+
+func benchmarkMarshal(b *testing.B, batches []RequestsV2, marshal func(*RequestsV2) ([]byte, error)) {
+	b.Helper()
+
+	size := 0
+
+	for i := 0; i < b.N; i++ {
+		size = 0
+
+		for j := range batches {
+			data, err := marshal(&batches[j])
+			require.NoError(b, err)
+
+			size += len(data)
+		}
+	}
+
+	b.ReportMetric(float64(size), "bytes/record")
+}
+
+func benchmarkUnmarshal(b *testing.B, batches []RequestsV2, marshal func(*RequestsV2) ([]byte, error)) {
+	b.Helper()
+
+	encoded := make([][]byte, 0, len(batches))
+
+	for j := range batches {
+		data, err := marshal(&batches[j])
+		require.NoError(b, err)
+
+		encoded = append(encoded, data)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, data := range encoded {
+			require.NoError(b, new(RequestsV2).Unmarshal(data))
+		}
+	}
+}
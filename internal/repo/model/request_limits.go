@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"io"
+	"sort"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
@@ -11,8 +12,11 @@ import (
 	"github.com/lueurxax/crypto-tweet-sense/internal/common"
 )
 
-// 10000/sizeofint32 = 2500
-const maxRequestsInBatch = 2500
+const (
+	// 10000/sizeofint32 = 2500
+	maxRequestsInBatch = 2500
+	bucketsInWindow    = 60
+)
 
 type RequestLimits struct {
 	WindowSeconds uint64
@@ -143,6 +147,17 @@ func (r *RequestLimits) CleanCounters() *Requests {
 	}
 }
 
+// BucketSize is the time span of the request bucket, the window is split in about 60 buckets,
+// so the expired requests are dropped by the whole buckets.
+func BucketSize(window time.Duration) time.Duration {
+	return max((window / bucketsInWindow).Truncate(time.Second), time.Second)
+}
+
+// BucketStart is the start of the bucket the request time belongs to.
+func BucketStart(window time.Duration, t time.Time) time.Time {
+	return t.Truncate(BucketSize(window))
+}
+
 type RequestLimitsV2 struct {
 	WindowSeconds uint64       `json:"window_seconds"`
 	RequestsCount uint32       `json:"requests_count"`
@@ -158,89 +173,80 @@ type RequestLimitsV2Debug struct {
 }
 
 func (r *RequestLimitsV2) Marshal() ([]byte, error) {
-	return jsoniter.Marshal(r)
+	return r.MarshalBinary()
 }
 
+// Unmarshal reads both the binary and the JSON records.
 func (r *RequestLimitsV2) Unmarshal(data []byte) error {
-	return jsoniter.Unmarshal(data, r)
+	return r.UnmarshalBinary(data)
 }
 
-func (r *RequestLimitsV2) CleanCounters() []RequestsV2 {
-	window := time.Duration(r.WindowSeconds) * time.Second
-	requestData := make([]RequestsV2, 0, len(r.Requests))
-
-	newStart := time.Now().Add(-window)
-
-	reached := false
-	for _, batch := range r.Requests {
-		if reached {
-			requestData = append(requestData, batch)
-			continue
-		}
-
-		counter := int32(0)
-
-		data := make([]uint32, 0, maxRequestsInBatch)
+// RequestsV2 is a batch of the request times, the first element is the offset from the start in seconds
+// and every next one is the offset from the previous request.
+type RequestsV2 struct {
+	Data  []uint32  `json:"data"`
+	Start time.Time `json:"start"`
+}
 
-		for j, key := range batch.Data {
-			tt := batch.Start.Add(time.Duration(key+uint32(counter)) * time.Second)
+// NewRequestsV2 packs the request times in the batch, the times must not be before the start.
+func NewRequestsV2(start time.Time, times []time.Time) RequestsV2 {
+	sorted := append(make([]time.Time, 0, len(times)), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
 
-			if time.Since(tt) < window {
-				value := uint32(tt.Sub(newStart).Seconds())
+	r := RequestsV2{Data: make([]uint32, 0, len(sorted)), Start: start}
+	offset := uint32(0)
 
-				if j != len(batch.Data)-1 {
-					data = batch.Data[j:]
-					data[0] = value
-				} else {
-					data = []uint32{value}
-				}
+	for _, t := range sorted {
+		value := uint32(t.Sub(start).Seconds())
+		r.Data = append(r.Data, value-offset)
+		offset = value
+	}
 
-				reached = true
+	return r
+}
 
-				break
-			}
+// NewRequestBuckets groups the request times in the buckets of the window, the buckets are ordered by their start.
+func NewRequestBuckets(window time.Duration, times []time.Time) []RequestsV2 {
+	byStart := make(map[time.Time][]time.Time)
+	starts := make([]time.Time, 0)
 
-			counter += int32(key)
+	for _, t := range times {
+		start := BucketStart(window, t)
+		if _, ok := byStart[start]; !ok {
+			starts = append(starts, start)
 		}
 
-		requestData = append(requestData, RequestsV2{
-			Data:  data,
-			Start: newStart,
-		})
+		byStart[start] = append(byStart[start], t)
 	}
 
-	return requestData
-}
-
-func (r *RequestLimitsV2) AddCounter(counterTime time.Time) {
-	r.RequestsCount++
-	if len(r.Requests) == 0 || len(r.Requests[len(r.Requests)-1].Data) == maxRequestsInBatch {
-		r.Requests = append(r.Requests, RequestsV2{
-			Data:  []uint32{0},
-			Start: counterTime,
-		})
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
 
-		return
+	buckets := make([]RequestsV2, 0, len(starts))
+	for _, start := range starts {
+		buckets = append(buckets, NewRequestsV2(start, byStart[start]))
 	}
 
-	value := uint32(counterTime.Sub(r.Requests[len(r.Requests)-1].Start).Seconds())
+	return buckets
+}
 
-	for _, el := range r.Requests[len(r.Requests)-1].Data {
-		value -= el
-	}
+// Times unpacks the request times of the batch.
+func (r *RequestsV2) Times() []time.Time {
+	times := make([]time.Time, 0, len(r.Data))
+	offset := uint32(0)
 
-	r.Requests[len(r.Requests)-1].Data = append(r.Requests[len(r.Requests)-1].Data, value)
-}
+	for _, v := range r.Data {
+		offset += v
+		times = append(times, r.Start.Add(time.Duration(offset)*time.Second))
+	}
 
-type RequestsV2 struct {
-	Data  []uint32  `json:"data"`
-	Start time.Time `json:"start"`
+	return times
 }
 
 func (r *RequestsV2) Marshal() ([]byte, error) {
-	return jsoniter.Marshal(r)
+	return r.MarshalBinary()
 }
 
+// Unmarshal reads both the binary and the JSON batches.
 func (r *RequestsV2) Unmarshal(data []byte) error {
-	return jsoniter.Unmarshal(data, r)
+	return r.UnmarshalBinary(data)
 }
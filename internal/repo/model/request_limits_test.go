@@ -87,3 +87,21 @@ func TestRequestLimits_CleanCounters(t *testing.T) {
 		}
 	})
 }
+
+func TestNewRequestBuckets(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		start.Add(time.Minute*2 + time.Second*5),
+		start.Add(time.Second * 10),
+		start.Add(time.Minute*2 + time.Second),
+		start.Add(time.Second * 3),
+	}
+
+	got := NewRequestBuckets(time.Hour, times)
+
+	require.Equal(t, []RequestsV2{
+		{Data: []uint32{3, 7}, Start: start},
+		{Data: []uint32{1, 4}, Start: start.Add(time.Minute * 2)},
+	}, got)
+	require.Empty(t, NewRequestBuckets(time.Hour, nil))
+}
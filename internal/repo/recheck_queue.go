@@ -0,0 +1,109 @@
+package fdb
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+type recheckQueueRepo interface {
+	ScheduleRecheck(ctx context.Context, id string, at time.Time) error
+	LeaseDueRechecks(ctx context.Context, now time.Time, limit int, until time.Time) ([]string, error)
+	CompleteRecheck(ctx context.Context, id string, leasedUntil time.Time) error
+}
+
+// ScheduleRecheck puts the tweet into the recheck queue, replacing its previous position.
+func (d *db) ScheduleRecheck(ctx context.Context, id string, at time.Time) error {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = d.unscheduleRecheckTx(tr, id); err != nil {
+		return err
+	}
+
+	key := d.keyBuilder.RecheckQueueItem(at, id)
+
+	tr.Set(key, []byte(id))
+	tr.Set(d.keyBuilder.RecheckByTweet(id), key)
+
+	return tr.Commit()
+}
+
+// LeaseDueRechecks moves up to limit tweets due before now to the until time and returns their ids in due order.
+// The leased tweets come back when they are not completed or rescheduled before the lease ends,
+// so the rechecks are not lost on a crash.
+func (d *db) LeaseDueRechecks(ctx context.Context, now time.Time, limit int, until time.Time) ([]string, error) {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := new(fdbclient.RangeOptions)
+	opts.SetLimit(limit)
+
+	kvs, err := tr.GetRange(d.keyBuilder.RecheckQueueUntil(now), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(kvs))
+
+	for _, kv := range kvs {
+		id := string(kv.Value)
+		key := d.keyBuilder.RecheckQueueItem(until, id)
+
+		tr.Clear(kv.Key)
+		tr.Set(key, []byte(id))
+		tr.Set(d.keyBuilder.RecheckByTweet(id), key)
+
+		ids = append(ids, id)
+	}
+
+	if err = tr.Commit(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// CompleteRecheck removes the leased tweet from the queue, the tweet rescheduled during the recheck is kept.
+func (d *db) CompleteRecheck(ctx context.Context, id string, leasedUntil time.Time) error {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := tr.Get(d.keyBuilder.RecheckByTweet(id))
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(key, d.keyBuilder.RecheckQueueItem(leasedUntil, id)) {
+		return tr.Commit()
+	}
+
+	tr.Clear(key)
+	tr.Clear(d.keyBuilder.RecheckByTweet(id))
+
+	return tr.Commit()
+}
+
+func (d *db) unscheduleRecheckTx(tr fdbclient.Transaction, id string) error {
+	key, err := tr.Get(d.keyBuilder.RecheckByTweet(id))
+	if err != nil {
+		return err
+	}
+
+	if key == nil {
+		return nil
+	}
+
+	tr.Clear(key)
+	tr.Clear(d.keyBuilder.RecheckByTweet(id))
+
+	return nil
+}
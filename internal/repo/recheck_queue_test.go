@@ -0,0 +1,61 @@
+package fdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+func Test_db_RecheckQueue(t *testing.T) {
+	ctx := context.Background()
+	repo := NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	now := time.Now()
+
+	require.NoError(t, repo.ScheduleRecheck(ctx, "1", now.Add(-time.Minute)))
+	require.NoError(t, repo.ScheduleRecheck(ctx, "2", now.Add(-2*time.Minute)))
+	require.NoError(t, repo.ScheduleRecheck(ctx, "3", now.Add(-3*time.Minute)))
+	require.NoError(t, repo.ScheduleRecheck(ctx, "4", now.Add(time.Minute)))
+
+	// rescheduling moves the tweet instead of duplicating it
+	require.NoError(t, repo.ScheduleRecheck(ctx, "1", now.Add(-4*time.Minute)))
+
+	require.NoError(t, repo.Save(ctx, []common.TweetSnapshot{{
+		Tweet:     &common.Tweet{ID: "2", TimeParsed: now.Add(-time.Hour)},
+		CheckedAt: now,
+	}}))
+	require.NoError(t, repo.DeleteTweet(ctx, "2"))
+
+	leasedUntil := now.Add(time.Hour)
+
+	ids, err := repo.LeaseDueRechecks(ctx, now, 1, leasedUntil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1"}, ids)
+
+	ids, err = repo.LeaseDueRechecks(ctx, now, 10, leasedUntil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"3"}, ids)
+
+	ids, err = repo.LeaseDueRechecks(ctx, now.Add(2*time.Minute), 10, leasedUntil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"4"}, ids)
+
+	// the completed recheck is removed, the rescheduled one is kept
+	require.NoError(t, repo.CompleteRecheck(ctx, "1", leasedUntil))
+	require.NoError(t, repo.ScheduleRecheck(ctx, "3", now.Add(2*time.Hour)))
+	require.NoError(t, repo.CompleteRecheck(ctx, "3", leasedUntil))
+
+	// the lease of the not completed recheck ends
+	ids, err = repo.LeaseDueRechecks(ctx, leasedUntil.Add(time.Second), 10, leasedUntil.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []string{"4"}, ids)
+
+	ids, err = repo.LeaseDueRechecks(ctx, now.Add(3*time.Hour), 10, now.Add(4*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []string{"3", "4"}, ids)
+}
@@ -2,11 +2,14 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
 )
 
 type twitterAccountsRepo interface {
@@ -15,6 +18,7 @@ type twitterAccountsRepo interface {
 	SaveCookie(ctx context.Context, login string, cookie []*http.Cookie) error
 	GetCookie(ctx context.Context, login string) ([]*http.Cookie, error)
 	GetAccounts(ctx context.Context) ([]common.TwitterAccount, error)
+	DeleteAccount(ctx context.Context, login string) error
 }
 
 func (d *db) GetAccounts(ctx context.Context) ([]common.TwitterAccount, error) {
@@ -54,6 +58,10 @@ func (d *db) GetAccounts(ctx context.Context) ([]common.TwitterAccount, error) {
 func (d *db) GetAccount(ctx context.Context, login string) (common.TwitterAccount, error) {
 	data, err := d.db.Get(ctx, string(d.keyBuilder.TwitterAccount(login))).Result()
 	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return common.TwitterAccount{}, fdb.ErrTwitterAccountNotFound
+		}
+
 		return common.TwitterAccount{}, err
 	}
 
@@ -87,6 +95,10 @@ func (d *db) SaveCookie(ctx context.Context, login string, cookie []*http.Cookie
 func (d *db) GetCookie(ctx context.Context, login string) ([]*http.Cookie, error) {
 	data, err := d.db.Get(ctx, string(d.keyBuilder.Cookie(login))).Result()
 	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, fdb.ErrCookieNotFound
+		}
+
 		return nil, err
 	}
 
@@ -98,3 +110,13 @@ func (d *db) GetCookie(ctx context.Context, login string) ([]*http.Cookie, error
 
 	return cookie, nil
 }
+
+// DeleteAccount removes the account with its cookies and proxy assignment.
+func (d *db) DeleteAccount(ctx context.Context, login string) error {
+	return d.db.Del(
+		ctx,
+		string(d.keyBuilder.TwitterAccount(login)),
+		string(d.keyBuilder.Cookie(login)),
+		string(d.keyBuilder.ProxyAssignment(login)),
+	).Err()
+}
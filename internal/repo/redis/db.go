@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"sync/atomic"
 
 	"github.com/gotd/td/telegram"
 	"github.com/lueurxax/crypto-tweet-sense/internal/log"
@@ -17,11 +18,16 @@ type DB interface {
 	version
 	telegram.SessionStorage
 	twitterAccountsRepo
+	proxyAssignmentsRepo
+	accountLeasesRepo
+	requestLimiterRepo
 }
 
 type db struct {
 	keyBuilder keys.Builder
 	db         *redis.Client
+	// requests is the sequence of the request members written by the replica
+	requests atomic.Uint64
 
 	log log.Logger
 }
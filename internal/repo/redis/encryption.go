@@ -0,0 +1,272 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/gotd/td/session"
+	"github.com/kelseyhightower/envconfig"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/envelope"
+)
+
+const sessionContext = "telegram:session"
+
+type EncryptionConfig struct {
+	// Keys are the id:base64 pairs separated by commas, KeysFile holds the same pairs one per line.
+	Keys       string `envconfig:"KEYS"`
+	KeysFile   string `envconfig:"KEYS_FILE"`
+	PrimaryKey string `envconfig:"PRIMARY_KEY"`
+}
+
+func GetEncryptionConfig() *EncryptionConfig {
+	cfg := new(EncryptionConfig)
+	if err := envconfig.Process("ENCRYPTION", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
+
+// Keyring returns nil when no keys are configured.
+func (c *EncryptionConfig) Keyring() (*envelope.Keyring, error) {
+	data := c.Keys
+
+	if c.KeysFile != "" {
+		file, err := os.ReadFile(c.KeysFile)
+		if err != nil {
+			return nil, err
+		}
+
+		data += "\n" + string(file)
+	}
+
+	keys, err := envelope.ParseKeys(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	return envelope.NewKeyring(keys, c.PrimaryKey)
+}
+
+// WithEncryption seals the Twitter credentials, cookies and Telegram session written through the db
+// when the keys are configured. The plain values written before are read transparently.
+func WithEncryption(db DB, config *EncryptionConfig) (DB, error) {
+	keyring, err := config.Keyring()
+	if err != nil || keyring == nil {
+		return db, err
+	}
+
+	return &encryptedDB{DB: db, keyring: keyring}, nil
+}
+
+// Reencrypt rewrites the secrets through the db, so the encrypted db seals them with its primary key.
+// It returns the number of rewritten accounts.
+func Reencrypt(ctx context.Context, db DB) (int, error) {
+	accounts, err := db.GetAccounts(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, account := range accounts {
+		if err = db.SaveAccount(ctx, account); err != nil {
+			return 0, err
+		}
+
+		cookies, err := db.GetCookie(ctx, account.Login)
+		if errors.Is(err, fdb.ErrCookieNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		if err = db.SaveCookie(ctx, account.Login, cookies); err != nil {
+			return 0, err
+		}
+	}
+
+	data, err := db.LoadSession(ctx)
+	if errors.Is(err, session.ErrNotFound) {
+		return len(accounts), nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(accounts), db.StoreSession(ctx, data)
+}
+
+// PlainSessionStorage is the foundationdb storage of the Telegram session kept before redis.
+type PlainSessionStorage interface {
+	LoadSession(ctx context.Context) ([]byte, error)
+	DeleteSession(ctx context.Context) error
+}
+
+// MoveSession stores the plain Telegram session of the old storage through the db unless the db already has one,
+// and deletes the plain copy. It returns false when the old storage has no session.
+func MoveSession(ctx context.Context, db DB, old PlainSessionStorage) (bool, error) {
+	data, err := old.LoadSession(ctx)
+	if errors.Is(err, session.ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	_, err = db.LoadSession(ctx)
+
+	switch {
+	case errors.Is(err, session.ErrNotFound):
+		if err = db.StoreSession(ctx, data); err != nil {
+			return false, err
+		}
+	case err != nil:
+		return false, err
+	}
+
+	return true, old.DeleteSession(ctx)
+}
+
+type encryptedDB struct {
+	DB
+	keyring *envelope.Keyring
+}
+
+func (d *encryptedDB) GetAccounts(ctx context.Context) ([]common.TwitterAccount, error) {
+	accounts, err := d.DB.GetAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range accounts {
+		if err = d.openAccount(&accounts[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return accounts, nil
+}
+
+func (d *encryptedDB) GetAccount(ctx context.Context, login string) (common.TwitterAccount, error) {
+	account, err := d.DB.GetAccount(ctx, login)
+	if err != nil {
+		return account, err
+	}
+
+	return account, d.openAccount(&account)
+}
+
+func (d *encryptedDB) SaveAccount(ctx context.Context, account common.TwitterAccount) error {
+	var err error
+
+	if account.AccessToken, err = d.seal(account.AccessToken, passwordContext(account.Login)); err != nil {
+		return err
+	}
+
+	if account.Confirmation, err = d.seal(account.Confirmation, confirmationContext(account.Login)); err != nil {
+		return err
+	}
+
+	return d.DB.SaveAccount(ctx, account)
+}
+
+func (d *encryptedDB) SaveCookie(ctx context.Context, login string, cookies []*http.Cookie) error {
+	sealed := make([]*http.Cookie, 0, len(cookies))
+
+	for _, cookie := range cookies {
+		value, err := d.seal(cookie.Value, cookieContext(login, cookie.Name))
+		if err != nil {
+			return err
+		}
+
+		c := *cookie
+		c.Value = value
+		sealed = append(sealed, &c)
+	}
+
+	return d.DB.SaveCookie(ctx, login, sealed)
+}
+
+func (d *encryptedDB) GetCookie(ctx context.Context, login string) ([]*http.Cookie, error) {
+	cookies, err := d.DB.GetCookie(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cookie := range cookies {
+		if cookie.Value, err = d.open(cookie.Value, cookieContext(login, cookie.Name)); err != nil {
+			return nil, err
+		}
+	}
+
+	return cookies, nil
+}
+
+func (d *encryptedDB) LoadSession(ctx context.Context) ([]byte, error) {
+	data, err := d.DB.LoadSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.keyring.Open(string(data), []byte(sessionContext))
+}
+
+func (d *encryptedDB) StoreSession(ctx context.Context, data []byte) error {
+	sealed, err := d.keyring.Seal(data, []byte(sessionContext))
+	if err != nil {
+		return err
+	}
+
+	return d.DB.StoreSession(ctx, []byte(sealed))
+}
+
+func (d *encryptedDB) openAccount(account *common.TwitterAccount) error {
+	var err error
+
+	if account.AccessToken, err = d.open(account.AccessToken, passwordContext(account.Login)); err != nil {
+		return err
+	}
+
+	account.Confirmation, err = d.open(account.Confirmation, confirmationContext(account.Login))
+
+	return err
+}
+
+// seal keeps the empty values empty, there is nothing to hide in them.
+func (d *encryptedDB) seal(value string, context []byte) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	return d.keyring.Seal([]byte(value), context)
+}
+
+func (d *encryptedDB) open(value string, context []byte) (string, error) {
+	plain, err := d.keyring.Open(value, context)
+
+	return string(plain), err
+}
+
+func passwordContext(login string) []byte {
+	return []byte("account:" + login + ":password")
+}
+
+func confirmationContext(login string) []byte {
+	return []byte("account:" + login + ":confirmation")
+}
+
+func cookieContext(login, name string) []byte {
+	return []byte("cookie:" + login + ":" + name)
+}
@@ -0,0 +1,196 @@
+package redis
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gotd/td/session"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/envelope"
+)
+
+// memoryDB keeps the values as the redis db would store them, the other methods are not used.
+type memoryDB struct {
+	DB
+	accounts map[string]common.TwitterAccount
+	cookies  map[string][]*http.Cookie
+	session  []byte
+}
+
+func (d *memoryDB) GetAccounts(context.Context) ([]common.TwitterAccount, error) {
+	accounts := make([]common.TwitterAccount, 0, len(d.accounts))
+	for _, account := range d.accounts {
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+func (d *memoryDB) GetAccount(_ context.Context, login string) (common.TwitterAccount, error) {
+	account, ok := d.accounts[login]
+	if !ok {
+		return account, fdb.ErrTwitterAccountNotFound
+	}
+
+	return account, nil
+}
+
+func (d *memoryDB) SaveAccount(_ context.Context, account common.TwitterAccount) error {
+	d.accounts[account.Login] = account
+	return nil
+}
+
+func (d *memoryDB) SaveCookie(_ context.Context, login string, cookies []*http.Cookie) error {
+	d.cookies[login] = cookies
+	return nil
+}
+
+func (d *memoryDB) GetCookie(_ context.Context, login string) ([]*http.Cookie, error) {
+	cookies, ok := d.cookies[login]
+	if !ok {
+		return nil, fdb.ErrCookieNotFound
+	}
+
+	// the redis db returns new cookies on every read
+	res := make([]*http.Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		c := *cookie
+		res = append(res, &c)
+	}
+
+	return res, nil
+}
+
+func (d *memoryDB) LoadSession(context.Context) ([]byte, error) {
+	if d.session == nil {
+		return nil, session.ErrNotFound
+	}
+
+	return d.session, nil
+}
+
+func (d *memoryDB) StoreSession(_ context.Context, data []byte) error {
+	d.session = data
+	return nil
+}
+
+// plainSessionStorage keeps the session as the foundationdb storage did.
+type plainSessionStorage struct {
+	session []byte
+}
+
+func (s *plainSessionStorage) LoadSession(context.Context) ([]byte, error) {
+	if s.session == nil {
+		return nil, session.ErrNotFound
+	}
+
+	return s.session, nil
+}
+
+func (s *plainSessionStorage) DeleteSession(context.Context) error {
+	s.session = nil
+	return nil
+}
+
+func encryptionConfig(primary string) *EncryptionConfig {
+	return &EncryptionConfig{
+		Keys: "k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("1", envelope.KeySize))) +
+			",k2:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("2", envelope.KeySize))),
+		PrimaryKey: primary,
+	}
+}
+
+func TestWithEncryption(t *testing.T) {
+	ctx := context.Background()
+	plain := &memoryDB{
+		accounts: map[string]common.TwitterAccount{"alice": {Login: "alice", AccessToken: "password"}},
+		cookies:  map[string][]*http.Cookie{"alice": {{Name: "auth_token", Value: "token"}}},
+		session:  []byte(`{"Version":1}`),
+	}
+
+	unchanged, err := WithEncryption(plain, &EncryptionConfig{})
+	require.NoError(t, err)
+	require.Same(t, plain, unchanged)
+
+	db, err := WithEncryption(plain, encryptionConfig("k1"))
+	require.NoError(t, err)
+
+	// the plain values written before are read transparently
+	account, err := db.GetAccount(ctx, "alice")
+	require.NoError(t, err)
+	require.Equal(t, "password", account.AccessToken)
+
+	count, err := Reencrypt(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.True(t, envelope.IsSealed(plain.accounts["alice"].AccessToken))
+	require.Empty(t, plain.accounts["alice"].Confirmation)
+	require.True(t, envelope.IsSealed(plain.cookies["alice"][0].Value))
+	require.True(t, envelope.IsSealed(string(plain.session)))
+
+	// the rotated keyring opens the values of the old key and reseals them
+	rotated, err := WithEncryption(plain, encryptionConfig("k2"))
+	require.NoError(t, err)
+
+	_, err = Reencrypt(ctx, rotated)
+	require.NoError(t, err)
+
+	onlyNew, err := WithEncryption(plain, &EncryptionConfig{
+		Keys:       "k2:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("2", envelope.KeySize))),
+		PrimaryKey: "k2",
+	})
+	require.NoError(t, err)
+
+	accounts, err := onlyNew.GetAccounts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []common.TwitterAccount{{Login: "alice", AccessToken: "password"}}, accounts)
+
+	cookies, err := onlyNew.GetCookie(ctx, "alice")
+	require.NoError(t, err)
+	require.Equal(t, "token", cookies[0].Value)
+
+	data, err := onlyNew.LoadSession(ctx)
+	require.NoError(t, err)
+	require.Equal(t, `{"Version":1}`, string(data))
+}
+
+func TestMoveSession(t *testing.T) {
+	ctx := context.Background()
+	plain := &memoryDB{}
+	old := &plainSessionStorage{session: []byte(`{"Version":1}`)}
+
+	db, err := WithEncryption(plain, encryptionConfig("k1"))
+	require.NoError(t, err)
+
+	moved, err := MoveSession(ctx, db, old)
+	require.NoError(t, err)
+	require.True(t, moved)
+	require.Nil(t, old.session)
+	require.True(t, envelope.IsSealed(string(plain.session)))
+
+	data, err := db.LoadSession(ctx)
+	require.NoError(t, err)
+	require.Equal(t, `{"Version":1}`, string(data))
+
+	// the session of the db is newer than the copy left in the old storage
+	old.session = []byte(`{"Version":0}`)
+
+	moved, err = MoveSession(ctx, db, old)
+	require.NoError(t, err)
+	require.True(t, moved)
+	require.Nil(t, old.session)
+
+	data, err = db.LoadSession(ctx)
+	require.NoError(t, err)
+	require.Equal(t, `{"Version":1}`, string(data))
+
+	moved, err = MoveSession(ctx, db, old)
+	require.NoError(t, err)
+	require.False(t, moved)
+}
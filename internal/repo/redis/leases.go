@@ -0,0 +1,78 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// the lease is renewed and released only by its owner
+var (
+	renewLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+type accountLeasesRepo interface {
+	AcquireLease(ctx context.Context, login, owner string, ttl time.Duration) (bool, error)
+	RenewLease(ctx context.Context, login, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, login, owner string) error
+	Heartbeat(ctx context.Context, replica string, ttl time.Duration) error
+	DeleteReplica(ctx context.Context, replica string) error
+	CountReplicas(ctx context.Context) (int, error)
+}
+
+func (d *db) AcquireLease(ctx context.Context, login, owner string, ttl time.Duration) (bool, error) {
+	return d.db.SetNX(ctx, string(d.keyBuilder.AccountLease(login)), owner, ttl).Result()
+}
+
+// RenewLease returns false when the lease is expired or held by another owner.
+func (d *db) RenewLease(ctx context.Context, login, owner string, ttl time.Duration) (bool, error) {
+	renewed, err := renewLease.Run(ctx, d.db, []string{string(d.keyBuilder.AccountLease(login))}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return renewed == 1, nil
+}
+
+func (d *db) ReleaseLease(ctx context.Context, login, owner string) error {
+	return releaseLease.Run(ctx, d.db, []string{string(d.keyBuilder.AccountLease(login))}, owner).Err()
+}
+
+func (d *db) Heartbeat(ctx context.Context, replica string, ttl time.Duration) error {
+	return d.db.Set(ctx, string(d.keyBuilder.Replica(replica)), time.Now().Unix(), ttl).Err()
+}
+
+func (d *db) DeleteReplica(ctx context.Context, replica string) error {
+	return d.db.Del(ctx, string(d.keyBuilder.Replica(replica))).Err()
+}
+
+// CountReplicas counts the replicas with the live heartbeat.
+func (d *db) CountReplicas(ctx context.Context) (int, error) {
+	var (
+		cursor uint64
+		count  int
+	)
+
+	for {
+		keys, next, err := d.db.Scan(ctx, cursor, string(d.keyBuilder.Replicas())+"*", 0).Result()
+		if err != nil {
+			return 0, err
+		}
+
+		count += len(keys)
+
+		if cursor = next; cursor == 0 {
+			return count, nil
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type proxyAssignmentsRepo interface {
+	GetProxyAssignment(ctx context.Context, login string) (string, error)
+	SaveProxyAssignment(ctx context.Context, login, proxy string) error
+}
+
+// GetProxyAssignment returns the proxy the account is bound to, empty when the account has no proxy yet.
+func (d *db) GetProxyAssignment(ctx context.Context, login string) (string, error) {
+	proxy, err := d.db.Get(ctx, string(d.keyBuilder.ProxyAssignment(login))).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+
+	return proxy, err
+}
+
+func (d *db) SaveProxyAssignment(ctx context.Context, login, proxy string) error {
+	return d.db.Set(ctx, string(d.keyBuilder.ProxyAssignment(login)), proxy, 0).Err()
+}
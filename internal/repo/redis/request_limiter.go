@@ -0,0 +1,248 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/model"
+)
+
+// The request limit is the hash of the window and threshold, the requests of the limit are the sorted set
+// scored by the request time in milliseconds.
+const (
+	RequestLimitWindowField    = "window_seconds"
+	RequestLimitThresholdField = "threshold"
+)
+
+var (
+	// the limit is created only once, the counters of the missing limit are never written
+	createRequestLimit = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "window_seconds", ARGV[1], "threshold", ARGV[2])
+return 1`)
+	// the threshold is only raised, -1 is returned for the missing limit
+	increaseThreshold = redis.NewScript(`
+local current = redis.call("HGET", KEYS[1], "threshold")
+if not current then
+	return -1
+end
+if tonumber(current) > tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("HSET", KEYS[1], "threshold", ARGV[1])
+return 1`)
+)
+
+type requestLimiterRepo interface {
+	AddCounters(ctx context.Context, id string, window time.Duration, times []time.Time) error
+	CleanCounters(ctx context.Context, id string, window time.Duration) error
+	SetThreshold(ctx context.Context, id string, window time.Duration) error
+	IncreaseThresholdTo(ctx context.Context, id string, window time.Duration, threshold uint64) error
+	SetThresholdTo(ctx context.Context, id string, window time.Duration, threshold uint64) error
+	ResetCounters(ctx context.Context, id string, window time.Duration) error
+	CheckIfExist(ctx context.Context, id string, window time.Duration) (bool, error)
+	Create(ctx context.Context, id string, window time.Duration, threshold uint64) error
+	GetRequestLimit(ctx context.Context, id string, window time.Duration) (common.RequestLimitData, error)
+	GetRequestLimitDebug(ctx context.Context, id string, window time.Duration) (model.RequestLimitsV2Debug, error)
+}
+
+// RequestScore is the score of the request in the requests set of the limit.
+func RequestScore(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
+
+// RequestMember is the member of the request in the requests set, the sequence tells apart the requests
+// made at the same time.
+func RequestMember(t time.Time, seq uint64) string {
+	return strconv.FormatInt(t.UnixNano(), 10) + ":" + strconv.FormatUint(seq, 10)
+}
+
+func (d *db) GetRequestLimit(ctx context.Context, id string, window time.Duration) (common.RequestLimitData, error) {
+	pipe := d.db.Pipeline()
+	threshold := pipe.HGet(ctx, d.requestLimitKey(id, window), RequestLimitThresholdField)
+	count := pipe.ZCount(ctx, d.requestsKey(id, window), sinceScore(window), "+inf")
+
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return common.RequestLimitData{}, err
+	}
+
+	value, err := threshold.Uint64()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return common.RequestLimitData{}, fdb.ErrRequestLimitsNotFound
+		}
+
+		return common.RequestLimitData{}, err
+	}
+
+	return common.RequestLimitData{
+		RequestsCount: uint64(count.Val()),
+		Threshold:     value,
+	}, nil
+}
+
+func (d *db) AddCounters(ctx context.Context, id string, window time.Duration, times []time.Time) error {
+	if len(times) == 0 {
+		return nil
+	}
+
+	if err := d.checkRequestLimit(ctx, id, window); err != nil {
+		return err
+	}
+
+	members := make([]redis.Z, 0, len(times))
+	for _, t := range times {
+		members = append(members, redis.Z{Score: RequestScore(t), Member: RequestMember(t, d.requests.Add(1))})
+	}
+
+	d.log.WithField("id", id).WithField("requests", len(times)).Trace("add counters")
+
+	return d.db.ZAdd(ctx, d.requestsKey(id, window), members...).Err()
+}
+
+// CleanCounters removes the requests which are out of the window.
+func (d *db) CleanCounters(ctx context.Context, id string, window time.Duration) error {
+	return d.db.ZRemRangeByScore(ctx, d.requestsKey(id, window), "-inf", strconv.FormatInt(time.Now().Add(-window).UnixMilli(), 10)).Err()
+}
+
+func (d *db) SetThreshold(ctx context.Context, id string, window time.Duration) error {
+	if err := d.checkRequestLimit(ctx, id, window); err != nil {
+		return err
+	}
+
+	count, err := d.db.ZCount(ctx, d.requestsKey(id, window), sinceScore(window), "+inf").Result()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		d.log.WithField("id", id).WithField("duration", int(window.Seconds())).Debug("can't set threshold, no requests")
+		return nil
+	}
+
+	return d.db.HSet(ctx, d.requestLimitKey(id, window), RequestLimitThresholdField, count).Err()
+}
+
+func (d *db) IncreaseThresholdTo(ctx context.Context, id string, window time.Duration, threshold uint64) error {
+	increased, err := increaseThreshold.Run(ctx, d.db, []string{d.requestLimitKey(id, window)}, threshold).Int()
+	if err != nil {
+		return err
+	}
+
+	if increased < 0 {
+		return fdb.ErrRequestLimitsNotFound
+	}
+
+	if increased == 1 {
+		d.log.
+			WithField("id", id).
+			WithField("duration", int(window.Seconds())).
+			WithField("new_threshold", threshold).
+			Debug("increase threshold")
+	}
+
+	return nil
+}
+
+// SetThresholdTo sets the threshold as is, unlike IncreaseThresholdTo it lowers the threshold too.
+func (d *db) SetThresholdTo(ctx context.Context, id string, window time.Duration, threshold uint64) error {
+	if err := d.checkRequestLimit(ctx, id, window); err != nil {
+		return err
+	}
+
+	return d.db.HSet(ctx, d.requestLimitKey(id, window), RequestLimitThresholdField, threshold).Err()
+}
+
+// ResetCounters removes every request of the limit, the threshold is kept.
+func (d *db) ResetCounters(ctx context.Context, id string, window time.Duration) error {
+	if err := d.checkRequestLimit(ctx, id, window); err != nil {
+		return err
+	}
+
+	return d.db.Del(ctx, d.requestsKey(id, window)).Err()
+}
+
+func (d *db) CheckIfExist(ctx context.Context, id string, window time.Duration) (bool, error) {
+	exists, err := d.db.Exists(ctx, d.requestLimitKey(id, window)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return exists == 1, nil
+}
+
+func (d *db) Create(ctx context.Context, id string, window time.Duration, threshold uint64) error {
+	created, err := createRequestLimit.Run(ctx, d.db, []string{d.requestLimitKey(id, window)}, uint64(window.Seconds()), threshold).Int()
+	if err != nil {
+		return err
+	}
+
+	if created == 0 {
+		return fdb.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+// GetRequestLimitDebug groups the stored requests in the buckets of the window as the foundationdb repository does.
+func (d *db) GetRequestLimitDebug(ctx context.Context, id string, window time.Duration) (model.RequestLimitsV2Debug, error) {
+	result := model.RequestLimitsV2Debug{Requests: make([]model.RequestsV2, 0)}
+
+	limit, err := d.GetRequestLimit(ctx, id, window)
+	if err != nil {
+		return result, err
+	}
+
+	requests, err := d.db.ZRangeWithScores(ctx, d.requestsKey(id, window), 0, -1).Result()
+	if err != nil {
+		return result, err
+	}
+
+	times := make([]time.Time, 0, len(requests))
+	for _, request := range requests {
+		times = append(times, time.UnixMilli(int64(request.Score)))
+	}
+
+	result.WindowSeconds = uint64(window.Seconds())
+	result.RequestsCount = uint32(limit.RequestsCount)
+	result.Threshold = limit.Threshold
+	result.Requests = model.NewRequestBuckets(window, times)
+
+	d.log.WithField("elements", len(result.Requests)).Info("requests buckets")
+
+	return result, nil
+}
+
+func (d *db) checkRequestLimit(ctx context.Context, id string, window time.Duration) error {
+	exists, err := d.CheckIfExist(ctx, id, window)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fdb.ErrRequestLimitsNotFound
+	}
+
+	return nil
+}
+
+func (d *db) requestLimitKey(id string, window time.Duration) string {
+	return string(d.keyBuilder.RequestLimits(id, window))
+}
+
+func (d *db) requestsKey(id string, window time.Duration) string {
+	return string(d.keyBuilder.RequestsByRequestLimits(id, window))
+}
+
+// sinceScore is the exclusive lower bound of the requests within the window.
+func sinceScore(window time.Duration) string {
+	return "(" + strconv.FormatInt(time.Now().Add(-window).UnixMilli(), 10)
+}
@@ -2,11 +2,19 @@ package redis
 
 import (
 	"context"
+	"errors"
+
+	"github.com/gotd/td/session"
+	"github.com/redis/go-redis/v9"
 )
 
 func (d *db) LoadSession(ctx context.Context) ([]byte, error) {
 	data, err := d.db.Get(ctx, string(d.keyBuilder.TelegramSessionStorage())).Result()
 	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, session.ErrNotFound
+		}
+
 		return nil, err
 	}
 
@@ -13,13 +13,19 @@ import (
 	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
 )
 
-const dataKey = "data"
+const (
+	dataKey = "data"
+	// the thresholds changed by the limits tool reach the running limiters after the cached records expire
+	requestsCacheTTL = time.Minute
+)
 
 type requestLimiter interface {
-	AddCounter(ctx context.Context, id string, window time.Duration, counterTime time.Time) error
+	AddCounters(ctx context.Context, id string, window time.Duration, times []time.Time) error
 	CleanCounters(ctx context.Context, id string, window time.Duration) error
 	SetThreshold(ctx context.Context, id string, window time.Duration) error
 	IncreaseThresholdTo(ctx context.Context, id string, window time.Duration, threshold uint64) error
+	SetThresholdTo(ctx context.Context, id string, window time.Duration, threshold uint64) error
+	ResetCounters(ctx context.Context, id string, window time.Duration) error
 	CheckIfExist(ctx context.Context, id string, window time.Duration) (bool, error)
 	Create(ctx context.Context, id string, window time.Duration, threshold uint64) error
 	GetRequestLimit(ctx context.Context, id string, window time.Duration) (common.RequestLimitData, error)
@@ -37,92 +43,109 @@ func (d *db) GetRequestLimit(ctx context.Context, id string, window time.Duratio
 		return common.RequestLimitData{}, err
 	}
 
+	count, err := d.countRequests(tx, id, window)
+	if err != nil {
+		return common.RequestLimitData{}, err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return common.RequestLimitData{}, err
 	}
 
 	return common.RequestLimitData{
-		RequestsCount: uint64(el.RequestsCount),
+		RequestsCount: count,
 		Threshold:     el.Threshold,
 	}, nil
 }
 
-func (d *db) AddCounter(ctx context.Context, id string, window time.Duration, counterTime time.Time) error {
+// AddCounters merges the request times into the time buckets, every touched bucket is written once.
+func (d *db) AddCounters(ctx context.Context, id string, window time.Duration, times []time.Time) error {
+	if len(times) == 0 {
+		return nil
+	}
+
 	tx, err := d.db.NewTransaction(ctx)
 	if err != nil {
 		return err
 	}
 
-	el, err := d.getRateLimit(ctx, tx, id, window)
-	if err != nil {
+	if _, err = d.getRateLimit(ctx, tx, id, window); err != nil {
 		return err
 	}
 
-	el.AddCounter(counterTime)
+	buckets := make(map[time.Time][]time.Time)
 
-	data, err := el.Marshal()
-	if err != nil {
-		return err
+	for _, t := range times {
+		start := model.BucketStart(window, t)
+		buckets[start] = append(buckets[start], t)
 	}
 
-	key := d.keyBuilder.RequestLimits(id, window)
+	for start, bucketTimes := range buckets {
+		key := d.keyBuilder.RequestBucket(id, window, start)
 
-	tx.Set(key, data)
+		data, err := tx.Get(key)
+		if err != nil {
+			return err
+		}
 
-	data, err = el.Requests[len(el.Requests)-1].Marshal()
-	if err != nil {
-		return err
-	}
+		if data != nil {
+			stored := new(model.RequestsV2)
+			if err = stored.Unmarshal(data); err != nil {
+				return errors.Join(err, ErrRequestLimitsUnmarshallingError)
+			}
 
-	tx.Set(d.keyBuilder.Requests(id, window, el.Requests[len(el.Requests)-1].Start), data)
+			bucketTimes = append(stored.Times(), bucketTimes...)
+		}
 
-	if err = d.requestsCache.Set(ctx, key, el); err != nil {
-		d.log.WithError(err).Error("set cache error")
+		bucket := model.NewRequestsV2(start, bucketTimes)
+
+		if data, err = bucket.Marshal(); err != nil {
+			return err
+		}
+
+		tx.Set(key, data)
 	}
 
-	d.log.WithField("request_limits", el).Trace("add counter")
+	d.log.WithField("id", id).WithField("requests", len(times)).WithField("buckets", len(buckets)).Trace("add counters")
 
 	return tx.Commit()
 }
 
+// CleanCounters clears the buckets which are out of the window entirely,
+// the requests of the bucket crossing the window border are filtered on read.
 func (d *db) CleanCounters(ctx context.Context, id string, window time.Duration) error {
 	tx, err := d.db.NewTransaction(ctx)
 	if err != nil {
 		return err
 	}
 
-	el, err := d.getRateLimit(ctx, tx, id, window)
+	tx.ClearKeyRange(d.keyBuilder.RequestBucketsUntil(id, window, model.BucketStart(window, time.Now().Add(-window))))
+
+	return tx.Commit()
+}
+
+func (d *db) SetThreshold(ctx context.Context, id string, window time.Duration) error {
+	tx, err := d.db.NewTransaction(ctx)
 	if err != nil {
-		return errors.Join(err, ErrRequestLimitsUnmarshallingError)
+		return err
 	}
 
-	if el.Requests == nil {
-		el.Requests = make([]model.RequestsV2, 0)
+	el, err := d.getRateLimit(ctx, tx, id, window)
+	if err != nil {
+		return err
 	}
 
-	deleteMap := make(map[time.Time]struct{})
-
-	for _, v := range el.Requests {
-		deleteMap[v.Start] = struct{}{}
+	count, err := d.countRequests(tx, id, window)
+	if err != nil {
+		return err
 	}
 
-	requests := el.CleanCounters()
-
-	insert := make([]model.RequestsV2, 0, len(requests))
-
-	for _, v := range requests {
-		if _, ok := deleteMap[v.Start]; ok {
-			delete(deleteMap, v.Start)
-		} else {
-			insert = append(insert, v)
-		}
+	if count == 0 {
+		d.log.WithField("id", id).WithField("duration", int(window.Seconds())).Debug("can't set threshold, no requests")
+		return nil
 	}
 
-	el.RequestsCount = 0
-
-	for _, v := range el.Requests {
-		el.RequestsCount += uint32(len(v.Data))
-	}
+	el.Threshold = count
 
 	data, err := el.Marshal()
 	if err != nil {
@@ -133,29 +156,14 @@ func (d *db) CleanCounters(ctx context.Context, id string, window time.Duration)
 
 	tx.Set(key, data)
 
-	for v := range deleteMap {
-		tx.Clear(d.keyBuilder.Requests(id, window, v))
-	}
-
-	for _, v := range insert {
-		data, err = v.Marshal()
-		if err != nil {
-			return err
-		}
-
-		tx.Set(d.keyBuilder.Requests(id, window, v.Start), data)
-	}
-
-	el.Requests = requests
-
-	if err = d.requestsCache.Set(ctx, key, el); err != nil {
+	if err = d.requestsCache.Set(ctx, key, el, store.WithExpiration(requestsCacheTTL)); err != nil {
 		d.log.WithError(err).Error("set cache error")
 	}
 
 	return tx.Commit()
 }
 
-func (d *db) SetThreshold(ctx context.Context, id string, window time.Duration) error {
+func (d *db) IncreaseThresholdTo(ctx context.Context, id string, window time.Duration, threshold uint64) error {
 	tx, err := d.db.NewTransaction(ctx)
 	if err != nil {
 		return err
@@ -166,12 +174,18 @@ func (d *db) SetThreshold(ctx context.Context, id string, window time.Duration)
 		return err
 	}
 
-	if el.RequestsCount == 0 {
-		d.log.WithField("id", id).WithField("duration", int(window.Seconds())).Debug("can't set threshold, no requests")
+	if el.Threshold > threshold {
 		return nil
 	}
 
-	el.Threshold = uint64(el.RequestsCount)
+	d.log.
+		WithField("id", id).
+		WithField("duration", int(window.Seconds())).
+		WithField("old_threshold", el.Threshold).
+		WithField("new_threshold", threshold).
+		Debug("increase threshold")
+
+	el.Threshold = threshold
 
 	data, err := el.Marshal()
 	if err != nil {
@@ -182,14 +196,15 @@ func (d *db) SetThreshold(ctx context.Context, id string, window time.Duration)
 
 	tx.Set(key, data)
 
-	if err = d.requestsCache.Set(ctx, key, el); err != nil {
+	if err = d.requestsCache.Set(ctx, key, el, store.WithExpiration(requestsCacheTTL)); err != nil {
 		d.log.WithError(err).Error("set cache error")
 	}
 
 	return tx.Commit()
 }
 
-func (d *db) IncreaseThresholdTo(ctx context.Context, id string, window time.Duration, threshold uint64) error {
+// SetThresholdTo sets the threshold as is, unlike IncreaseThresholdTo it lowers the threshold too.
+func (d *db) SetThresholdTo(ctx context.Context, id string, window time.Duration, threshold uint64) error {
 	tx, err := d.db.NewTransaction(ctx)
 	if err != nil {
 		return err
@@ -200,17 +215,6 @@ func (d *db) IncreaseThresholdTo(ctx context.Context, id string, window time.Dur
 		return err
 	}
 
-	if el.Threshold > threshold {
-		return nil
-	}
-
-	d.log.
-		WithField("id", id).
-		WithField("duration", int(window.Seconds())).
-		WithField("old_threshold", el.Threshold).
-		WithField("new_threshold", threshold).
-		Debug("increase threshold")
-
 	el.Threshold = threshold
 
 	data, err := el.Marshal()
@@ -222,13 +226,31 @@ func (d *db) IncreaseThresholdTo(ctx context.Context, id string, window time.Dur
 
 	tx.Set(key, data)
 
-	if err = d.requestsCache.Set(ctx, key, el); err != nil {
+	if err = d.requestsCache.Set(ctx, key, el, store.WithExpiration(requestsCacheTTL)); err != nil {
 		d.log.WithError(err).Error("set cache error")
 	}
 
 	return tx.Commit()
 }
 
+// ResetCounters clears every request of the limit, the threshold is kept.
+func (d *db) ResetCounters(ctx context.Context, id string, window time.Duration) error {
+	tx, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err = d.getRateLimit(ctx, tx, id, window); err != nil {
+		return err
+	}
+
+	if err = tx.ClearRange(d.keyBuilder.RequestBuckets(id, window)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (d *db) CheckIfExist(ctx context.Context, id string, window time.Duration) (bool, error) {
 	tx, err := d.db.NewTransaction(ctx)
 	if err != nil {
@@ -308,28 +330,7 @@ func (d *db) getRateLimit(ctx context.Context, tx fdbclient.Transaction, id stri
 		return nil, err
 	}
 
-	el.Requests = make([]model.RequestsV2, 0)
-
-	pr, err := fdb.PrefixRange(d.keyBuilder.RequestsByRequestLimits(id, window))
-	if err != nil {
-		return nil, err
-	}
-
-	kvs, err := tx.GetRange(pr)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, kv := range kvs {
-		r := new(model.RequestsV2)
-		if err = r.Unmarshal(kv.Value); err != nil {
-			return nil, err
-		}
-
-		el.Requests = append(el.Requests, *r)
-	}
-
-	if err = d.requestsCache.Set(ctx, key, el); err != nil {
+	if err = d.requestsCache.Set(ctx, key, el, store.WithExpiration(requestsCacheTTL)); err != nil {
 		d.log.WithError(err).Error("set cache error")
 	}
 
@@ -349,34 +350,76 @@ func (d *db) GetRequestLimitDebug(ctx context.Context, id string, window time.Du
 		return result, err
 	}
 
-	result.RequestsCount = el.RequestsCount
+	count, err := d.countRequests(tx, id, window)
+	if err != nil {
+		return result, err
+	}
+
+	result.RequestsCount = uint32(count)
 	result.WindowSeconds = el.WindowSeconds
 	result.Threshold = el.Threshold
 
-	pr, err := fdb.PrefixRange(d.keyBuilder.RequestsByRequestLimits(id, window))
-	if err != nil {
+	if result.Requests, err = d.getRequestBuckets(tx, id, window, time.Time{}); err != nil {
 		return result, err
 	}
 
-	kvs, err := tx.GetRange(pr)
-	if err != nil {
+	d.log.WithField("elements", len(result.Requests)).Info("requests buckets")
+
+	if err = tx.Commit(); err != nil {
 		return result, err
 	}
 
-	d.log.WithField("elements", len(kvs)).Info("requests batches")
+	return result, nil
+}
 
-	for _, kv := range kvs {
-		r := new(model.RequestsV2)
-		if err = r.Unmarshal(kv.Value); err != nil {
-			return result, err
+// countRequests counts the requests within the window, the expired buckets may be not cleaned yet.
+func (d *db) countRequests(tx fdbclient.Transaction, id string, window time.Duration) (uint64, error) {
+	since := time.Now().Add(-window)
+
+	buckets, err := d.getRequestBuckets(tx, id, window, since)
+	if err != nil {
+		return 0, err
+	}
+
+	count := uint64(0)
+
+	for _, bucket := range buckets {
+		for _, t := range bucket.Times() {
+			if t.After(since) {
+				count++
+			}
 		}
+	}
 
-		result.Requests = append(result.Requests, *r)
+	return count, nil
+}
+
+// getRequestBuckets reads the buckets holding the requests made after the time.
+func (d *db) getRequestBuckets(tx fdbclient.Transaction, id string, window time.Duration, since time.Time) ([]model.RequestsV2, error) {
+	pr, err := fdb.PrefixRange(d.keyBuilder.RequestBuckets(id, window))
+	if err != nil {
+		return nil, err
 	}
 
-	if err = tx.Commit(); err != nil {
-		return result, err
+	if !since.IsZero() {
+		pr.Begin = fdb.Key(d.keyBuilder.RequestBucket(id, window, model.BucketStart(window, since)))
 	}
 
-	return result, nil
+	kvs, err := tx.GetRange(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]model.RequestsV2, 0, len(kvs))
+
+	for _, kv := range kvs {
+		bucket := model.RequestsV2{}
+		if err = bucket.Unmarshal(kv.Value); err != nil {
+			return nil, errors.Join(err, ErrRequestLimitsUnmarshallingError)
+		}
+
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, nil
 }
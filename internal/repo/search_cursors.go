@@ -0,0 +1,120 @@
+package fdb
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+type searchCursorsRepo interface {
+	SaveSearchCursor(ctx context.Context, cursor common.SearchCursor) error
+	GetSearchCursors(ctx context.Context) ([]common.SearchCursor, error)
+	DeleteSearchCursor(ctx context.Context, cursor common.SearchCursor) error
+	SaveSearchWatermark(ctx context.Context, query string, until time.Time) error
+	GetSearchWatermark(ctx context.Context, query string) (time.Time, error)
+}
+
+func (d *db) SaveSearchCursor(ctx context.Context, cursor common.SearchCursor) error {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := jsoniter.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+
+	tr.Set(d.keyBuilder.SearchCursor(cursor.Query, cursor.Start), data)
+
+	return tr.Commit()
+}
+
+func (d *db) GetSearchCursors(ctx context.Context) ([]common.SearchCursor, error) {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := fdb.PrefixRange(d.keyBuilder.SearchCursors())
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := tr.GetRange(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]common.SearchCursor, 0, len(kvs))
+
+	for _, kv := range kvs {
+		cursor := common.SearchCursor{}
+		if err = jsoniter.Unmarshal(kv.Value, &cursor); err != nil {
+			return nil, err
+		}
+
+		res = append(res, cursor)
+	}
+
+	return res, tr.Commit()
+}
+
+func (d *db) DeleteSearchCursor(ctx context.Context, cursor common.SearchCursor) error {
+	return d.db.Clear(ctx, d.keyBuilder.SearchCursor(cursor.Query, cursor.Start))
+}
+
+// SaveSearchWatermark moves the time the query is searched until, the earlier time never moves it back.
+func (d *db) SaveSearchWatermark(ctx context.Context, query string, until time.Time) error {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := d.getSearchWatermarkTx(tr, query)
+	if err != nil {
+		return err
+	}
+
+	if !until.After(current) {
+		return tr.Commit()
+	}
+
+	tr.Set(d.keyBuilder.SearchWatermark(query), binary.BigEndian.AppendUint64(nil, uint64(until.UTC().UnixNano())))
+
+	return tr.Commit()
+}
+
+// GetSearchWatermark returns the time the query is searched until, zero for the query never searched.
+func (d *db) GetSearchWatermark(ctx context.Context, query string) (time.Time, error) {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	until, err := d.getSearchWatermarkTx(tr, query)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return until, tr.Commit()
+}
+
+func (d *db) getSearchWatermarkTx(tr fdbclient.Transaction, query string) (time.Time, error) {
+	data, err := tr.Get(d.keyBuilder.SearchWatermark(query))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(data) != binary.Size(uint64(0)) {
+		return time.Time{}, nil
+	}
+
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data))).UTC(), nil
+}
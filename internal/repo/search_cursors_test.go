@@ -0,0 +1,66 @@
+package fdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+func Test_db_SearchCursors(t *testing.T) {
+	ctx := context.Background()
+	repo := NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	start := time.Now().UTC().Add(-time.Hour)
+
+	first := common.SearchCursor{Query: "btc", Start: start, End: start.Add(time.Minute), Cursor: "a"}
+	second := common.SearchCursor{Query: "btc", Start: start.Add(time.Minute), End: start.Add(2 * time.Minute), Cursor: "b"}
+
+	require.NoError(t, repo.SaveSearchCursor(ctx, second))
+	require.NoError(t, repo.SaveSearchCursor(ctx, first))
+
+	// progress overwrites the cursor with the same query and start
+	first.Cursor = "c"
+	require.NoError(t, repo.SaveSearchCursor(ctx, first))
+
+	cursors, err := repo.GetSearchCursors(ctx)
+	require.NoError(t, err)
+	require.Len(t, cursors, 2)
+	require.Equal(t, "c", cursors[0].Cursor)
+	require.True(t, first.Start.Equal(cursors[0].Start))
+	require.Equal(t, "b", cursors[1].Cursor)
+
+	require.NoError(t, repo.DeleteSearchCursor(ctx, cursors[0]))
+
+	cursors, err = repo.GetSearchCursors(ctx)
+	require.NoError(t, err)
+	require.Len(t, cursors, 1)
+	require.Equal(t, "b", cursors[0].Cursor)
+}
+
+func Test_db_SearchWatermark(t *testing.T) {
+	ctx := context.Background()
+	repo := NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	until := time.Now().UTC().Truncate(time.Second)
+
+	watermark, err := repo.GetSearchWatermark(ctx, "btc")
+	require.NoError(t, err)
+	require.True(t, watermark.IsZero())
+
+	require.NoError(t, repo.SaveSearchWatermark(ctx, "btc", until))
+
+	// the watermark never moves back
+	require.NoError(t, repo.SaveSearchWatermark(ctx, "btc", until.Add(-time.Minute)))
+
+	watermark, err = repo.GetSearchWatermark(ctx, "btc")
+	require.NoError(t, err)
+	require.True(t, until.Equal(watermark))
+
+	watermark, err = repo.GetSearchWatermark(ctx, "eth")
+	require.NoError(t, err)
+	require.True(t, watermark.IsZero())
+}
@@ -0,0 +1,140 @@
+package fdb
+
+import (
+	"context"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+type searchQueriesRepo interface {
+	SaveSearchQuery(ctx context.Context, query common.SearchQuery) error
+	GetSearchQuery(ctx context.Context, name string) (common.SearchQuery, error)
+	GetSearchQueries(ctx context.Context) ([]common.SearchQuery, error)
+	DeleteSearchQuery(ctx context.Context, name string) error
+	SeedSearchQueries(ctx context.Context, queries []common.SearchQuery) (bool, error)
+}
+
+func (d *db) SaveSearchQuery(ctx context.Context, query common.SearchQuery) error {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := jsoniter.Marshal(query)
+	if err != nil {
+		return err
+	}
+
+	tr.Set(d.keyBuilder.SearchQuery(query.Name), data)
+
+	return tr.Commit()
+}
+
+func (d *db) GetSearchQuery(ctx context.Context, name string) (common.SearchQuery, error) {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return common.SearchQuery{}, err
+	}
+
+	data, err := tr.Get(d.keyBuilder.SearchQuery(name))
+	if err != nil {
+		return common.SearchQuery{}, err
+	}
+
+	if data == nil {
+		return common.SearchQuery{}, ErrSearchQueryNotFound
+	}
+
+	query := common.SearchQuery{}
+	if err = jsoniter.Unmarshal(data, &query); err != nil {
+		return common.SearchQuery{}, err
+	}
+
+	return query, tr.Commit()
+}
+
+func (d *db) GetSearchQueries(ctx context.Context) ([]common.SearchQuery, error) {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := fdb.PrefixRange(d.keyBuilder.SearchQueries())
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := tr.GetRange(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]common.SearchQuery, 0, len(kvs))
+
+	for _, kv := range kvs {
+		query := common.SearchQuery{}
+		if err = jsoniter.Unmarshal(kv.Value, &query); err != nil {
+			return nil, err
+		}
+
+		res = append(res, query)
+	}
+
+	return res, tr.Commit()
+}
+
+func (d *db) DeleteSearchQuery(ctx context.Context, name string) error {
+	return d.db.Clear(ctx, d.keyBuilder.SearchQuery(name))
+}
+
+// SeedSearchQueries stores the queries once, so the queries deleted later are not seeded again.
+// The queries stored before the seeding are kept instead. It returns false when nothing is stored.
+func (d *db) SeedSearchQueries(ctx context.Context, queries []common.SearchQuery) (bool, error) {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	seeded, err := tr.Get(d.keyBuilder.SearchQueriesSeeded())
+	if err != nil {
+		return false, err
+	}
+
+	if seeded != nil {
+		return false, tr.Commit()
+	}
+
+	pr, err := fdb.PrefixRange(d.keyBuilder.SearchQueries())
+	if err != nil {
+		return false, err
+	}
+
+	opts := new(fdbclient.RangeOptions)
+	opts.SetLimit(1)
+
+	kvs, err := tr.GetRange(pr, opts)
+	if err != nil {
+		return false, err
+	}
+
+	tr.Set(d.keyBuilder.SearchQueriesSeeded(), []byte{1})
+
+	if len(kvs) > 0 {
+		return false, tr.Commit()
+	}
+
+	for _, query := range queries {
+		data, err := jsoniter.Marshal(query)
+		if err != nil {
+			return false, err
+		}
+
+		tr.Set(d.keyBuilder.SearchQuery(query.Name), data)
+	}
+
+	return true, tr.Commit()
+}
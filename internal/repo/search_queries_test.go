@@ -0,0 +1,70 @@
+package fdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+func Test_db_SearchQueries(t *testing.T) {
+	ctx := context.Background()
+	repo := NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+
+	query := common.SearchQuery{Name: "btc", Keywords: []string{"bitcoin"}, Lang: "en", Enabled: true, SearchInterval: time.Minute}
+
+	_, err := repo.GetSearchQuery(ctx, query.Name)
+	require.ErrorIs(t, err, ErrSearchQueryNotFound)
+
+	require.NoError(t, repo.SaveSearchQuery(ctx, query))
+	require.NoError(t, repo.SaveSearchQuery(ctx, common.SearchQuery{Name: "eth", Keywords: []string{"ethereum"}}))
+
+	got, err := repo.GetSearchQuery(ctx, query.Name)
+	require.NoError(t, err)
+	require.Equal(t, query, got)
+
+	require.NoError(t, repo.DeleteSearchQuery(ctx, "eth"))
+
+	queries, err := repo.GetSearchQueries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []common.SearchQuery{query}, queries)
+}
+
+func Test_db_SeedSearchQueries(t *testing.T) {
+	ctx := context.Background()
+	repo := NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	seeds := []common.SearchQuery{{Name: "btc", Keywords: []string{"bitcoin"}, Enabled: true}}
+
+	seeded, err := repo.SeedSearchQueries(ctx, seeds)
+	require.NoError(t, err)
+	require.True(t, seeded)
+
+	// the queries deleted by the operator are not seeded again
+	require.NoError(t, repo.DeleteSearchQuery(ctx, "btc"))
+
+	seeded, err = repo.SeedSearchQueries(ctx, seeds)
+	require.NoError(t, err)
+	require.False(t, seeded)
+
+	queries, err := repo.GetSearchQueries(ctx)
+	require.NoError(t, err)
+	require.Empty(t, queries)
+
+	// the queries stored before the seeding are kept
+	stored := NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	require.NoError(t, stored.SaveSearchQuery(ctx, common.SearchQuery{Name: "eth", Keywords: []string{"ethereum"}}))
+
+	seeded, err = stored.SeedSearchQueries(ctx, seeds)
+	require.NoError(t, err)
+	require.False(t, seeded)
+
+	queries, err = stored.GetSearchQueries(ctx)
+	require.NoError(t, err)
+	require.Len(t, queries, 1)
+	require.Equal(t, "eth", queries[0].Name)
+}
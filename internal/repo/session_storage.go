@@ -4,8 +4,14 @@ import (
 	"context"
 
 	"github.com/gotd/td/session"
+	"github.com/gotd/td/telegram"
 )
 
+type sessionRepo interface {
+	telegram.SessionStorage
+	DeleteSession(ctx context.Context) error
+}
+
 // Deprecated: use redis instead
 func (d *db) LoadSession(ctx context.Context) ([]byte, error) {
 	tx, err := d.db.NewTransaction(ctx)
@@ -40,3 +46,15 @@ func (d *db) StoreSession(ctx context.Context, data []byte) error {
 
 	return tx.Commit()
 }
+
+// DeleteSession removes the plain session left in foundationdb after it is moved to redis.
+func (d *db) DeleteSession(ctx context.Context) error {
+	tx, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx.Clear(d.keyBuilder.TelegramSessionStorage())
+
+	return tx.Commit()
+}
@@ -0,0 +1,223 @@
+package fdb
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/keys"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+const (
+	// history point key suffix is a 0x00 separator and a big endian unix nano timestamp
+	historyPointSuffixLen = 9
+	historyPageSize       = 1000
+	prefixLen             = len(keys.Prefix{})
+)
+
+type tweetHistoryRepo interface {
+	GetTweetHistory(ctx context.Context, id string) ([]common.TweetEngagement, error)
+	CompactTweetHistory(ctx context.Context, retention common.HistoryRetention) error
+}
+
+func (d *db) GetTweetHistory(ctx context.Context, id string) ([]common.TweetEngagement, error) {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := d.getTweetHistoryTx(tr, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tr.Commit(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// CompactTweetHistory pages through the whole history, every page is read and cleared in its own transactions,
+// so the compaction stays within the transaction limits on any history size.
+func (d *db) CompactTweetHistory(ctx context.Context, retention common.HistoryRetention) error {
+	pr, err := fdb.PrefixRange(d.keyBuilder.TweetsHistory())
+	if err != nil {
+		return err
+	}
+
+	compaction := newHistoryCompaction(retention, time.Now())
+
+	for begin := pr.Begin; ; {
+		kvs, err := d.getHistoryPage(ctx, fdb.KeyRange{Begin: begin, End: pr.End})
+		if err != nil {
+			return err
+		}
+
+		if err = d.clearHistoryPoints(ctx, compaction.collect(kvs, d.log)); err != nil {
+			return err
+		}
+
+		if len(kvs) < historyPageSize {
+			break
+		}
+
+		// the page continues right after the last read key
+		begin = append(fdb.Key{}, append(kvs[len(kvs)-1].Key, 0x00)...)
+	}
+
+	d.log.WithField("processed", compaction.processed).WithField("compacted", compaction.compacted).Debug("compact tweet history")
+
+	return nil
+}
+
+func (d *db) getTweetHistoryTx(tr fdbclient.Transaction, id string) ([]common.TweetEngagement, error) {
+	pr, err := fdb.PrefixRange(d.keyBuilder.TweetHistory(id))
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := tr.GetRange(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]common.TweetEngagement, 0, len(kvs))
+
+	for _, kv := range kvs {
+		point := common.TweetEngagement{}
+		if err = jsoniter.Unmarshal(kv.Value, &point); err != nil {
+			return nil, err
+		}
+
+		res = append(res, point)
+	}
+
+	return res, nil
+}
+
+func (d *db) saveTweetHistoryTx(tr fdbclient.Transaction, tweet common.TweetSnapshot) error {
+	data, err := jsoniter.Marshal(tweet.Engagement())
+	if err != nil {
+		return err
+	}
+
+	tr.Set(d.keyBuilder.TweetHistoryPoint(tweet.ID, tweet.CheckedAt), data)
+
+	return nil
+}
+
+func (d *db) getHistoryPage(ctx context.Context, pr fdb.KeyRange) ([]fdb.KeyValue, error) {
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := new(fdbclient.RangeOptions)
+	opts.SetLimit(historyPageSize)
+	opts.SetMode(fdb.StreamingModeWantAll)
+
+	kvs, err := tr.GetRange(pr, opts)
+	if err != nil {
+		d.log.WithError(err).Error(errIterating)
+		return nil, err
+	}
+
+	if err = tr.Commit(); err != nil {
+		return nil, err
+	}
+
+	return kvs, nil
+}
+
+func (d *db) clearHistoryPoints(ctx context.Context, toClear []fdb.Key) error {
+	if len(toClear) == 0 {
+		return nil
+	}
+
+	tr, err := d.db.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range toClear {
+		tr.Clear(key)
+	}
+
+	return tr.Commit()
+}
+
+// historyCompaction finds the points removed by the retention policy, the pages are passed in the key order.
+// In the thinned zone the latest point of every step is kept.
+type historyCompaction struct {
+	retention    common.HistoryRetention
+	rawBorder    time.Time
+	maxAgeBorder time.Time
+
+	prevID     string
+	prevBucket time.Time
+	prevKey    fdb.Key
+
+	processed int
+	compacted int
+}
+
+func newHistoryCompaction(retention common.HistoryRetention, now time.Time) *historyCompaction {
+	return &historyCompaction{
+		retention:    retention,
+		rawBorder:    now.Add(-retention.KeepRaw),
+		maxAgeBorder: now.Add(-retention.MaxAge),
+	}
+}
+
+// collect returns the keys of the page to clear, the previous point of the step may come from the previous page.
+func (c *historyCompaction) collect(kvs []fdb.KeyValue, logger *logrus.Entry) []fdb.Key {
+	toClear := make([]fdb.Key, 0)
+
+	for _, kv := range kvs {
+		c.processed++
+
+		id, checkedAt, ok := parseHistoryPointKey(kv.Key)
+		if !ok {
+			logger.WithField("key", kv.Key).Warn("wrong history point key")
+			continue
+		}
+
+		switch {
+		case c.retention.MaxAge > 0 && checkedAt.Before(c.maxAgeBorder):
+			toClear = append(toClear, kv.Key)
+			continue
+		case c.retention.Step <= 0 || checkedAt.After(c.rawBorder):
+			continue
+		}
+
+		bucket := checkedAt.Truncate(c.retention.Step)
+		if c.prevKey != nil && c.prevID == id && c.prevBucket.Equal(bucket) {
+			toClear = append(toClear, c.prevKey)
+		}
+
+		c.prevID, c.prevBucket, c.prevKey = id, bucket, kv.Key
+	}
+
+	c.compacted += len(toClear)
+
+	return toClear
+}
+
+func parseHistoryPointKey(key fdb.Key) (string, time.Time, bool) {
+	if len(key) < prefixLen+historyPointSuffixLen {
+		return "", time.Time{}, false
+	}
+
+	idEnd := len(key) - historyPointSuffixLen
+	id := string(key[prefixLen:idEnd])
+	nano := binary.BigEndian.Uint64(key[idEnd+1:])
+
+	return id, time.Unix(0, int64(nano)).UTC(), true
+}
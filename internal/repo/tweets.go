@@ -23,7 +23,6 @@ const (
 type tweetRepo interface {
 	Save(ctx context.Context, tweets []common.TweetSnapshot) error
 	DeleteTweet(ctx context.Context, id string) error
-	GetOldestTopReachableTweet(ctx context.Context, top float64) (*common.TweetSnapshot, int, error)
 	GetTweetsOlderThen(ctx context.Context, after time.Time) ([]string, error)
 	SaveSentTweet(ctx context.Context, link string) error
 	CheckIfSentTweetExist(ctx context.Context, link string) (bool, error)
@@ -80,6 +79,9 @@ func (d *db) Save(ctx context.Context, tweets []common.TweetSnapshot) error {
 				d.log.WithField("old", oldTweet).WithField("new", tweet).Debug("skip tweet because it is older then exist")
 				continue
 			}
+
+			// the backfilled tweet stays backfilled when the watcher finds it again
+			tweet.Backfilled = tweet.Backfilled || oldTweet.Backfilled
 		}
 
 		data, err := jsoniter.Marshal(tweet)
@@ -90,6 +92,7 @@ func (d *db) Save(ctx context.Context, tweets []common.TweetSnapshot) error {
 		dataIndex, err := jsoniter.Marshal(&common.TweetSnapshotIndex{
 			ID:              tweet.ID,
 			RatingGrowSpeed: tweet.RatingGrowSpeed,
+			RatingCurve:     tweet.RatingCurve,
 			CreatedAt:       tweet.TimeParsed,
 			CheckedAt:       tweet.CheckedAt,
 		})
@@ -99,19 +102,30 @@ func (d *db) Save(ctx context.Context, tweets []common.TweetSnapshot) error {
 
 		tr.Set(key, data)
 
+		if err = d.saveTweetHistoryTx(tr, tweet); err != nil {
+			return err
+		}
+
 		if oldTweet != nil {
 			tr.Clear(d.keyBuilder.TweetRatingIndex(oldTweet.RatingGrowSpeed, oldTweet.ID))
 		}
 
 		tr.Set(d.keyBuilder.TweetRatingIndex(tweet.RatingGrowSpeed, tweet.ID), dataIndex)
-		tr.Set(d.keyBuilder.TweetCreationIndex(tweet.TimeParsed, tweet.ID), []byte(tweet.ID))
+
+		// the cleaner of the too old tweets walks the creation index, the backfilled tweets are left out of it
+		switch {
+		case !tweet.Backfilled:
+			tr.Set(d.keyBuilder.TweetCreationIndex(tweet.TimeParsed, tweet.ID), []byte(tweet.ID))
+		case oldTweet != nil:
+			tr.Clear(d.keyBuilder.TweetCreationIndex(oldTweet.TimeParsed, oldTweet.ID))
+		}
 
 		if err = tr.Commit(); err != nil {
 			d.log.WithError(err).Error("error while committing transaction")
 		}
 
 		if oldTweet == nil {
-			atomic.AddInt32(d.tweetsCounter, 1)
+			d.addTweets(1)
 		}
 	}
 
@@ -133,82 +147,17 @@ func (d *db) DeleteTweet(ctx context.Context, id string) error {
 	tr.Clear(d.keyBuilder.TweetRatingIndex(data.RatingGrowSpeed, data.ID))
 	tr.Clear(d.keyBuilder.TweetCreationIndex(data.TimeParsed, data.ID))
 
-	if err = tr.Commit(); err != nil {
+	if err = d.unscheduleRecheckTx(tr, id); err != nil {
 		return err
 	}
 
-	atomic.AddInt32(d.tweetsCounter, -1)
-
-	return nil
-}
-
-func (d *db) GetOldestTopReachableTweet(ctx context.Context, top float64) (*common.TweetSnapshot, int, error) {
-	ch, err := d.GetTweetPositiveIndexes(ctx)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	var result *common.TweetSnapshotIndex
-
-	var fallbackResult *string
-
-	pretopcounter := 0
-
-	best := 0.0000000001
-
-	for snapshotIndex := range ch {
-		predictedRating := snapshotIndex.RatingGrowSpeed * time.Since(snapshotIndex.CreatedAt).Seconds()
-
-		// skip unreachable top tweets
-		if predictedRating > best {
-			best = predictedRating
-			fallbackResult = &snapshotIndex.ID
-		}
-
-		// skip unreachable top tweets
-		if predictedRating < top {
-			continue
-		}
-		pretopcounter++
-
-		if result == nil {
-			result = snapshotIndex
-			continue
-		}
-
-		if result.CreatedAt.After(snapshotIndex.CreatedAt) {
-			result = snapshotIndex
-		}
-	}
-
-	d.log.WithField("pretopcounter", pretopcounter).
-		Debug("GetOldestTopReachableTweet")
-
-	if result == nil {
-		if fallbackResult != nil {
-			result, err := d.getTweet(ctx, *fallbackResult)
-			if err != nil {
-				return nil, 0, err
-			}
-
-			return result, 0, nil
-		}
-
-		return nil, 0, ErrTweetsNotFound
+	if err = tr.Commit(); err != nil {
+		return err
 	}
 
-	res, err := d.getTweet(ctx, result.ID)
-	if err != nil {
-		if errors.Is(err, ErrTweetsNotFound) {
-			if clearErr := d.db.Clear(ctx, result.Key); clearErr != nil {
-				return nil, 0, clearErr
-			}
-		}
+	d.addTweets(-1)
 
-		return nil, 0, err
-	}
-
-	return res, pretopcounter, nil
+	return nil
 }
 
 func (d *db) GetTweetsOlderThen(ctx context.Context, after time.Time) ([]string, error) {
@@ -217,6 +166,7 @@ func (d *db) GetTweetsOlderThen(ctx context.Context, after time.Time) ([]string,
 	tr, err := d.db.NewTransaction(ctx)
 	if err != nil {
 		d.log.WithError(err).Error(errCreatingTransaction)
+		return nil, err
 	}
 
 	go d.getTweetsUntilTx(tr, after, ch)
@@ -250,21 +200,6 @@ func (d *db) GetTweetPositiveIndexes(ctx context.Context) (<-chan *common.TweetS
 	return ch, nil
 }
 
-func (d *db) getTweet(ctx context.Context, id string) (*common.TweetSnapshot, error) {
-	tr, err := d.db.NewTransaction(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	res, err := d.getTweetTx(tr, id)
-	if err != nil {
-		d.log.WithField("id", id).WithError(err).Error("error while getting tweet")
-		return nil, err
-	}
-
-	return res, tr.Commit()
-}
-
 func (d *db) getTweetTx(tr fdbclient.Transaction, id string) (*common.TweetSnapshot, error) {
 	data, err := tr.Get(d.keyBuilder.Tweet(id))
 	if err != nil {
@@ -462,13 +397,12 @@ func (d *db) CheckTweetOrClear(ctx context.Context, key fdb.Key, id string) {
 }
 
 func (d *db) Count(ctx context.Context) (uint32, error) {
-	if d.tweetsCounter != nil {
-		count := atomic.LoadInt32(d.tweetsCounter)
+	counter := d.tweetsCounter.Load()
+	if counter != nil {
+		count := atomic.LoadInt32(counter)
 		if count > 0 {
 			return uint32(count), nil
 		}
-	} else {
-		d.tweetsCounter = new(int32)
 	}
 
 	tr, err := d.db.NewTransaction(ctx)
@@ -493,7 +427,19 @@ func (d *db) Count(ctx context.Context) (uint32, error) {
 		return 0, err
 	}
 
-	atomic.StoreInt32(d.tweetsCounter, int32(len(kvs)))
+	if counter == nil {
+		d.tweetsCounter.CompareAndSwap(nil, new(int32))
+		counter = d.tweetsCounter.Load()
+	}
+
+	atomic.StoreInt32(counter, int32(len(kvs)))
 
 	return uint32(len(kvs)), nil
 }
+
+// addTweets keeps the loaded counter up to date, the counter is left to the first Count till then.
+func (d *db) addTweets(delta int32) {
+	if counter := d.tweetsCounter.Load(); counter != nil {
+		atomic.AddInt32(counter, delta)
+	}
+}
@@ -0,0 +1,150 @@
+package fdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+func Test_db_SaveAndDeleteTweet(t *testing.T) {
+	ctx := context.Background()
+	client := fdbclient.NewMemoryDatabase()
+	repo := NewDBFromClient(client, logrus.NewEntry(logrus.New()))
+
+	now := time.Now().UTC()
+	tweet := common.TweetSnapshot{
+		Tweet:           &common.Tweet{ID: "1", TimeParsed: now.Add(-time.Hour)},
+		RatingGrowSpeed: 0.5,
+		CheckedAt:       now,
+	}
+
+	require.NoError(t, repo.Save(ctx, []common.TweetSnapshot{tweet}))
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), count)
+
+	ids, err := repo.GetTweetsOlderThen(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1"}, ids)
+
+	// the repository started later counts the stored tweets with the saved ones
+	second := NewDBFromClient(client, logrus.NewEntry(logrus.New()))
+	tweet.Tweet = &common.Tweet{ID: "2", TimeParsed: now.Add(-time.Hour)}
+	require.NoError(t, second.Save(ctx, []common.TweetSnapshot{tweet}))
+
+	count, err = second.Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), count)
+
+	require.NoError(t, second.DeleteTweet(ctx, "2"))
+	require.NoError(t, repo.DeleteTweet(ctx, "1"))
+
+	ids, err = repo.GetTweetsOlderThen(ctx, now)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+}
+
+func Test_db_TweetHistory(t *testing.T) {
+	ctx := context.Background()
+	repo := NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+
+	now := time.Now().UTC()
+	createdAt := now.Add(-3 * time.Hour)
+	checks := []time.Duration{
+		-150 * time.Minute,
+		-125 * time.Minute,
+		-121 * time.Minute,
+		-95 * time.Minute,
+		-10 * time.Minute,
+		-5 * time.Minute,
+	}
+
+	for i, offset := range checks {
+		require.NoError(t, repo.Save(ctx, []common.TweetSnapshot{{
+			Tweet:     &common.Tweet{ID: "1", TimeParsed: createdAt, Likes: i * 10},
+			CheckedAt: now.Add(offset),
+		}}))
+	}
+
+	require.NoError(t, repo.Save(ctx, []common.TweetSnapshot{{
+		Tweet:     &common.Tweet{ID: "12", TimeParsed: createdAt, Likes: 1},
+		CheckedAt: now,
+	}}))
+
+	history, err := repo.GetTweetHistory(ctx, "1")
+	require.NoError(t, err)
+	require.Len(t, history, len(checks))
+
+	for i := range history {
+		require.Equal(t, i*10, history[i].Likes)
+	}
+
+	require.NoError(t, repo.CompactTweetHistory(ctx, common.HistoryRetention{
+		KeepRaw: time.Hour,
+		Step:    time.Hour,
+		MaxAge:  140 * time.Minute,
+	}))
+
+	history, err = repo.GetTweetHistory(ctx, "1")
+	require.NoError(t, err)
+
+	// the first point is too old, the thinned zone keeps the latest point per hour
+	likes := make([]int, 0, len(history))
+	for _, point := range history {
+		likes = append(likes, point.Likes)
+	}
+
+	// buckets are aligned to the wall clock, so the expected set depends on the current time
+	expected := make([]int, 0, len(checks))
+
+	for i := 1; i <= 3; i++ {
+		bucket := now.Add(checks[i]).Truncate(time.Hour)
+		if i == 3 || !bucket.Equal(now.Add(checks[i+1]).Truncate(time.Hour)) {
+			expected = append(expected, i*10)
+		}
+	}
+
+	expected = append(expected, 40, 50)
+
+	require.Equal(t, expected, likes)
+
+	history, err = repo.GetTweetHistory(ctx, "12")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+}
+
+func Test_db_CompactTweetHistoryPages(t *testing.T) {
+	ctx := context.Background()
+	repo := NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+
+	now := time.Now().UTC()
+	points := historyPageSize*2 + historyPageSize/2
+
+	for i := points; i > 0; i-- {
+		require.NoError(t, repo.Save(ctx, []common.TweetSnapshot{{
+			Tweet:     &common.Tweet{ID: "1", TimeParsed: now.Add(-time.Duration(points+1) * time.Minute), Likes: points - i},
+			CheckedAt: now.Add(-time.Duration(i) * time.Minute),
+		}}))
+	}
+
+	require.NoError(t, repo.CompactTweetHistory(ctx, common.HistoryRetention{Step: 10 * time.Minute}))
+
+	history, err := repo.GetTweetHistory(ctx, "1")
+	require.NoError(t, err)
+
+	// every step keeps its latest point, the steps split by the pages included
+	buckets := make(map[time.Time]struct{})
+
+	for i := points; i > 0; i-- {
+		buckets[now.Add(-time.Duration(i)*time.Minute).Truncate(10*time.Minute)] = struct{}{}
+	}
+
+	require.Len(t, history, len(buckets))
+}
@@ -5,6 +5,9 @@ import (
 	"encoding/binary"
 )
 
+// sharedVersion is the last foundationdb migration counted by the version key shared with the migrations to redis
+const sharedVersion = 1
+
 type version interface {
 	GetVersion(ctx context.Context) (uint32, error)
 	WriteVersion(ctx context.Context, version uint32) error
@@ -19,7 +22,7 @@ func (d *db) WriteVersion(ctx context.Context, version uint32) error {
 	data := make([]byte, binary.Size(version))
 	binary.BigEndian.PutUint32(data, version)
 
-	tr.Set(d.keyBuilder.Version(), data)
+	tr.Set(d.keyBuilder.MigrationFDBVersion(), data)
 
 	return tr.Commit()
 }
@@ -30,14 +33,24 @@ func (d *db) GetVersion(ctx context.Context) (uint32, error) {
 		return 0, err
 	}
 
-	data, err := tr.Get(d.keyBuilder.Version())
+	data, err := tr.Get(d.keyBuilder.MigrationFDBVersion())
 	if err != nil {
 		return 0, err
 	}
 
+	if data != nil {
+		return binary.BigEndian.Uint32(data), nil
+	}
+
+	// the version key was shared with the migrations to redis before,
+	// the shared version is trusted up to the last foundationdb migration written there
+	if data, err = tr.Get(d.keyBuilder.Version()); err != nil {
+		return 0, err
+	}
+
 	if data == nil {
 		return 0, nil
 	}
 
-	return binary.BigEndian.Uint32(data), nil
+	return min(binary.BigEndian.Uint32(data), sharedVersion), nil
 }
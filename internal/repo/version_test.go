@@ -0,0 +1,60 @@
+package fdb
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/keys"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+func Test_db_MigrateFromSharedVersion(t *testing.T) {
+	ctx := context.Background()
+	client := fdbclient.NewMemoryDatabase()
+	builder := keys.NewBuilder()
+	repo := NewDBFromClient(client, logrus.NewEntry(logrus.New()))
+
+	tr, err := client.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	// the migrations to redis wrote their last version to the shared key
+	shared := make([]byte, 4)
+	binary.BigEndian.PutUint32(shared, 4)
+	tr.Set(builder.Version(), shared)
+
+	data, err := jsoniter.Marshal(common.TweetSnapshotIndex{ID: "1", RatingGrowSpeed: 1})
+	require.NoError(t, err)
+
+	tr.Set(builder.TweetRatingIndex(1, "1"), data)
+	require.NoError(t, tr.Commit())
+
+	v, err := repo.GetVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), v)
+
+	require.NoError(t, repo.Migrate(ctx))
+
+	v, err = repo.GetVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint32(5), v)
+
+	tr, err = client.NewTransaction(ctx)
+	require.NoError(t, err)
+
+	// the recheck queue is seeded by the second migration
+	queued, err := tr.Get(builder.RecheckByTweet("1"))
+	require.NoError(t, err)
+	require.NotNil(t, queued)
+
+	// the shared key is left to the migrations to redis
+	data, err = tr.Get(builder.Version())
+	require.NoError(t, err)
+	require.Equal(t, shared, data)
+	require.NoError(t, tr.Commit())
+}
@@ -0,0 +1,19 @@
+package source
+
+import (
+	"net/http"
+	"reflect"
+	"unsafe"
+
+	twitterscraper "github.com/lueurxax/twitter-scraper"
+)
+
+// HTTPClient returns the http client of the scraper, which the scraper keeps private. The scraper replaces
+// the transport of the client on every SetProxy, so the transport wrappers are applied again after it.
+// TODO: replace with a public transport hook of the scraper, e.g. Scraper.WrapTransport applied on every
+// SetProxy, once it is released upstream.
+func HTTPClient(s *twitterscraper.Scraper) *http.Client {
+	field := reflect.ValueOf(s).Elem().FieldByName("client")
+
+	return *(**http.Client)(unsafe.Pointer(field.UnsafeAddr()))
+}
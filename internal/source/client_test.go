@@ -0,0 +1,20 @@
+package source
+
+import (
+	"testing"
+	"time"
+
+	twitterscraper "github.com/lueurxax/twitter-scraper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClient(t *testing.T) {
+	scraper := twitterscraper.New().WithClientTimeout(time.Minute)
+
+	client := HTTPClient(scraper)
+	require.Equal(t, time.Minute, client.Timeout)
+	require.Nil(t, client.Transport)
+
+	require.NoError(t, scraper.SetProxy("http://10.0.0.1:3128"))
+	require.NotNil(t, client.Transport)
+}
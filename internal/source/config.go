@@ -0,0 +1,26 @@
+package source
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Config struct {
+	// NitterURL enables the Nitter source, e.g. https://nitter.net.
+	NitterURL      string        `envconfig:"NITTER_URL"`
+	NitterTimeout  time.Duration `envconfig:"NITTER_TIMEOUT" default:"30s"`
+	WebhookEnabled bool          `envconfig:"WEBHOOK_ENABLED" default:"false"`
+	// WebhookToken is the bearer token the webhook requests are authorized with, it is required by the webhook.
+	WebhookToken  string `envconfig:"WEBHOOK_TOKEN"`
+	WebhookBuffer int    `envconfig:"WEBHOOK_BUFFER" default:"10000"`
+}
+
+func GetConfig() *Config {
+	cfg := new(Config)
+	if err := envconfig.Process("SOURCE", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
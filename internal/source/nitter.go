@@ -0,0 +1,171 @@
+package source
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+)
+
+const (
+	// nitterCursorHeader is set by Nitter on RSS responses, it is the cursor of the next page.
+	nitterCursorHeader = "Min-Id"
+	twitterURL         = "https://twitter.com"
+)
+
+var (
+	tagRegexp   = regexp.MustCompile(`<[^>]*>`)
+	imageRegexp = regexp.MustCompile(`<img[^>]+src="([^"]+)"`)
+)
+
+type rssFeed struct {
+	Items []rssItem `xml:"channel>item"`
+}
+
+type rssItem struct {
+	Creator     string `xml:"creator"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	Link        string `xml:"link"`
+}
+
+type nitter struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (n *nitter) Search(ctx context.Context, query common.SearchQuery, limit int, cursor string) ([]common.TweetSnapshot, string, error) {
+	params := url.Values{"f": {"tweets"}, "q": {query.Render()}}
+
+	return n.feed(ctx, "/search/rss", params, limit, cursor)
+}
+
+// GetTweet is not supported, Nitter has no feed of a single tweet.
+func (n *nitter) GetTweet(context.Context, string) (*common.TweetSnapshot, error) {
+	return nil, ErrUnsupported
+}
+
+func (n *nitter) UserTimeline(ctx context.Context, username string, limit int, cursor string) ([]common.TweetSnapshot, string, error) {
+	return n.feed(ctx, "/"+url.PathEscape(username)+"/rss", url.Values{}, limit, cursor)
+}
+
+func (n *nitter) feed(ctx context.Context, path string, params url.Values, limit int, cursor string) ([]common.TweetSnapshot, string, error) {
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+path+"?"+params.Encode(), http.NoBody)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return nil, "", ErrRateLimited
+	case http.StatusNotFound:
+		return nil, "", ErrNotFound
+	default:
+		return nil, "", fmt.Errorf("nitter responded %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tweets, err := parseNitterFeed(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if limit > 0 && len(tweets) > limit {
+		tweets = tweets[:limit]
+	}
+
+	nextCursor := resp.Header.Get(nitterCursorHeader)
+	if len(tweets) == 0 || nextCursor == cursor {
+		nextCursor = ""
+	}
+
+	return tweets, nextCursor, nil
+}
+
+// parseNitterFeed converts the feed items, the tweet text is the description with the HTML markup stripped.
+func parseNitterFeed(data []byte) ([]common.TweetSnapshot, error) {
+	feed := rssFeed{}
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		return nil, err
+	}
+
+	tweets := make([]common.TweetSnapshot, 0, len(feed.Items))
+
+	for _, item := range feed.Items {
+		tweet, err := nitterItemToCommon(item)
+		if err != nil {
+			return nil, err
+		}
+
+		tweets = append(tweets, common.TweetSnapshot{Tweet: tweet, CheckedAt: time.Now()})
+	}
+
+	return tweets, nil
+}
+
+func nitterItemToCommon(item rssItem) (*common.Tweet, error) {
+	link, err := url.Parse(strings.TrimSpace(item.Link))
+	if err != nil {
+		return nil, err
+	}
+
+	// links look like https://nitter.net/<username>/status/<id>#m
+	parts := strings.Split(strings.Trim(link.Path, "/"), "/")
+	if len(parts) != 3 || parts[1] != "status" {
+		return nil, fmt.Errorf("unexpected nitter link %q", item.Link)
+	}
+
+	createdAt, err := time.Parse(time.RFC1123, item.PubDate)
+	if err != nil {
+		return nil, err
+	}
+
+	photos := make([]common.Photo, 0)
+	for _, match := range imageRegexp.FindAllStringSubmatch(item.Description, -1) {
+		photos = append(photos, common.Photo{URL: html.UnescapeString(match[1])})
+	}
+
+	username := strings.TrimPrefix(strings.TrimSpace(item.Creator), "@")
+	if username == "" {
+		username = parts[0]
+	}
+
+	return &common.Tweet{
+		ID:           parts[2],
+		PermanentURL: fmt.Sprintf("%s/%s/status/%s", twitterURL, username, parts[2]),
+		Text:         strings.TrimSpace(html.UnescapeString(tagRegexp.ReplaceAllString(item.Description, ""))),
+		TimeParsed:   createdAt.UTC(),
+		Timestamp:    createdAt.Unix(),
+		Username:     username,
+		Photos:       photos,
+	}, nil
+}
+
+// NewNitter reads the RSS feeds of the Nitter instance, engagement counters are not part of the feeds.
+func NewNitter(baseURL string, client *http.Client) Source {
+	return &nitter{baseURL: strings.TrimRight(baseURL, "/"), client: client}
+}
@@ -0,0 +1,73 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+)
+
+const nitterFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/" version="2.0">
+  <channel>
+    <title>Search results for "bitcoin"</title>
+    <item>
+      <title>Bitcoin &amp; friends</title>
+      <dc:creator>@saylor</dc:creator>
+      <description><![CDATA[<p>Bitcoin &amp; <a href="https://nitter.net/search?q=%23ETF">#ETF</a></p><img src="https://nitter.net/pic/media%2Fabc.jpg" style="max-width:250px;" />]]></description>
+      <pubDate>Tue, 02 Jan 2024 10:00:00 GMT</pubDate>
+      <guid>https://nitter.net/saylor/status/1742#m</guid>
+      <link>https://nitter.net/saylor/status/1742#m</link>
+    </item>
+  </channel>
+</rss>`
+
+func TestNitter_Search(t *testing.T) {
+	var gotQuery, gotCursor string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/search/rss", r.URL.Path)
+
+		gotQuery, gotCursor = r.URL.Query().Get("q"), r.URL.Query().Get("cursor")
+
+		w.Header().Set(nitterCursorHeader, "next")
+		_, _ = w.Write([]byte(nitterFeed))
+	}))
+	defer server.Close()
+
+	src := NewNitter(server.URL+"/", server.Client())
+
+	tweets, nextCursor, err := src.Search(context.Background(), common.KeywordQuery("btc", "bitcoin", time.Minute), 10, "prev")
+	require.NoError(t, err)
+	require.Equal(t, "bitcoin -filter:retweets", gotQuery)
+	require.Equal(t, "prev", gotCursor)
+	require.Equal(t, "next", nextCursor)
+	require.Len(t, tweets, 1)
+	require.Equal(t, &common.Tweet{
+		ID:           "1742",
+		PermanentURL: "https://twitter.com/saylor/status/1742",
+		Text:         "Bitcoin & #ETF",
+		TimeParsed:   time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+		Timestamp:    time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC).Unix(),
+		Username:     "saylor",
+		Photos:       []common.Photo{{URL: "https://nitter.net/pic/media%2Fabc.jpg"}},
+	}, tweets[0].Tweet)
+
+	_, err = src.GetTweet(context.Background(), "1742")
+	require.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestNitter_RateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	_, _, err := NewNitter(server.URL, server.Client()).UserTimeline(context.Background(), "saylor", 10, "")
+	require.ErrorIs(t, err, ErrRateLimited)
+}
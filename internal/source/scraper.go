@@ -0,0 +1,146 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	twitterscraper "github.com/lueurxax/twitter-scraper"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+)
+
+const (
+	notFound = "not found"
+
+	// messages of the Twitter API errors which tell the state of the account
+	locked           = "temporarily locked"
+	suspended        = "suspended"
+	notAuthenticated = "Could not authenticate you"
+)
+
+type scraper struct {
+	scraper *twitterscraper.Scraper
+}
+
+func (s *scraper) Search(ctx context.Context, query common.SearchQuery, limit int, cursor string) ([]common.TweetSnapshot, string, error) {
+	tweets, nextCursor, err := s.scraper.FetchSearchTweets(ctx, query.Render(), limit, cursor)
+	if err != nil {
+		return nil, "", scraperError(err)
+	}
+
+	return scraperTweetsToCommon(tweets), nextCursor, nil
+}
+
+func (s *scraper) GetTweet(ctx context.Context, id string) (*common.TweetSnapshot, error) {
+	tweet, err := s.scraper.GetTweet(ctx, id)
+	if err != nil {
+		return nil, scraperError(err)
+	}
+
+	return &common.TweetSnapshot{Tweet: scraperTweetToCommon(tweet), CheckedAt: time.Now()}, nil
+}
+
+func (s *scraper) UserTimeline(ctx context.Context, username string, limit int, cursor string) ([]common.TweetSnapshot, string, error) {
+	tweets, nextCursor, err := s.scraper.FetchTweets(ctx, username, limit, cursor)
+	if err != nil {
+		return nil, "", scraperError(err)
+	}
+
+	return scraperTweetsToCommon(tweets), nextCursor, nil
+}
+
+func scraperError(err error) error {
+	if errors.Is(err, twitterscraper.ErrRateLimitExceeded{}) {
+		return errors.Join(ErrRateLimited, err)
+	}
+
+	if strings.Contains(err.Error(), notFound) {
+		return errors.Join(ErrNotFound, err)
+	}
+
+	if accountErr := scraperAccountError(err); accountErr != nil {
+		return errors.Join(accountErr, err)
+	}
+
+	return err
+}
+
+func scraperAccountError(err error) error {
+	var apiErr twitterscraper.ErrOther
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+
+	switch {
+	case strings.Contains(err.Error(), locked):
+		return ErrLocked
+	case strings.Contains(err.Error(), suspended):
+		return ErrSuspended
+	case apiErr.StatusCode == http.StatusUnauthorized, strings.Contains(err.Error(), notAuthenticated):
+		return ErrUnauthorized
+	default:
+		return nil
+	}
+}
+
+func scraperTweetsToCommon(tweets []*twitterscraper.Tweet) []common.TweetSnapshot {
+	response := make([]common.TweetSnapshot, 0, len(tweets))
+
+	for _, tweet := range tweets {
+		response = append(response, common.TweetSnapshot{Tweet: scraperTweetToCommon(tweet), CheckedAt: time.Now()})
+	}
+
+	return response
+}
+
+func scraperTweetToCommon(tweet *twitterscraper.Tweet) *common.Tweet {
+	return &common.Tweet{
+		ID:           tweet.ID,
+		Likes:        tweet.Likes,
+		Name:         tweet.Name,
+		PermanentURL: tweet.PermanentURL,
+		Replies:      tweet.Replies,
+		Retweets:     tweet.Retweets,
+		Text:         tweet.Text,
+		TimeParsed:   tweet.TimeParsed,
+		Timestamp:    tweet.Timestamp,
+		UserID:       tweet.UserID,
+		Username:     tweet.Username,
+		Views:        tweet.Views,
+		Photos:       scraperPhotosToCommon(tweet.Photos),
+		Videos:       scraperVideosToCommon(tweet.Videos),
+	}
+}
+
+func scraperPhotosToCommon(photos []twitterscraper.Photo) []common.Photo {
+	res := make([]common.Photo, len(photos))
+	for i, photo := range photos {
+		res[i] = common.Photo{
+			ID:  photo.ID,
+			URL: photo.URL,
+		}
+	}
+
+	return res
+}
+
+func scraperVideosToCommon(videos []twitterscraper.Video) []common.Video {
+	res := make([]common.Video, len(videos))
+	for i, video := range videos {
+		res[i] = common.Video{
+			ID:      video.ID,
+			Preview: video.Preview,
+			URL:     video.URL,
+		}
+	}
+
+	return res
+}
+
+// NewScraper adapts the authorized scraper of a Twitter account.
+func NewScraper(s *twitterscraper.Scraper) Source {
+	return &scraper{scraper: s}
+}
@@ -0,0 +1,33 @@
+package source
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+)
+
+// Names of the sources a search query can select, an empty source is the scraper.
+const (
+	Scraper = "scraper"
+	Nitter  = "nitter"
+	Webhook = "webhook"
+)
+
+var (
+	ErrNotFound    = errors.New("tweet not found")
+	ErrRateLimited = errors.New("source rate limit exceeded")
+	ErrUnsupported = errors.New("operation is not supported by the source")
+	// ErrUnauthorized means the session of the account is no longer valid and it has to log in again.
+	ErrUnauthorized = errors.New("source account is not authorized")
+	ErrLocked       = errors.New("source account is locked")
+	ErrSuspended    = errors.New("source account is suspended")
+)
+
+// Source fetches tweets from one ingestion backend. Paged methods return the cursor of the next page,
+// an empty cursor means there are no more pages.
+type Source interface {
+	Search(ctx context.Context, query common.SearchQuery, limit int, cursor string) ([]common.TweetSnapshot, string, error)
+	GetTweet(ctx context.Context, id string) (*common.TweetSnapshot, error)
+	UserTimeline(ctx context.Context, username string, limit int, cursor string) ([]common.TweetSnapshot, string, error)
+}
@@ -0,0 +1,157 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/valyala/fasthttp"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+)
+
+// QueryParam is the route parameter of the webhook handler with the name of the query the tweets belong to.
+const QueryParam = "query"
+
+var (
+	ErrWrongWebhookTweet   = errors.New("webhook tweet must have id and created_at")
+	ErrUnknownWebhookQuery = errors.New("no webhook query with the name")
+)
+
+// WebhookSource is a push source, the tweets posted to the handler are queued until the query searches them.
+type WebhookSource interface {
+	Source
+	Handler(ctx *fasthttp.RequestCtx)
+}
+
+type webhookTweet struct {
+	ID        string    `json:"id"`
+	Text      string    `json:"text"`
+	Name      string    `json:"name"`
+	Username  string    `json:"username"`
+	UserID    string    `json:"user_id"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	Likes     int       `json:"likes"`
+	Retweets  int       `json:"retweets"`
+	Replies   int       `json:"replies"`
+	Views     int       `json:"views"`
+	Photos    []string  `json:"photos"`
+}
+
+func (t webhookTweet) toCommon(checkedAt time.Time) common.TweetSnapshot {
+	photos := make([]common.Photo, 0, len(t.Photos))
+	for _, photo := range t.Photos {
+		photos = append(photos, common.Photo{URL: photo})
+	}
+
+	return common.TweetSnapshot{
+		Tweet: &common.Tweet{
+			ID:           t.ID,
+			Likes:        t.Likes,
+			Name:         t.Name,
+			PermanentURL: t.URL,
+			Replies:      t.Replies,
+			Retweets:     t.Retweets,
+			Text:         t.Text,
+			TimeParsed:   t.CreatedAt.UTC(),
+			Timestamp:    t.CreatedAt.Unix(),
+			UserID:       t.UserID,
+			Username:     t.Username,
+			Views:        t.Views,
+			Photos:       photos,
+		},
+		CheckedAt: checkedAt,
+	}
+}
+
+type queries interface {
+	GetSearchQuery(ctx context.Context, name string) (common.SearchQuery, error)
+}
+
+type webhook struct {
+	mu       sync.Mutex
+	queues   map[string][]common.TweetSnapshot
+	capacity int
+	queries  queries
+}
+
+// Search drains the queue of the query, there is never a next page.
+func (w *webhook) Search(_ context.Context, query common.SearchQuery, limit int, _ string) ([]common.TweetSnapshot, string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	queue := w.queues[query.Name]
+	if limit <= 0 || limit > len(queue) {
+		limit = len(queue)
+	}
+
+	tweets := queue[:limit:limit]
+	w.queues[query.Name] = queue[limit:]
+
+	return tweets, "", nil
+}
+
+// GetTweet is not supported, the pushed tweets can not be fetched again.
+func (w *webhook) GetTweet(context.Context, string) (*common.TweetSnapshot, error) {
+	return nil, ErrUnsupported
+}
+
+// UserTimeline is not supported, the tweets are pushed per query.
+func (w *webhook) UserTimeline(context.Context, string, int, string) ([]common.TweetSnapshot, string, error) {
+	return nil, "", ErrUnsupported
+}
+
+// Handler accepts a JSON array of tweets for the query, the oldest queued tweets are dropped above the capacity.
+// Only the stored queries of the webhook source are accepted, the tweets of other ones would be never drained.
+func (w *webhook) Handler(ctx *fasthttp.RequestCtx) {
+	query, _ := ctx.UserValue(QueryParam).(string)
+
+	stored, err := w.queries.GetSearchQuery(ctx, query)
+	if err != nil && !errors.Is(err, fdb.ErrSearchQueryNotFound) {
+		ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	if err != nil || stored.Source != Webhook {
+		ctx.Error(ErrUnknownWebhookQuery.Error(), fasthttp.StatusNotFound)
+		return
+	}
+
+	payload := make([]webhookTweet, 0)
+	if err := jsoniter.Unmarshal(ctx.PostBody(), &payload); err != nil {
+		ctx.Error(err.Error(), fasthttp.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	tweets := make([]common.TweetSnapshot, 0, len(payload))
+
+	for _, tweet := range payload {
+		if tweet.ID == "" || tweet.CreatedAt.IsZero() {
+			ctx.Error(ErrWrongWebhookTweet.Error(), fasthttp.StatusBadRequest)
+			return
+		}
+
+		tweets = append(tweets, tweet.toCommon(now))
+	}
+
+	w.mu.Lock()
+	queue := append(w.queues[query], tweets...)
+
+	if len(queue) > w.capacity {
+		queue = queue[len(queue)-w.capacity:]
+	}
+
+	w.queues[query] = queue
+	w.mu.Unlock()
+
+	ctx.SetStatusCode(fasthttp.StatusAccepted)
+}
+
+func NewWebhook(capacity int, queries queries) WebhookSource {
+	return &webhook{queues: make(map[string][]common.TweetSnapshot), capacity: capacity, queries: queries}
+}
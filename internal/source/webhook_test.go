@@ -0,0 +1,71 @@
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+)
+
+func post(src WebhookSource, query, body string) int {
+	ctx := new(fasthttp.RequestCtx)
+	ctx.SetUserValue(QueryParam, query)
+	ctx.Request.SetBodyString(body)
+
+	src.Handler(ctx)
+
+	return ctx.Response.StatusCode()
+}
+
+type storedQueries map[string]common.SearchQuery
+
+func (q storedQueries) GetSearchQuery(_ context.Context, name string) (common.SearchQuery, error) {
+	query, ok := q[name]
+	if !ok {
+		return common.SearchQuery{}, fdb.ErrSearchQueryNotFound
+	}
+
+	return query, nil
+}
+
+func TestWebhook(t *testing.T) {
+	query := common.KeywordQuery("btc", "bitcoin", time.Minute)
+	query.Source = Webhook
+	eth := common.KeywordQuery("eth", "ethereum", time.Minute)
+	eth.Source = Webhook
+	src := NewWebhook(2, storedQueries{"btc": query, "eth": eth, "sol": common.KeywordQuery("sol", "solana", time.Minute)})
+
+	// the tweets of the unknown queries and the queries of other sources would be never drained
+	require.Equal(t, fasthttp.StatusNotFound, post(src, "doge", `[{"id":"5","created_at":"2024-01-02T10:00:00Z"}]`))
+	require.Equal(t, fasthttp.StatusNotFound, post(src, "sol", `[{"id":"6","created_at":"2024-01-02T10:00:00Z"}]`))
+
+	require.Equal(t, fasthttp.StatusBadRequest, post(src, "btc", `[{"text":"no id"}]`))
+	require.Equal(t, fasthttp.StatusAccepted, post(src, "btc", `[
+		{"id":"1","text":"first","created_at":"2024-01-02T10:00:00Z"},
+		{"id":"2","text":"second","created_at":"2024-01-02T10:01:00Z"},
+		{"id":"3","text":"third","username":"saylor","likes":5,"created_at":"2024-01-02T10:02:00Z"}
+	]`))
+	require.Equal(t, fasthttp.StatusAccepted, post(src, "eth", `[{"id":"4","created_at":"2024-01-02T10:00:00Z"}]`))
+
+	tweets, nextCursor, err := src.Search(context.Background(), query, 1, "")
+	require.NoError(t, err)
+	require.Empty(t, nextCursor)
+	require.Len(t, tweets, 1)
+	require.Equal(t, "2", tweets[0].ID)
+
+	tweets, _, err = src.Search(context.Background(), query, 10, "")
+	require.NoError(t, err)
+	require.Len(t, tweets, 1)
+	require.Equal(t, "3", tweets[0].ID)
+	require.Equal(t, "saylor", tweets[0].Username)
+	require.Equal(t, 5, tweets[0].Likes)
+
+	tweets, _, err = src.Search(context.Background(), query, 10, "")
+	require.NoError(t, err)
+	require.Empty(t, tweets)
+}
@@ -0,0 +1,66 @@
+package tweetfinder
+
+import (
+	"context"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+)
+
+// accountMiddleware reports the outcome of every request to the account manager and evicts the finder
+// when its account is no longer usable.
+type accountMiddleware struct {
+	login   string
+	manager accountManager
+	evict   func(status common.AccountStatus)
+	next    Finder
+}
+
+func (m *accountMiddleware) IsHot() bool {
+	return m.next.IsHot()
+}
+
+func (m *accountMiddleware) FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
+	tweets, nextCursor, err := m.next.FindNext(ctx, query, cursor)
+
+	m.report(ctx, err)
+
+	return tweets, nextCursor, err
+}
+
+func (m *accountMiddleware) Find(ctx context.Context, id string) (*common.TweetSnapshot, error) {
+	tweet, err := m.next.Find(ctx, id)
+
+	m.report(ctx, err)
+
+	return tweet, err
+}
+
+func (m *accountMiddleware) CurrentDelay() int64 {
+	return m.next.CurrentDelay()
+}
+
+func (m *accountMiddleware) CurrentTemp(ctx context.Context) float64 {
+	return m.next.CurrentTemp(ctx)
+}
+
+func (m *accountMiddleware) Init(context.Context) error {
+	return nil
+}
+
+func (m *accountMiddleware) Capacity() int {
+	return m.next.Capacity()
+}
+
+func (m *accountMiddleware) report(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	if status := m.manager.Report(ctx, m.login, err); !status.Usable() {
+		m.evict(status)
+	}
+}
+
+func newAccountMiddleware(manager accountManager, login string, evict func(status common.AccountStatus), next Finder) Finder {
+	return &accountMiddleware{login: login, manager: manager, evict: evict, next: next}
+}
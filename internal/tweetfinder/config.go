@@ -3,6 +3,7 @@ package tweetfinder
 import (
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
@@ -33,3 +34,115 @@ func (c ConfigProxies) GetProxies() []string {
 
 	return data
 }
+
+type SelectionConfig struct {
+	// Strategy picks the finder out of the idle ones: least_temperature, weighted_round_robin or query_affinity.
+	Strategy    string        `envconfig:"STRATEGY" default:"least_temperature"`
+	WaitTimeout time.Duration `envconfig:"WAIT_TIMEOUT" default:"1m"`
+	// CoolDownCheck is how often the waiting requests are offered the finders which cooled down without a release.
+	CoolDownCheck time.Duration `envconfig:"COOL_DOWN_CHECK" default:"1s"`
+}
+
+func GetSelectionConfig() *SelectionConfig {
+	cfg := new(SelectionConfig)
+	if err := envconfig.Process("POOL", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
+
+// Names of the rate limit sources.
+const (
+	LimitsWindow = "window"
+	LimitsHeader = "header"
+	LimitsBoth   = "both"
+)
+
+// Names of the request limits storages.
+const (
+	StorageFDB   = "fdb"
+	StorageRedis = "redis"
+)
+
+type LimitsConfig struct {
+	// Source of the rate limits: window counts the requests till the account is throttled, header reads the limits
+	// from the responses, both applies the stricter of them.
+	Source string `envconfig:"SOURCE" default:"both"`
+	// PaceFrom is the used share of the endpoint limit from which the requests are spread till its reset.
+	PaceFrom float64 `envconfig:"PACE_FROM" default:"0.5"`
+	// Storage keeps the counters and thresholds of the window limiters: fdb or redis.
+	Storage string `envconfig:"STORAGE" default:"fdb"`
+}
+
+// SelectLimitsRepo returns the request limits repository of the configured storage, T is the part of the repositories
+// the caller needs: RequestLimitsRepo for the pool or LimitsAdminRepo for the limits tool.
+func SelectLimitsRepo[T RequestLimitsRepo](config *LimitsConfig, fdb, redis T) (T, error) {
+	switch config.Storage {
+	case StorageFDB:
+		return fdb, nil
+	case StorageRedis:
+		return redis, nil
+	default:
+		var empty T
+		return empty, fmt.Errorf("%w: %s", ErrUnknownLimitsStorage, config.Storage)
+	}
+}
+
+func GetLimitsConfig() *LimitsConfig {
+	cfg := new(LimitsConfig)
+	if err := envconfig.Process("RATE_LIMITS", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
+
+// Names of the delay controllers.
+const (
+	ControllerV2   = "v2"
+	ControllerAIMD = "aimd"
+)
+
+type DelayConfig struct {
+	// Controller of the request delay: v2 jumps to the delay recommended by the limiters, aimd raises the request rate
+	// additively and cuts it multiplicatively on the throttle.
+	Controller string `envconfig:"CONTROLLER" default:"v2"`
+	// AIMDIncrease is the rate in requests per minute added every AIMDInterval without the pressure of the limiters.
+	AIMDIncrease float64       `envconfig:"AIMD_INCREASE" default:"1"`
+	AIMDDecrease float64       `envconfig:"AIMD_DECREASE" default:"0.5"`
+	AIMDInterval time.Duration `envconfig:"AIMD_INTERVAL" default:"1m"`
+	AIMDMinRate  float64       `envconfig:"AIMD_MIN_RATE" default:"0.5"`
+	AIMDMaxRate  float64       `envconfig:"AIMD_MAX_RATE" default:"30"`
+}
+
+func GetDelayConfig() *DelayConfig {
+	cfg := new(DelayConfig)
+	if err := envconfig.Process("DELAY", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
+
+// WarmUpConfig is the ramp of the new accounts: the request rate is capped at StartRate first and grows
+// to the rate of the established accounts over Duration.
+type WarmUpConfig struct {
+	// Duration is zero to start the new accounts at the full rate.
+	Duration time.Duration `envconfig:"DURATION" default:"168h"`
+	// StartRate is in requests per minute.
+	StartRate float64 `envconfig:"START_RATE" default:"0.5"`
+	// ThrottlePenalty moves the ramp back on the throttle, the throttles of the same Interval are counted once.
+	ThrottlePenalty time.Duration `envconfig:"THROTTLE_PENALTY" default:"12h"`
+	// Interval is how often the rate cap and the limiter thresholds follow the ramp.
+	Interval time.Duration `envconfig:"INTERVAL" default:"10m"`
+}
+
+func GetWarmUpConfig() *WarmUpConfig {
+	cfg := new(WarmUpConfig)
+	if err := envconfig.Process("WARM_UP", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
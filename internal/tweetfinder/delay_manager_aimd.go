@@ -0,0 +1,190 @@
+package tweetfinder
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/clock"
+)
+
+const rateKey = "rate"
+
+// managerAIMD controls the request rate instead of the delay: the rate grows by the fixed step while the limiters
+// are calm and is cut by the factor on the throttle, so it probes the limit smoothly instead of oscillating.
+type managerAIMD struct {
+	config *DelayConfig
+	setter func(seconds int64)
+
+	mu sync.Mutex
+	// rate is in requests per minute, the delay is its rounded up inverse
+	rate  float64
+	delay int64
+
+	windowLimiters []WindowLimiter
+	headerLimiter  HeaderLimiter
+	throttled      chan struct{}
+
+	clock     clock.Clock
+	startTime time.Time
+
+	login   string
+	metrics *DelayMetrics
+
+	log log.Logger
+}
+
+func (m *managerAIMD) TooManyRequests(ctx context.Context) {
+	m.log.WithField(tempKey, m.CurrentTemp(ctx)).WithField(delayKey, m.CurrentDelay()).Error("too many requests")
+	m.metrics.Throttles.WithLabelValues(m.login, ControllerAIMD).Inc()
+
+	settleThreshold(ctx, m.windowLimiters, m.startTime, m.CurrentDelay(), m.log)
+
+	m.AfterRequest()
+
+	select {
+	case m.throttled <- struct{}{}:
+	default:
+		// the decrease is pending already, the throttles of the same burst cut the rate once
+	}
+}
+
+func (m *managerAIMD) AfterRequest() {
+	for _, limiter := range m.windowLimiters {
+		limiter.Inc()
+	}
+}
+
+func (m *managerAIMD) CurrentDelay() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.delay
+}
+
+func (m *managerAIMD) CurrentTemp(ctx context.Context) float64 {
+	return limitersTemp(ctx, m.windowLimiters, m.headerLimiter)
+}
+
+func (m *managerAIMD) Start(ctx context.Context) error {
+	for _, limiter := range m.windowLimiters {
+		if err := limiter.Start(ctx, m.CurrentDelay()); err != nil {
+			return err
+		}
+	}
+
+	go m.loop(ctx)
+
+	return nil
+}
+
+func (m *managerAIMD) loop(ctx context.Context) {
+	ticker := m.clock.NewTicker(m.config.AIMDInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.throttled:
+			m.decrease()
+		case <-ticker.C():
+			if err := m.increase(ctx); err != nil {
+				m.log.WithError(err).Error(recalculateError)
+			}
+		}
+	}
+}
+
+func (m *managerAIMD) decrease() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.apply(m.rate * m.config.AIMDDecrease)
+	m.log.WithField(rateKey, m.rate).Debug("rate decreased")
+}
+
+// increase adds the step to the rate, the limiters under pressure hold the rate at their recommended one instead.
+func (m *managerAIMD) increase(ctx context.Context) error {
+	ceiling, err := m.ceiling(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ceiling > 0 {
+		m.apply(min(m.rate, ceiling))
+		m.log.WithField(rateKey, m.rate).Trace("rate held by limiters")
+
+		return nil
+	}
+
+	m.apply(m.rate + m.config.AIMDIncrease)
+	m.log.WithField(rateKey, m.rate).Trace("rate increased")
+
+	return nil
+}
+
+// ceiling is the lowest rate recommended by the limiters, zero when none of them is over its threshold.
+func (m *managerAIMD) ceiling(ctx context.Context) (float64, error) {
+	var delay time.Duration
+
+	for _, limiter := range m.windowLimiters {
+		recommendedDelay, err := limiter.RecommendedDelay(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		delay = max(delay, time.Duration(recommendedDelay)*time.Second)
+	}
+
+	if m.headerLimiter != nil {
+		delay = max(delay, m.headerLimiter.RecommendedDelay())
+	}
+
+	if delay == 0 {
+		return 0, nil
+	}
+
+	return perMinute / delay.Seconds(), nil
+}
+
+// apply clamps the rate and sets the delay, it must be called under the lock.
+func (m *managerAIMD) apply(rate float64) {
+	m.rate = min(max(rate, m.config.AIMDMinRate), m.config.AIMDMaxRate)
+	m.delay = max(int64(math.Ceil(perMinute/m.rate)), 1)
+
+	m.setter(m.delay)
+	m.metrics.TargetRate.WithLabelValues(m.login, ControllerAIMD).Set(m.rate)
+}
+
+func NewDelayManagerAIMD(
+	clock clock.Clock,
+	config *DelayConfig,
+	setter func(seconds int64),
+	windowLimiters []WindowLimiter,
+	headerLimiter HeaderLimiter,
+	minimalDelay int64,
+	login string,
+	metrics *DelayMetrics,
+	log log.Logger,
+) Manager {
+	return &managerAIMD{
+		config:         config,
+		setter:         setter,
+		rate:           perMinute / float64(max(minimalDelay, 1)),
+		delay:          minimalDelay,
+		windowLimiters: windowLimiters,
+		headerLimiter:  headerLimiter,
+		throttled:      make(chan struct{}, 1),
+		clock:          clock,
+		startTime:      clock.Now(),
+		login:          login,
+		metrics:        metrics,
+		log:            log,
+	}
+}
@@ -0,0 +1,109 @@
+package tweetfinder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/clock"
+)
+
+type stubHeaderLimiter struct {
+	delay time.Duration
+}
+
+func (l *stubHeaderLimiter) RecommendedDelay() time.Duration { return l.delay }
+
+func (l *stubHeaderLimiter) Temp() float64 { return 0 }
+
+func TestManagerAIMD(t *testing.T) {
+	ctx := context.Background()
+	config := &DelayConfig{AIMDIncrease: 2, AIMDDecrease: 0.5, AIMDInterval: time.Hour, AIMDMinRate: 1, AIMDMaxRate: 12}
+	metrics := NewDelayMetrics("", "")
+	headers := new(stubHeaderLimiter)
+
+	var delay int64
+
+	m := NewDelayManagerAIMD(
+		clock.NewVirtual(time.Now()),
+		config,
+		func(seconds int64) { delay = seconds },
+		nil,
+		headers,
+		15,
+		"alice",
+		metrics,
+		log.NewLogger(logrus.New()),
+	).(*managerAIMD)
+
+	// 4 requests per minute grow by the step up to the max rate
+	require.NoError(t, m.increase(ctx))
+	require.Equal(t, int64(10), delay)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, m.increase(ctx))
+	}
+
+	require.Equal(t, int64(5), delay)
+	require.Equal(t, 12.0, testutil.ToFloat64(metrics.TargetRate.WithLabelValues("alice", ControllerAIMD)))
+
+	// the throttle halves the rate once per burst
+	m.TooManyRequests(ctx)
+	m.TooManyRequests(ctx)
+	<-m.throttled
+	m.decrease()
+	require.Equal(t, int64(10), delay)
+	require.Equal(t, 2.0, testutil.ToFloat64(metrics.Throttles.WithLabelValues("alice", ControllerAIMD)))
+
+	// the limiter under pressure holds the rate instead of the increase
+	headers.delay = time.Second * 20
+	require.NoError(t, m.increase(ctx))
+	require.Equal(t, int64(20), delay)
+
+	require.NoError(t, m.increase(ctx))
+	require.Equal(t, int64(20), delay)
+
+	// the rate never drops below the min
+	for i := 0; i < 10; i++ {
+		m.decrease()
+	}
+
+	require.Equal(t, int64(60), delay)
+	require.Equal(t, int64(60), m.CurrentDelay())
+}
+
+func TestNewPool_invalidAIMD(t *testing.T) {
+	valid := DelayConfig{Controller: ControllerAIMD, AIMDIncrease: 1, AIMDDecrease: 0.5, AIMDInterval: time.Minute, AIMDMinRate: 0.5, AIMDMaxRate: 30}
+	newPool := func(delays DelayConfig) error {
+		_, err := NewPool(&SelectionConfig{Strategy: LeastTemperature}, &LimitsConfig{Source: LimitsBoth}, &delays, &WarmUpConfig{},
+			nil, nil, nil, nil, nil, nil, nil, nil, nil, log.NewLogger(logrus.New()))
+
+		return err
+	}
+
+	require.NoError(t, newPool(valid))
+
+	invalid := map[string]func(config *DelayConfig){
+		"zero increase":     func(config *DelayConfig) { config.AIMDIncrease = 0 },
+		"zero decrease":     func(config *DelayConfig) { config.AIMDDecrease = 0 },
+		"full decrease":     func(config *DelayConfig) { config.AIMDDecrease = 1 },
+		"zero interval":     func(config *DelayConfig) { config.AIMDInterval = 0 },
+		"zero min rate":     func(config *DelayConfig) { config.AIMDMinRate = 0 },
+		"min over max rate": func(config *DelayConfig) { config.AIMDMinRate = 31 },
+	}
+	for name, mutate := range invalid {
+		t.Run(name, func(t *testing.T) {
+			config := valid
+			mutate(&config)
+			require.ErrorIs(t, newPool(config), ErrInvalidAIMD)
+		})
+	}
+
+	// the gains of the v2 controller are not used
+	require.NoError(t, newPool(DelayConfig{Controller: ControllerV2}))
+}
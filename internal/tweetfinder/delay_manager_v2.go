@@ -2,10 +2,13 @@ package tweetfinder
 
 import (
 	"context"
+	"math"
+	"sync/atomic"
 	"time"
 
 	"github.com/lueurxax/crypto-tweet-sense/internal/log"
 	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder/windowlimiter"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/clock"
 )
 
 const (
@@ -13,6 +16,7 @@ const (
 	tempKey  = "temp"
 
 	loopInterval     = time.Second * 10
+	perMinute        = 60
 	recalculateError = "error while recalculate"
 )
 
@@ -35,48 +39,36 @@ type WindowLimiter interface {
 	Start(ctx context.Context, delay int64) error
 }
 
+// HeaderLimiter paces the requests by the rate limits Twitter reports in the responses.
+type HeaderLimiter interface {
+	RecommendedDelay() time.Duration
+	Temp() float64
+}
+
 type managerV2 struct {
 	setter func(seconds int64)
-	delay  int64
+	// delay is owned by the loop, current publishes it to the finder
+	delay   int64
+	current atomic.Int64
 
 	windowLimiters   []WindowLimiter
+	headerLimiter    HeaderLimiter
 	forceRecalculate chan struct{}
 
+	clock     clock.Clock
 	startTime time.Time
 
+	login   string
+	metrics *DelayMetrics
+
 	log log.Logger
 }
 
 func (m *managerV2) TooManyRequests(ctx context.Context) {
-	m.log.WithField(tempKey, m.CurrentTemp(ctx)).WithField(delayKey, m.delay).Error("too many requests")
-
-	settled := false
-
-	level := 3.0
-
-	for !settled {
-		for _, limiter := range m.windowLimiters {
-			temp := limiter.Temp(ctx)
-			if temp < level && !settled {
-				if err := limiter.TrySetThreshold(ctx, m.startTime); err != nil {
-					m.log.WithError(err).Error("error while setting threshold")
-					return
-				}
-
-				m.log.
-					WithField("duration", limiter.Duration()).
-					WithField(delayKey, m.delay).
-					WithField(tempKey, temp).
-					WithField("level", level).
-					Debug("setting threshold")
+	m.log.WithField(tempKey, m.CurrentTemp(ctx)).WithField(delayKey, m.CurrentDelay()).Error("too many requests")
+	m.metrics.Throttles.WithLabelValues(m.login, ControllerV2).Inc()
 
-				settled = true
-
-				break
-			}
-		}
-		level++
-	}
+	settleThreshold(ctx, m.windowLimiters, m.startTime, m.CurrentDelay(), m.log)
 
 	m.AfterRequest()
 	m.forceRecalculate <- struct{}{}
@@ -90,21 +82,11 @@ func (m *managerV2) AfterRequest() {
 }
 
 func (m *managerV2) CurrentDelay() int64 {
-	return m.delay
+	return m.current.Load()
 }
 
 func (m *managerV2) CurrentTemp(ctx context.Context) float64 {
-	var temp float64
-
-	for _, limiter := range m.windowLimiters {
-		tr := limiter.Temp(ctx)
-
-		if tr > temp {
-			temp = tr
-		}
-	}
-
-	return temp
+	return limitersTemp(ctx, m.windowLimiters, m.headerLimiter)
 }
 
 func (m *managerV2) Start(ctx context.Context) error {
@@ -120,7 +102,8 @@ func (m *managerV2) Start(ctx context.Context) error {
 }
 
 func (m *managerV2) loop(ctx context.Context) {
-	ticker := time.NewTicker(loopInterval)
+	ticker := m.clock.NewTicker(loopInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
@@ -130,7 +113,7 @@ func (m *managerV2) loop(ctx context.Context) {
 			if err := m.recalculate(ctx, 2); err != nil {
 				m.log.WithError(err).Error(recalculateError)
 			}
-		case <-ticker.C:
+		case <-ticker.C():
 			if err := m.recalculate(ctx, 1); err != nil {
 				m.log.WithError(err).Error(recalculateError)
 			}
@@ -169,6 +152,17 @@ func (m *managerV2) recalculate(ctx context.Context, factor int) error {
 		}
 	}
 
+	if m.headerLimiter != nil {
+		if delay := int64(math.Ceil(m.headerLimiter.RecommendedDelay().Seconds())); delay > 0 {
+			shouldDecrease = false
+
+			if delay > m.delay {
+				m.delay = delay
+				m.log.WithField(delayKey, m.delay).Trace("delay increased by rate limit headers")
+			}
+		}
+	}
+
 	if shouldDecrease && m.delay > 1 {
 		if m.delay < 6 {
 			m.delay--
@@ -180,22 +174,88 @@ func (m *managerV2) recalculate(ctx context.Context, factor int) error {
 	}
 
 	m.setter(m.delay)
+	m.current.Store(m.delay)
+	m.metrics.TargetRate.WithLabelValues(m.login, ControllerV2).Set(perMinute / float64(max(m.delay, 1)))
 
 	return nil
 }
 
+// settleThreshold sets the threshold of the shortest window limiter which is not on fire yet,
+// the throttled account has reached the limit of that window.
+func settleThreshold(ctx context.Context, limiters []WindowLimiter, startTime time.Time, delay int64, logger log.Logger) {
+	settled := false
+
+	level := 3.0
+
+	// the header limiter learns the reset from the throttled response itself
+	for !settled && len(limiters) > 0 {
+		for _, limiter := range limiters {
+			temp := limiter.Temp(ctx)
+			if temp < level && !settled {
+				if err := limiter.TrySetThreshold(ctx, startTime); err != nil {
+					logger.WithError(err).Error("error while setting threshold")
+					return
+				}
+
+				logger.
+					WithField("duration", limiter.Duration()).
+					WithField(delayKey, delay).
+					WithField(tempKey, temp).
+					WithField("level", level).
+					Debug("setting threshold")
+
+				settled = true
+
+				break
+			}
+		}
+		level++
+	}
+}
+
+// limitersTemp is the temp of the hottest limiter.
+func limitersTemp(ctx context.Context, windowLimiters []WindowLimiter, headerLimiter HeaderLimiter) float64 {
+	var temp float64
+
+	for _, limiter := range windowLimiters {
+		tr := limiter.Temp(ctx)
+
+		if tr > temp {
+			temp = tr
+		}
+	}
+
+	if headerLimiter != nil {
+		temp = max(temp, headerLimiter.Temp())
+	}
+
+	return temp
+}
+
 func NewDelayManagerV2(
+	clock clock.Clock,
 	setter func(seconds int64),
 	windowLimiters []WindowLimiter,
+	headerLimiter HeaderLimiter,
 	minimalDelay int64,
+	login string,
+	metrics *DelayMetrics,
 	log log.Logger,
 ) Manager {
-	return &managerV2{
+	m := &managerV2{
 		forceRecalculate: make(chan struct{}, 1000),
 		setter:           setter,
 		delay:            minimalDelay,
 		windowLimiters:   windowLimiters,
-		startTime:        time.Now(),
+		headerLimiter:    headerLimiter,
+		clock:            clock,
+		startTime:        clock.Now(),
+		login:            login,
+		metrics:          metrics,
 		log:              log,
 	}
+
+	m.current.Store(minimalDelay)
+
+	return m
 }
@@ -0,0 +1,37 @@
+package tweetfinder
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// DelayMetrics are labeled by the controller, so the controllers are compared side by side.
+type DelayMetrics struct {
+	TargetRate *prometheus.GaugeVec   // login, controller
+	Throttles  *prometheus.CounterVec // login, controller
+	WarmUpRate *prometheus.GaugeVec   // login
+}
+
+func NewDelayMetrics(namespace, subsystem string) *DelayMetrics {
+	return &DelayMetrics{
+		TargetRate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "target_rate_per_minute",
+			Help:      "Request rate the delay controller aims at",
+		}, []string{"login", "controller"}),
+		Throttles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "throttles_total",
+			Help:      "Requests rejected by Twitter with too many requests",
+		}, []string{"login", "controller"}),
+		WarmUpRate: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "warm_up_rate_per_minute",
+			Help:      "Request rate cap of the warming up account",
+		}, []string{"login"}),
+	}
+}
+
+func (m *DelayMetrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.TargetRate, m.Throttles, m.WarmUpRate}
+}
@@ -3,7 +3,14 @@ package tweetfinder
 import "errors"
 
 var (
-	ErrNoTops              = errors.New("no top tweets")
-	ErrNotFound            = errors.New("not found")
-	ErrTimeoutSelectFinder = errors.New("timeout select finder")
+	ErrNoTops               = errors.New("no top tweets")
+	ErrNotFound             = errors.New("not found")
+	ErrTimeoutSelectFinder  = errors.New("timeout select finder")
+	ErrUnknownSource        = errors.New("unknown tweet source")
+	ErrUnknownStrategy      = errors.New("unknown finder selection strategy")
+	ErrUnknownLimitsSource  = errors.New("unknown rate limits source")
+	ErrUnknownController    = errors.New("unknown delay controller")
+	ErrUnknownLimitsStorage = errors.New("unknown request limits storage")
+	ErrInvalidWarmUp        = errors.New("invalid account warm-up")
+	ErrInvalidAIMD          = errors.New("invalid aimd delay controller")
 )
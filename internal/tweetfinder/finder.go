@@ -3,29 +3,25 @@ package tweetfinder
 import (
 	"context"
 	"errors"
-	"fmt"
-	"strings"
-	"time"
-
-	twitterscraper "github.com/lueurxax/twitter-scraper"
 
 	"github.com/lueurxax/crypto-tweet-sense/internal/common"
 	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	"github.com/lueurxax/crypto-tweet-sense/internal/source"
 )
 
 const (
-	limit    = 50
-	format   = "2006-01-02"
-	notFound = "not found"
+	limit = 50
 )
 
 type Finder interface {
 	IsHot() bool
-	FindNext(ctx context.Context, start, end *time.Time, search, cursor string) ([]common.TweetSnapshot, string, error)
+	FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error)
 	Find(ctx context.Context, id string) (*common.TweetSnapshot, error)
 	CurrentDelay() int64
 	CurrentTemp(ctx context.Context) float64
 	Init(ctx context.Context) error
+	// Capacity returns how many requests the finder is able to serve in parallel right now.
+	Capacity() int
 }
 
 type delayManager interface {
@@ -36,7 +32,7 @@ type delayManager interface {
 }
 
 type finder struct {
-	scraper *twitterscraper.Scraper
+	source source.Source
 	delayManager
 
 	log log.Logger
@@ -46,6 +42,10 @@ func (f *finder) IsHot() bool {
 	panic("implement me")
 }
 
+func (f *finder) Capacity() int {
+	return 1
+}
+
 func (f *finder) CurrentTemp(ctx context.Context) float64 {
 	return f.delayManager.CurrentTemp(ctx)
 }
@@ -55,13 +55,13 @@ func (f *finder) Init(context.Context) error {
 }
 
 func (f *finder) Find(ctx context.Context, id string) (*common.TweetSnapshot, error) {
-	tweet, err := f.scraper.GetTweet(ctx, id)
+	tweet, err := f.source.GetTweet(ctx, id)
 	if err != nil {
-		if errors.Is(err, twitterscraper.ErrRateLimitExceeded{}) {
+		if errors.Is(err, source.ErrRateLimited) {
 			f.delayManager.TooManyRequests(ctx)
 		}
 
-		if strings.Contains(err.Error(), notFound) {
+		if errors.Is(err, source.ErrNotFound) {
 			return nil, ErrNotFound
 		}
 
@@ -72,105 +72,27 @@ func (f *finder) Find(ctx context.Context, id string) (*common.TweetSnapshot, er
 
 	f.delayManager.AfterRequest()
 
-	return &common.TweetSnapshot{
-		Tweet: &common.Tweet{
-			ID:           tweet.ID,
-			Likes:        tweet.Likes,
-			Name:         tweet.Name,
-			PermanentURL: tweet.PermanentURL,
-			Replies:      tweet.Replies,
-			Retweets:     tweet.Retweets,
-			Text:         tweet.Text,
-			TimeParsed:   tweet.TimeParsed,
-			Timestamp:    tweet.Timestamp,
-			UserID:       tweet.UserID,
-			Username:     tweet.Username,
-			Views:        tweet.Views,
-			Photos:       scrapperPhotosToCommon(tweet.Photos),
-			Videos:       scrapperVideosToCommon(tweet.Videos),
-		},
-		CheckedAt: time.Now(),
-	}, nil
+	return tweet, nil
 }
 
-func (f *finder) FindNext(ctx context.Context, start, end *time.Time, search, cursor string) ([]common.TweetSnapshot, string, error) {
-	query := fmt.Sprintf("%s -filter:retweets", search)
-	if start != nil {
-		query = fmt.Sprintf("%s since:%s", search, start.Format(format))
-	}
-
-	if end != nil {
-		query = fmt.Sprintf("%s until:%s", query, end.Format(format))
-	}
-
-	tweets, nextCursor, err := f.scraper.FetchSearchTweets(ctx, query, limit, cursor)
+func (f *finder) FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
+	tweets, nextCursor, err := f.source.Search(ctx, query, limit, cursor)
 	if err != nil {
-		if errors.Is(err, twitterscraper.ErrRateLimitExceeded{}) {
+		if errors.Is(err, source.ErrRateLimited) {
 			f.delayManager.TooManyRequests(ctx)
 		}
 
 		return nil, "", err
 	}
 
-	response := make([]common.TweetSnapshot, 0)
-
-	for _, tweet := range tweets {
-		syncTime := time.Now()
-
-		response = append(response, common.TweetSnapshot{
-			Tweet: &common.Tweet{
-				ID:           tweet.ID,
-				Likes:        tweet.Likes,
-				Name:         tweet.Name,
-				PermanentURL: tweet.PermanentURL,
-				Replies:      tweet.Replies,
-				Retweets:     tweet.Retweets,
-				Text:         tweet.Text,
-				TimeParsed:   tweet.TimeParsed,
-				Timestamp:    tweet.Timestamp,
-				UserID:       tweet.UserID,
-				Username:     tweet.Username,
-				Views:        tweet.Views,
-				Photos:       scrapperPhotosToCommon(tweet.Photos),
-				Videos:       scrapperVideosToCommon(tweet.Videos),
-			},
-			CheckedAt: syncTime,
-		})
-	}
-
 	f.delayManager.AfterRequest()
 
-	return response, nextCursor, nil
-}
-
-func scrapperPhotosToCommon(photos []twitterscraper.Photo) []common.Photo {
-	res := make([]common.Photo, len(photos))
-	for i, photo := range photos {
-		res[i] = common.Photo{
-			ID:  photo.ID,
-			URL: photo.URL,
-		}
-	}
-
-	return res
-}
-
-func scrapperVideosToCommon(videos []twitterscraper.Video) []common.Video {
-	res := make([]common.Video, len(videos))
-	for i, video := range videos {
-		res[i] = common.Video{
-			ID:      video.ID,
-			Preview: video.Preview,
-			URL:     video.URL,
-		}
-	}
-
-	return res
+	return tweets, nextCursor, nil
 }
 
-func NewFinder(scraper *twitterscraper.Scraper, delayManager delayManager, logger log.Logger) Finder {
+func NewFinder(src source.Source, delayManager delayManager, logger log.Logger) Finder {
 	return &finder{
-		scraper:      scraper,
+		source:       src,
 		delayManager: delayManager,
 		log:          logger,
 	}
@@ -0,0 +1,184 @@
+package headerlimiter
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+)
+
+const (
+	limitHeader     = "X-Rate-Limit-Limit"
+	remainingHeader = "X-Rate-Limit-Remaining"
+	resetHeader     = "X-Rate-Limit-Reset"
+
+	endpointKey = "endpoint"
+
+	// exhaustedTemp is the temp of the endpoint without the remaining requests, as the window limiter caps its own
+	exhaustedTemp = 2
+)
+
+// Limit is the rate limit of the endpoint as Twitter reported it in the last response.
+type Limit struct {
+	Limit     uint64
+	Remaining uint64
+	Reset     time.Time
+}
+
+// Limiter learns the rate limits of the endpoints from the response headers, so the account backs off
+// before it is throttled instead of counting the requests till the limit is hit.
+type Limiter interface {
+	// Transport wraps the round tripper of the scraper to capture the headers of its responses.
+	Transport(next http.RoundTripper) http.RoundTripper
+	// RecommendedDelay spreads the remaining requests of the most used endpoint over the time left till its reset,
+	// it is zero while every endpoint has used less than the pace share of its limit.
+	RecommendedDelay() time.Duration
+	// Temp is the used share of the most used endpoint.
+	Temp() float64
+	// Limits returns the current limits by the endpoint.
+	Limits() map[string]Limit
+}
+
+type limiter struct {
+	paceFrom float64
+
+	mu     sync.RWMutex
+	limits map[string]Limit
+
+	log log.Logger
+}
+
+func (l *limiter) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &transport{next: next, limiter: l}
+}
+
+func (l *limiter) RecommendedDelay() time.Duration {
+	var delay time.Duration
+
+	now := time.Now()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, limit := range l.limits {
+		if !limit.Reset.After(now) || used(limit) < l.paceFrom {
+			continue
+		}
+
+		left := limit.Reset.Sub(now)
+		if limit.Remaining > 0 {
+			left /= time.Duration(limit.Remaining)
+		}
+
+		delay = max(delay, left)
+	}
+
+	return delay
+}
+
+func (l *limiter) Temp() float64 {
+	var temp float64
+
+	now := time.Now()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, limit := range l.limits {
+		if !limit.Reset.After(now) {
+			continue
+		}
+
+		if limit.Remaining == 0 {
+			return exhaustedTemp
+		}
+
+		temp = max(temp, used(limit))
+	}
+
+	return temp
+}
+
+func (l *limiter) Limits() map[string]Limit {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	res := make(map[string]Limit, len(l.limits))
+	for endpoint, limit := range l.limits {
+		res[endpoint] = limit
+	}
+
+	return res
+}
+
+func (l *limiter) observe(endpoint string, header http.Header) {
+	limit, ok := parse(header)
+	if !ok {
+		return
+	}
+
+	l.mu.Lock()
+	previous, known := l.limits[endpoint]
+	l.limits[endpoint] = limit
+	l.mu.Unlock()
+
+	if !known || previous.Limit != limit.Limit {
+		l.log.WithField(endpointKey, endpoint).WithField("limit", limit.Limit).Debug("rate limit learned")
+	}
+}
+
+type transport struct {
+	next    http.RoundTripper
+	limiter *limiter
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// the graphql endpoints are /i/api/graphql/<query id>/<operation>, the operation names the limit
+	t.limiter.observe(path.Base(req.URL.Path), resp.Header)
+
+	return resp, nil
+}
+
+func parse(header http.Header) (Limit, bool) {
+	limit, err := strconv.ParseUint(header.Get(limitHeader), 10, 64)
+	if err != nil || limit == 0 {
+		return Limit{}, false
+	}
+
+	remaining, err := strconv.ParseUint(header.Get(remainingHeader), 10, 64)
+	if err != nil {
+		return Limit{}, false
+	}
+
+	reset, err := strconv.ParseInt(header.Get(resetHeader), 10, 64)
+	if err != nil {
+		return Limit{}, false
+	}
+
+	return Limit{Limit: limit, Remaining: min(remaining, limit), Reset: time.Unix(reset, 0)}, true
+}
+
+func used(limit Limit) float64 {
+	return float64(limit.Limit-limit.Remaining) / float64(limit.Limit)
+}
+
+// NewLimiter creates the limiter which paces the requests once the endpoint has used the paceFrom share of its limit.
+func NewLimiter(paceFrom float64, logger log.Logger) Limiter {
+	return &limiter{
+		paceFrom: paceFrom,
+		limits:   make(map[string]Limit),
+		log:      logger,
+	}
+}
@@ -0,0 +1,71 @@
+package headerlimiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+)
+
+func TestLimiter(t *testing.T) {
+	reset := time.Now().Add(time.Minute * 10).Truncate(time.Second)
+	remaining := 30
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/1.1/guest/activate.json" {
+			return
+		}
+
+		w.Header().Set(limitHeader, "50")
+		w.Header().Set(remainingHeader, strconv.Itoa(remaining))
+		w.Header().Set(resetHeader, strconv.FormatInt(reset.Unix(), 10))
+
+		if remaining == 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer server.Close()
+
+	l := NewLimiter(0.5, log.NewLogger(logrus.New()))
+	client := &http.Client{Transport: l.Transport(nil)}
+
+	get := func(path string) {
+		resp, err := client.Get(server.URL + path)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	// the responses without the headers are skipped
+	get("/1.1/guest/activate.json")
+	require.Empty(t, l.Limits())
+
+	get("/i/api/graphql/nK1dw4oV3k4w5TdtcAdSww/SearchTimeline")
+	require.Equal(t, map[string]Limit{"SearchTimeline": {Limit: 50, Remaining: 30, Reset: reset}}, l.Limits())
+	require.InDelta(t, 0.4, l.Temp(), 0.001)
+	require.Zero(t, l.RecommendedDelay())
+
+	remaining = 10
+	get("/i/api/graphql/nK1dw4oV3k4w5TdtcAdSww/SearchTimeline")
+	require.InDelta(t, 0.8, l.Temp(), 0.001)
+	require.InDelta(t, time.Minute, l.RecommendedDelay(), float64(time.Second))
+
+	remaining = 0
+	get("/i/api/graphql/nK1dw4oV3k4w5TdtcAdSww/SearchTimeline")
+	require.Equal(t, float64(exhaustedTemp), l.Temp())
+	require.InDelta(t, time.Minute*10, l.RecommendedDelay(), float64(time.Second))
+}
+
+func TestLimiter_reset(t *testing.T) {
+	l := NewLimiter(0.5, log.NewLogger(logrus.New())).(*limiter)
+	l.limits["TweetDetail"] = Limit{Limit: 150, Remaining: 0, Reset: time.Now().Add(-time.Second)}
+
+	// the limit after its reset tells nothing
+	require.Zero(t, l.Temp())
+	require.Zero(t, l.RecommendedDelay())
+}
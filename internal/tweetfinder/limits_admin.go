@@ -0,0 +1,158 @@
+package tweetfinder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	"github.com/lueurxax/crypto-tweet-sense/internal/repo/model"
+	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder/windowlimiter"
+)
+
+var ErrUnknownWindow = errors.New("unknown limiter window")
+
+// LimitsAdmin inspects and corrects the window limiters of the accounts, the running limiters pick the changes up
+// when they reload the limits on the next flush.
+type LimitsAdmin interface {
+	// List returns the limits of every account in every window, the missing limits are listed too.
+	List(ctx context.Context) ([]LimitState, error)
+	Debug(ctx context.Context, login string, window time.Duration) (model.RequestLimitsV2Debug, error)
+	// Reset drops the counted requests, the threshold is kept.
+	Reset(ctx context.Context, login string, window time.Duration) error
+	SetThreshold(ctx context.Context, login string, window time.Duration, threshold uint64) error
+	// RaiseThreshold keeps the threshold when it is higher already.
+	RaiseThreshold(ctx context.Context, login string, window time.Duration, threshold uint64) error
+	// History returns the stored request times of the window, the expired ones not cleaned yet included.
+	History(ctx context.Context, login string, window time.Duration) ([]time.Time, error)
+}
+
+type LimitState struct {
+	Login  string
+	Window time.Duration
+	common.RequestLimitData
+	// Found is false when the limiter of the window was never started for the account.
+	Found bool
+}
+
+// Temp is the temperature of the limiter without the fire, which lives in the running limiter only.
+func (s LimitState) Temp() float64 {
+	return windowlimiter.Temperature(s.RequestLimitData)
+}
+
+type LimitsAdminRepo interface {
+	RequestLimitsRepo
+	ResetCounters(ctx context.Context, id string, window time.Duration) error
+	GetRequestLimitDebug(ctx context.Context, id string, window time.Duration) (model.RequestLimitsV2Debug, error)
+}
+
+type accountsRepo interface {
+	GetAccounts(ctx context.Context) ([]common.TwitterAccount, error)
+}
+
+type limitsAdmin struct {
+	repo     LimitsAdminRepo
+	accounts accountsRepo
+	windows  []time.Duration
+
+	log log.Logger
+}
+
+func (a *limitsAdmin) List(ctx context.Context) ([]LimitState, error) {
+	accounts, err := a.accounts.GetAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Login < accounts[j].Login })
+
+	res := make([]LimitState, 0, len(accounts)*len(a.windows))
+
+	for _, account := range accounts {
+		for _, window := range a.windows {
+			limit, err := a.repo.GetRequestLimit(ctx, account.Login, window)
+			if err != nil && !errors.Is(err, fdb.ErrRequestLimitsNotFound) {
+				return nil, err
+			}
+
+			res = append(res, LimitState{Login: account.Login, Window: window, RequestLimitData: limit, Found: err == nil})
+		}
+	}
+
+	return res, nil
+}
+
+func (a *limitsAdmin) Debug(ctx context.Context, login string, window time.Duration) (model.RequestLimitsV2Debug, error) {
+	if err := a.checkWindow(window); err != nil {
+		return model.RequestLimitsV2Debug{}, err
+	}
+
+	return a.repo.GetRequestLimitDebug(ctx, login, window)
+}
+
+func (a *limitsAdmin) Reset(ctx context.Context, login string, window time.Duration) error {
+	if err := a.checkWindow(window); err != nil {
+		return err
+	}
+
+	a.log.WithField(finderLogin, login).WithField("window", window).Info("reset counters")
+
+	return a.repo.ResetCounters(ctx, login, window)
+}
+
+func (a *limitsAdmin) SetThreshold(ctx context.Context, login string, window time.Duration, threshold uint64) error {
+	if err := a.checkWindow(window); err != nil {
+		return err
+	}
+
+	a.log.WithField(finderLogin, login).WithField("window", window).WithField("threshold", threshold).Info("set threshold")
+
+	return a.repo.SetThresholdTo(ctx, login, window, threshold)
+}
+
+func (a *limitsAdmin) RaiseThreshold(ctx context.Context, login string, window time.Duration, threshold uint64) error {
+	if err := a.checkWindow(window); err != nil {
+		return err
+	}
+
+	a.log.WithField(finderLogin, login).WithField("window", window).WithField("threshold", threshold).Info("raise threshold")
+
+	return a.repo.IncreaseThresholdTo(ctx, login, window, threshold)
+}
+
+func (a *limitsAdmin) History(ctx context.Context, login string, window time.Duration) ([]time.Time, error) {
+	limit, err := a.Debug(ctx, login, window)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make([]time.Time, 0, limit.RequestsCount)
+	for i := range limit.Requests {
+		times = append(times, limit.Requests[i].Times()...)
+	}
+
+	return times, nil
+}
+
+// checkWindow rejects the windows the pool has no limiters for, the limits of them would be never read.
+func (a *limitsAdmin) checkWindow(window time.Duration) error {
+	if !slices.Contains(a.windows, window) {
+		return fmt.Errorf("%w: %s", ErrUnknownWindow, window)
+	}
+
+	return nil
+}
+
+func NewLimitsAdmin(repo LimitsAdminRepo, accounts accountsRepo, windows []time.Duration, logger log.Logger) LimitsAdmin {
+	return &limitsAdmin{
+		repo:     repo,
+		accounts: accounts,
+		windows:  windows,
+		log:      logger,
+	}
+}
@@ -0,0 +1,67 @@
+package tweetfinder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+type memoryAccounts []common.TwitterAccount
+
+func (a memoryAccounts) GetAccounts(context.Context) ([]common.TwitterAccount, error) {
+	return a, nil
+}
+
+func TestLimitsAdmin(t *testing.T) {
+	ctx := context.Background()
+	repo := fdb.NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	accounts := memoryAccounts{{Login: "bob"}, {Login: "alice"}}
+	windows := []time.Duration{time.Minute * 10, time.Hour}
+	admin := NewLimitsAdmin(repo, accounts, windows, log.NewLogger(logrus.New()))
+
+	now := time.Now().Truncate(time.Second)
+	times := []time.Time{now.Add(-time.Minute * 20), now.Add(-time.Minute), now}
+
+	require.NoError(t, repo.Create(ctx, "alice", time.Hour, 10))
+	require.NoError(t, repo.AddCounters(ctx, "alice", time.Hour, times))
+
+	limits, err := admin.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []LimitState{
+		{Login: "alice", Window: time.Minute * 10},
+		{Login: "alice", Window: time.Hour, RequestLimitData: common.RequestLimitData{RequestsCount: 3, Threshold: 10}, Found: true},
+		{Login: "bob", Window: time.Minute * 10},
+		{Login: "bob", Window: time.Hour},
+	}, limits)
+	require.InDelta(t, 0.31, limits[1].Temp(), 0.001)
+
+	history, err := admin.History(ctx, "alice", time.Hour)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.True(t, history[0].Equal(times[0]))
+
+	require.NoError(t, admin.SetThreshold(ctx, "alice", time.Hour, 5))
+	require.NoError(t, admin.RaiseThreshold(ctx, "alice", time.Hour, 3))
+	require.NoError(t, admin.Reset(ctx, "alice", time.Hour))
+
+	limit, err := repo.GetRequestLimit(ctx, "alice", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, common.RequestLimitData{Threshold: 5}, limit)
+
+	require.NoError(t, admin.RaiseThreshold(ctx, "alice", time.Hour, 8))
+
+	limit, err = repo.GetRequestLimit(ctx, "alice", time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), limit.Threshold)
+
+	require.ErrorIs(t, admin.Reset(ctx, "bob", time.Hour), fdb.ErrRequestLimitsNotFound)
+	require.ErrorIs(t, admin.Reset(ctx, "alice", time.Hour*2), ErrUnknownWindow)
+}
@@ -30,12 +30,12 @@ func (m *metricMiddleware) Init(context.Context) error {
 	return nil
 }
 
-func (m *metricMiddleware) FindNext(ctx context.Context, start, end *time.Time, search, cursor string) ([]common.TweetSnapshot, string, error) {
+func (m *metricMiddleware) FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
 	st := time.Now()
 
-	data, nextCursor, err := m.next.FindNext(ctx, start, end, search, cursor)
+	data, nextCursor, err := m.next.FindNext(ctx, query, cursor)
 
-	m.findNextRequestsHistogramSeconds.WithLabelValues(m.login, search, strconv.FormatBool(err != nil)).Observe(time.Since(st).Seconds())
+	m.findNextRequestsHistogramSeconds.WithLabelValues(m.login, query.Name, strconv.FormatBool(err != nil)).Observe(time.Since(st).Seconds())
 
 	return data, nextCursor, err
 }
@@ -50,6 +50,10 @@ func (m *metricMiddleware) Find(ctx context.Context, id string) (*common.TweetSn
 	return data, err
 }
 
+func (m *metricMiddleware) Capacity() int {
+	return m.next.Capacity()
+}
+
 func (m *metricMiddleware) CurrentDelay() int64 {
 	return m.next.CurrentDelay()
 }
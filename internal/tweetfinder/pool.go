@@ -1,7 +1,10 @@
 package tweetfinder
 
 import (
+	"container/list"
 	"context"
+	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -9,58 +12,105 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/lease"
 	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	"github.com/lueurxax/crypto-tweet-sense/internal/source"
+	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder/headerlimiter"
+	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder/proxymanager"
 	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder/windowlimiter"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/clock"
 )
 
 const (
-	startDelay  = 15
-	finderLogin = "finder_login"
-	pkgKey      = "pkg"
+	startDelay    = 15
+	maxFinderTemp = 4
+	finderLogin   = "finder_login"
+	pkgKey        = "pkg"
+
+	waitAcquired  = "acquired"
+	waitTimeout   = "timeout"
+	waitCancelled = "cancelled"
 
 	defaultUserAgent = "TwitterAndroid/99"
 )
 
+// LimiterIntervals are the windows of the request limiters of every account, from the shortest.
+var LimiterIntervals = []time.Duration{
+	time.Minute * 10,
+	time.Hour,
+	time.Hour * 24,
+	time.Hour * 24 * 30,
+}
+
 type accountManager interface {
 	AuthScrapper(ctx context.Context, account common.TwitterAccount, scraper *twitterscraper.Scraper) error
 	SearchUnAuthAccounts(ctx context.Context) ([]common.TwitterAccount, error)
+	SearchDisabledAccounts(ctx context.Context) ([]string, error)
+	Report(ctx context.Context, login string, err error) common.AccountStatus
+	Forget(login string)
+	AddWarmUpThrottle(ctx context.Context, login string) (int, error)
 }
 
-type repo interface {
-	AddCounter(ctx context.Context, id string, window time.Duration, counterTime time.Time) error
+// RequestLimitsRepo stores the counters and thresholds of the window limiters.
+type RequestLimitsRepo interface {
+	AddCounters(ctx context.Context, id string, window time.Duration, times []time.Time) error
 	CleanCounters(ctx context.Context, id string, window time.Duration) error
 	SetThreshold(ctx context.Context, id string, window time.Duration) error
 	GetRequestLimit(ctx context.Context, id string, window time.Duration) (common.RequestLimitData, error)
 	CheckIfExist(ctx context.Context, id string, window time.Duration) (bool, error)
 	Create(ctx context.Context, id string, window time.Duration, threshold uint64) error
 	IncreaseThresholdTo(ctx context.Context, id string, duration time.Duration, threshold uint64) error
+	SetThresholdTo(ctx context.Context, id string, window time.Duration, threshold uint64) error
+}
+
+// waiter is the request waiting for the finder, the index of the finder is handed to it over the ready channel.
+type waiter struct {
+	request string
+	ready   chan int
 }
 
 type pool struct {
-	config        ConfigProxies
-	finders       []Finder
-	releaseSignal chan struct{}
+	finders []Finder
+	config  *SelectionConfig
+	limits  *LimitsConfig
+	delays  *DelayConfig
+	warmUp  *WarmUpConfig
+	clock   clock.Clock
+
+	// logins, evicted and cancels are indexed as finders, the evicted slot is reused when its account is back
+	logins  []string
+	evicted []bool
+	cancels []context.CancelFunc
 
 	manager accountManager
-	repo    repo
+	repo    RequestLimitsRepo
 
 	mu           sync.RWMutex
 	finderDelays []int64
 	finderTemp   []float64
+	strategy     strategy
+	// waiters is the FIFO queue of *waiter, the finders are handed out in the arrival order
+	waiters *list.List
 
 	log log.Logger
 
 	metricsOne   *prometheus.HistogramVec
 	metricsNext  *prometheus.HistogramVec
 	metricsDelay *prometheus.GaugeVec
-	proxies      []string
+	metricsWait  *prometheus.HistogramVec
+	delayMetrics *DelayMetrics
+	proxies      proxymanager.Manager
+	leases       lease.Leaser
 }
 
 func (p *pool) IsHot() bool {
 	hotCounter := 0
 
-	for _, temp := range p.finderTemp {
-		if skipFinder(temp) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for i, temp := range p.finderTemp {
+		if p.evicted[i] || skipFinder(temp) {
 			hotCounter++
 		}
 	}
@@ -68,6 +118,21 @@ func (p *pool) IsHot() bool {
 	return hotCounter > len(p.finderTemp)*9/10
 }
 
+// Capacity counts finders which are not overheated, busy ones are counted too as they return soon.
+func (p *pool) Capacity() int {
+	capacity := 0
+
+	p.mu.RLock()
+	for i, temp := range p.finderTemp {
+		if !p.evicted[i] && temp <= maxFinderTemp {
+			capacity++
+		}
+	}
+	p.mu.RUnlock()
+
+	return capacity
+}
+
 func (p *pool) CurrentTemp(context.Context) float64 {
 	sum := 0.0 //nolint:goconst
 
@@ -80,15 +145,15 @@ func (p *pool) CurrentTemp(context.Context) float64 {
 	return sum / float64(len(p.finderTemp))
 }
 
-func (p *pool) FindNext(ctx context.Context, start, end *time.Time, search, cursor string) ([]common.TweetSnapshot, string, error) {
-	f, index, err := p.getFinder(ctx)
+func (p *pool) FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
+	f, index, err := p.getFinder(ctx, query.Render())
 	if err != nil {
 		return nil, "", err
 	}
 
 	defer p.releaseFinder(index)
 
-	return f.FindNext(ctx, start, end, search, cursor)
+	return f.FindNext(ctx, query, cursor)
 }
 
 func (p *pool) Init(ctx context.Context) error {
@@ -97,6 +162,8 @@ func (p *pool) Init(ctx context.Context) error {
 	}
 
 	go p.reinit(ctx)
+	go p.coolDown(ctx)
+	go p.leases.Run(ctx, p.lost)
 
 	return nil
 }
@@ -114,7 +181,7 @@ func (p *pool) CurrentDelay() int64 {
 }
 
 func (p *pool) Find(ctx context.Context, id string) (*common.TweetSnapshot, error) {
-	f, index, err := p.getFinder(ctx)
+	f, index, err := p.getFinder(ctx, "")
 	if err != nil {
 		return nil, err
 	}
@@ -124,37 +191,65 @@ func (p *pool) Find(ctx context.Context, id string) (*common.TweetSnapshot, erro
 	return f.Find(ctx, id)
 }
 
-func (p *pool) getFinder(ctx context.Context) (Finder, int, error) {
-	index, ok := p.getFinderIndex(ctx)
+// getFinder takes the finder for the request right away if nobody waits, otherwise the request joins the queue.
+func (p *pool) getFinder(ctx context.Context, request string) (Finder, int, error) {
+	start := time.Now()
 
-	ticker := time.NewTicker(time.Second)
+	p.mu.Lock()
+	if p.waiters.Len() == 0 {
+		if index, ok := p.selectFinder(ctx, request); ok {
+			p.mu.Unlock()
+			p.observeWait(start, waitAcquired)
 
-	timer := time.NewTimer(time.Minute)
+			return p.finders[index], index, nil
+		}
+	}
+
+	w := &waiter{request: request, ready: make(chan int, 1)}
+	element := p.waiters.PushBack(w)
+	p.mu.Unlock()
+
+	timer := time.NewTimer(p.config.WaitTimeout)
 	defer timer.Stop()
 
-	for !ok {
-		select {
-		case <-ctx.Done():
-			return nil, 0, ctx.Err()
-		case <-timer.C:
-			return nil, 0, ErrTimeoutSelectFinder
-		case <-p.releaseSignal:
-			break
-		case <-ticker.C:
-			break
-		}
+	var (
+		err    error
+		result string
+	)
 
-		index, ok = p.getFinderIndex(ctx)
+	select {
+	case index := <-w.ready:
+		p.observeWait(start, waitAcquired)
+
+		return p.finders[index], index, nil
+	case <-ctx.Done():
+		err, result = ctx.Err(), waitCancelled
+	case <-timer.C:
+		err, result = ErrTimeoutSelectFinder, waitTimeout
 	}
 
-	return p.finders[index], index, nil
+	p.mu.Lock()
+	p.waiters.Remove(element)
+	p.mu.Unlock()
+
+	// the finder handed out right before the waiter gave up goes to the next one
+	select {
+	case index := <-w.ready:
+		p.releaseFinder(index)
+	default:
+	}
+
+	p.observeWait(start, result)
+
+	return nil, 0, err
 }
 
-func (p *pool) getFinderIndex(ctx context.Context) (int, bool) {
-	p.mu.Lock()
-	minimal := 0.0
-	index := 0
+func (p *pool) observeWait(start time.Time, result string) {
+	p.metricsWait.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
 
+// selectFinder marks the finder chosen by the strategy as busy, it must be called under the lock.
+func (p *pool) selectFinder(ctx context.Context, request string) (int, bool) {
 	for i := range p.finderTemp {
 		if p.finderTemp[i] != 0 {
 			p.finderDelays[i] = p.finders[i].CurrentDelay()
@@ -162,132 +257,316 @@ func (p *pool) getFinderIndex(ctx context.Context) (int, bool) {
 		}
 	}
 
+	candidates := make([]candidate, 0, len(p.finderTemp))
+
 	for i, d := range p.finderTemp {
-		if skipFinder(d) {
+		if p.evicted[i] || skipFinder(d) {
 			continue
 		}
 
-		if d < minimal || minimal == 0 {
-			minimal = d
-			index = i
-		}
+		candidates = append(candidates, candidate{index: i, login: p.logins[i], temp: d})
 	}
 
-	if minimal != 0 {
-		p.finderDelays[index] = 0
-		p.finderTemp[index] = 0
+	if len(candidates) == 0 {
+		return 0, false
 	}
 
-	p.mu.Unlock()
+	index := candidates[p.strategy.Select(request, candidates)].index
+	p.finderDelays[index] = 0
+	p.finderTemp[index] = 0
 
-	return index, minimal != 0
+	return index, true
+}
+
+// dispatch hands the finders to the waiters from the head of the queue while there are suitable ones,
+// it must be called under the lock.
+func (p *pool) dispatch(ctx context.Context) {
+	for p.waiters.Len() > 0 {
+		front := p.waiters.Front()
+		w := front.Value.(*waiter)
+
+		index, ok := p.selectFinder(ctx, w.request)
+		if !ok {
+			return
+		}
+
+		p.waiters.Remove(front)
+		w.ready <- index
+	}
+}
+
+// coolDown offers the finders to the waiters periodically, the hot finders cool down without a release.
+func (p *pool) coolDown(ctx context.Context) {
+	ticker := time.NewTicker(p.config.CoolDownCheck)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			if p.waiters.Len() > 0 {
+				p.dispatch(ctx)
+			}
+			p.mu.Unlock()
+		}
+	}
 }
 
 func skipFinder(d float64) bool {
-	return d == 0 || d > 4
+	return d == 0 || d > maxFinderTemp
 }
 
 func (p *pool) releaseFinder(i int) {
+	ctx := context.Background()
+
 	p.mu.Lock()
 	p.finderDelays[i] = p.finders[i].CurrentDelay()
-	p.finderTemp[i] = p.finders[i].CurrentTemp(context.Background())
+	p.finderTemp[i] = p.finders[i].CurrentTemp(ctx)
+	p.dispatch(ctx)
 	p.mu.Unlock()
-
-	select {
-	case p.releaseSignal <- struct{}{}:
-	default:
-	}
 }
 
 func (p *pool) init(ctx context.Context) error {
 	delayManagerLogger := p.log.WithField(pkgKey, "delay_manager")
-	limiterLogger := p.log.WithField(pkgKey, "window_limiter")
 	finderLogger := p.log.WithField(pkgKey, "finder")
 
 	var delayManager Manager
 
-	accounts, err := p.manager.SearchUnAuthAccounts(ctx)
+	// the accounts disabled by the admin are evicted even if their requests keep succeeding
+	disabled, err := p.manager.SearchDisabledAccounts(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, login := range disabled {
+		p.unhealthy(ctx, login, common.AccountDisabled)
+	}
+
+	candidates, err := p.manager.SearchUnAuthAccounts(ctx)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := p.leases.Claim(ctx, candidates)
 	if err != nil {
 		return err
 	}
 
 	for _, account := range accounts {
-		scraper := twitterscraper.New().WithDelay(startDelay).SetSearchMode(twitterscraper.SearchLatest)
+		scraper := twitterscraper.New().SetSearchMode(twitterscraper.SearchLatest)
 		scraper.WithClientTimeout(time.Minute)
 		scraper.SetUserAgent(defaultUserAgent)
 
-		if len(p.proxies) > 0 {
-			if err = scraper.SetProxy(p.proxies[0]); err != nil {
-				return err
-			}
+		// the proxy manager rebinds the account while it serves the requests
+		guard := new(proxyGuard)
+		windowLimiters, headers, bind := p.limiters(account.Login, scraper)
+		bind = guard.bind(bind)
 
-			p.proxies = p.proxies[1:]
-		}
+		warm := p.newWarmUp(account, windowLimiters)
 
-		if err = p.manager.AuthScrapper(ctx, account, scraper); err != nil {
-			return err
+		minimalDelay := int64(startDelay)
+		if warm != nil {
+			minimalDelay = max(minimalDelay, warm.Delay())
 		}
 
-		limiterIntervals := []time.Duration{
-			time.Minute * 10,
-			time.Hour,
-			time.Hour * 24,
-			time.Hour * 24 * 30,
+		scraper.WithDelay(minimalDelay)
+
+		// the account is given back, so another replica or the next refresh takes it,
+		// the other accounts are still added
+		if err = p.proxies.Bind(ctx, account.Login, bind); err != nil {
+			p.log.WithError(err).WithField(finderLogin, account.Login).Error("error while binding proxy")
+			p.leases.Release(ctx, account.Login)
+
+			continue
 		}
 
-		windowLimiters := make([]WindowLimiter, len(limiterIntervals))
+		// the manager backs the account off
+		if err = p.manager.AuthScrapper(ctx, account, scraper); err != nil {
+			p.leases.Release(ctx, account.Login)
+			continue
+		}
 
-		for j := len(limiterIntervals) - 1; j >= 0; j-- {
-			resetInterval := limiterIntervals[j]
-			if j != len(limiterIntervals)-1 {
-				resetInterval = limiterIntervals[j+1]
-			}
+		finderCtx, cancel := context.WithCancel(ctx)
 
-			windowLimiters[j] = windowlimiter.NewLimiter(
-				limiterIntervals[j],
-				resetInterval,
-				account.Login,
-				p.repo,
-				limiterLogger.WithField(finderLogin, account.Login),
-			)
+		ds := newDelaySetter(func(seconds int64) { scraper.WithDelay(seconds) }, p.metricsDelay, account.Login)
+		setter := ds.Set
 
-			if j != len(limiterIntervals)-1 {
-				windowLimiters[j].SetResetLimiter(windowLimiters[j+1])
-			}
+		if warm != nil {
+			setter = func(seconds int64) { ds.Set(max(seconds, warm.Delay())) }
 		}
 
-		ds := newDelaySetter(func(seconds int64) { scraper.WithDelay(seconds) }, p.metricsDelay, account.Login)
+		delayManager = p.newDelayManager(setter, windowLimiters, headers, minimalDelay, account.Login, delayManagerLogger)
 
-		delayManager = NewDelayManagerV2(
-			ds.Set,
-			windowLimiters,
-			startDelay,
-			delayManagerLogger.WithField(finderLogin, account.Login),
-		)
+		if warm != nil {
+			delayManager = &warmUpManager{Manager: delayManager, warmUp: warm, setter: setter}
+		}
 
-		if err = delayManager.Start(ctx); err != nil {
+		if err = delayManager.Start(finderCtx); err != nil {
+			cancel()
 			return err
 		}
 
+		login := account.Login
+
 		f := NewMetricMiddleware(
 			p.metricsOne, p.metricsNext,
-			account.Login,
-			NewFinder(scraper, delayManager, finderLogger.WithField(finderLogin, account.Login)),
+			login,
+			newAccountMiddleware(
+				p.manager,
+				login,
+				func(status common.AccountStatus) { p.unhealthy(ctx, login, status) },
+				newProxyMiddleware(
+					p.proxies,
+					login,
+					NewFinder(guard.source(source.NewScraper(scraper)), delayManager, finderLogger.WithField(finderLogin, login)),
+				),
+			),
 		)
 
-		p.mu.Lock()
+		p.add(ctx, login, f, cancel)
+	}
+
+	return nil
+}
+
+// newDelayManager creates the delay manager of the account with the configured controller.
+func (p *pool) newDelayManager(
+	setter func(seconds int64),
+	windowLimiters []WindowLimiter,
+	headers HeaderLimiter,
+	minimalDelay int64,
+	login string,
+	logger log.Logger,
+) Manager {
+	logger = logger.WithField(finderLogin, login)
+
+	if p.delays.Controller == ControllerAIMD {
+		return NewDelayManagerAIMD(p.clock, p.delays, setter, windowLimiters, headers, minimalDelay, login, p.delayMetrics, logger)
+	}
+
+	return NewDelayManagerV2(p.clock, setter, windowLimiters, headers, minimalDelay, login, p.delayMetrics, logger)
+}
+
+// newWarmUp returns the warm-up of the new account, the limiters of the account are created with its capped rate.
+func (p *pool) newWarmUp(account common.TwitterAccount, windowLimiters []WindowLimiter) *warmUp {
+	windows := make([]time.Duration, 0, len(windowLimiters))
+	for _, limiter := range windowLimiters {
+		windows = append(windows, limiter.Duration())
+	}
+
+	logger := p.log.WithField(pkgKey, "warm_up").WithField(finderLogin, account.Login)
+
+	return newWarmUp(p.warmUp, p.clock, account, windows, p.manager, p.repo, p.delayMetrics, logger)
+}
+
+// NewWindowLimiters creates the limiters of the account for the LimiterIntervals, every limiter raises its threshold
+// up to the share of the next longer one.
+func NewWindowLimiters(clock clock.Clock, login string, db RequestLimitsRepo, logger log.Logger) []WindowLimiter {
+	windowLimiters := make([]WindowLimiter, len(LimiterIntervals))
+
+	for j := len(LimiterIntervals) - 1; j >= 0; j-- {
+		resetInterval := LimiterIntervals[j]
+		if j != len(LimiterIntervals)-1 {
+			resetInterval = LimiterIntervals[j+1]
+		}
+
+		windowLimiters[j] = windowlimiter.NewLimiter(clock, LimiterIntervals[j], resetInterval, login, db, logger)
+
+		if j != len(LimiterIntervals)-1 {
+			windowLimiters[j].SetResetLimiter(windowLimiters[j+1])
+		}
+	}
+
+	return windowLimiters
+}
+
+// limiters creates the rate limiters of the account by the configured source. The header limiter captures
+// the responses of the scraper, so the returned bind wraps the transport again after every proxy change.
+func (p *pool) limiters(login string, scraper *twitterscraper.Scraper) ([]WindowLimiter, HeaderLimiter, func(proxy string) error) {
+	var windowLimiters []WindowLimiter
+
+	if p.limits.Source != LimitsHeader {
+		windowLimiters = NewWindowLimiters(p.clock, login, p.repo, p.log.WithField(pkgKey, "window_limiter").WithField(finderLogin, login))
+	}
+
+	if p.limits.Source == LimitsWindow {
+		return windowLimiters, nil, scraper.SetProxy
+	}
+
+	headers := headerlimiter.NewLimiter(p.limits.PaceFrom, p.log.WithField(pkgKey, "header_limiter").WithField(finderLogin, login))
+	client := source.HTTPClient(scraper)
+	client.Transport = headers.Transport(client.Transport)
+
+	bind := func(proxy string) error {
+		if err := scraper.SetProxy(proxy); err != nil {
+			return err
+		}
+
+		client.Transport = headers.Transport(client.Transport)
+
+		return nil
+	}
+
+	return windowLimiters, headers, bind
+}
+
+// add puts the finder into the slot of its evicted predecessor or appends a new one.
+func (p *pool) add(ctx context.Context, login string, f Finder, cancel context.CancelFunc) {
+	p.mu.Lock()
+
+	index := slices.Index(p.logins, login)
+	if index == -1 {
 		p.finders = append(p.finders, f)
 		p.finderDelays = append(p.finderDelays, f.CurrentDelay())
 		p.finderTemp = append(p.finderTemp, f.CurrentTemp(ctx))
-		p.mu.Unlock()
+		p.logins = append(p.logins, login)
+		p.evicted = append(p.evicted, false)
+		p.cancels = append(p.cancels, cancel)
+	} else {
+		p.cancels[index]()
+		p.finders[index] = f
+		p.finderDelays[index] = f.CurrentDelay()
+		p.finderTemp[index] = f.CurrentTemp(ctx)
+		p.evicted[index] = false
+		p.cancels[index] = cancel
+	}
 
-		select {
-		case p.releaseSignal <- struct{}{}:
-		default:
-		}
+	p.dispatch(ctx)
+	p.mu.Unlock()
+}
+
+// unhealthy evicts the finder of the account which is no longer usable. The disabled account never comes back,
+// so its lease is released to keep the share of the replica for the working accounts.
+func (p *pool) unhealthy(ctx context.Context, login string, status common.AccountStatus) {
+	if status == common.AccountDisabled {
+		p.leases.Release(ctx, login)
 	}
 
-	return nil
+	p.evict(login)
+}
+
+// evict takes the finder out of rotation, the request in flight finishes as usual.
+func (p *pool) evict(login string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	index := slices.Index(p.logins, login)
+	if index == -1 || p.evicted[index] {
+		return
+	}
+
+	p.evicted[index] = true
+	p.cancels[index]()
+
+	p.log.WithField(finderLogin, login).Warn("finder evicted from the pool")
+}
+
+// lost evicts the finder of the account leased by another replica now.
+func (p *pool) lost(login string) {
+	p.manager.Forget(login)
+	p.evict(login)
 }
 
 func (p *pool) reinit(ctx context.Context) {
@@ -299,20 +578,56 @@ func (p *pool) reinit(ctx context.Context) {
 	}
 }
 
-func NewPool(metricsOne, metricsNext *prometheus.HistogramVec, metricsDelay *prometheus.GaugeVec,
-	config ConfigProxies, manager accountManager, db repo, logger log.Logger) Finder {
+func NewPool(config *SelectionConfig, limits *LimitsConfig, delays *DelayConfig, warmUp *WarmUpConfig,
+	metricsOne, metricsNext, metricsWait *prometheus.HistogramVec, metricsDelay *prometheus.GaugeVec, delayMetrics *DelayMetrics,
+	proxies proxymanager.Manager, leases lease.Leaser, manager accountManager, db RequestLimitsRepo, logger log.Logger) (Finder, error) {
+	selection, err := newStrategy(config.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	if limits.Source != LimitsWindow && limits.Source != LimitsHeader && limits.Source != LimitsBoth {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownLimitsSource, limits.Source)
+	}
+
+	if delays.Controller != ControllerV2 && delays.Controller != ControllerAIMD {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownController, delays.Controller)
+	}
+
+	if delays.Controller == ControllerAIMD && (delays.AIMDIncrease <= 0 || delays.AIMDDecrease <= 0 || delays.AIMDDecrease >= 1 ||
+		delays.AIMDInterval <= 0 || delays.AIMDMinRate <= 0 || delays.AIMDMinRate > delays.AIMDMaxRate) {
+		return nil, fmt.Errorf("%w: increase %v, decrease %v, interval %s, min rate %v, max rate %v", ErrInvalidAIMD,
+			delays.AIMDIncrease, delays.AIMDDecrease, delays.AIMDInterval, delays.AIMDMinRate, delays.AIMDMaxRate)
+	}
+
+	if warmUp.Duration != 0 && (warmUp.StartRate <= 0 || warmUp.StartRate > fullRate || warmUp.Interval <= 0) {
+		return nil, fmt.Errorf("%w: start rate %v, interval %s", ErrInvalidWarmUp, warmUp.StartRate, warmUp.Interval)
+	}
+
 	return &pool{
-		config:       config,
 		finders:      make([]Finder, 0),
+		config:       config,
+		limits:       limits,
+		delays:       delays,
+		warmUp:       warmUp,
+		clock:        clock.New(),
+		logins:       make([]string, 0),
+		evicted:      make([]bool, 0),
+		cancels:      make([]context.CancelFunc, 0),
 		manager:      manager,
 		repo:         db,
-		proxies:      config.GetProxies(),
+		proxies:      proxies,
+		leases:       leases,
 		mu:           sync.RWMutex{},
 		finderDelays: make([]int64, 0),
 		finderTemp:   make([]float64, 0),
+		strategy:     selection,
+		waiters:      list.New(),
 		log:          logger,
 		metricsOne:   metricsOne,
 		metricsNext:  metricsNext,
 		metricsDelay: metricsDelay,
-	}
+		metricsWait:  metricsWait,
+		delayMetrics: delayMetrics,
+	}, nil
 }
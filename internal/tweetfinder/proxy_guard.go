@@ -0,0 +1,56 @@
+package tweetfinder
+
+import (
+	"context"
+	"sync"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/source"
+)
+
+// proxyGuard serializes the proxy changes of the account with its requests: the change swaps the transport
+// of the http client the requests run on, so it waits for the requests in flight and holds the new ones.
+type proxyGuard struct {
+	mu sync.RWMutex
+}
+
+// bind applies the proxy once the account has no request in flight.
+func (g *proxyGuard) bind(bind func(proxy string) error) func(proxy string) error {
+	return func(proxy string) error {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		return bind(proxy)
+	}
+}
+
+// source runs the requests of the account under the guard.
+func (g *proxyGuard) source(next source.Source) source.Source {
+	return &guardedSource{guard: g, next: next}
+}
+
+type guardedSource struct {
+	guard *proxyGuard
+	next  source.Source
+}
+
+func (s *guardedSource) Search(ctx context.Context, query common.SearchQuery, limit int, cursor string) ([]common.TweetSnapshot, string, error) {
+	s.guard.mu.RLock()
+	defer s.guard.mu.RUnlock()
+
+	return s.next.Search(ctx, query, limit, cursor)
+}
+
+func (s *guardedSource) GetTweet(ctx context.Context, id string) (*common.TweetSnapshot, error) {
+	s.guard.mu.RLock()
+	defer s.guard.mu.RUnlock()
+
+	return s.next.GetTweet(ctx, id)
+}
+
+func (s *guardedSource) UserTimeline(ctx context.Context, username string, limit int, cursor string) ([]common.TweetSnapshot, string, error) {
+	s.guard.mu.RLock()
+	defer s.guard.mu.RUnlock()
+
+	return s.next.UserTimeline(ctx, username, limit, cursor)
+}
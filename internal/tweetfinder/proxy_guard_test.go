@@ -0,0 +1,64 @@
+package tweetfinder
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/source"
+)
+
+// heldSource answers the search once released.
+type heldSource struct {
+	source.Source
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *heldSource) Search(context.Context, common.SearchQuery, int, string) ([]common.TweetSnapshot, string, error) {
+	close(s.started)
+	<-s.release
+
+	return nil, "", nil
+}
+
+func TestProxyGuard(t *testing.T) {
+	guard := new(proxyGuard)
+	held := &heldSource{started: make(chan struct{}), release: make(chan struct{})}
+	src := guard.source(held)
+
+	searched := make(chan struct{})
+
+	go func() {
+		_, _, err := src.Search(context.Background(), common.SearchQuery{}, 1, "")
+		require.NoError(t, err)
+		close(searched)
+	}()
+
+	<-held.started
+
+	var bound atomic.Bool
+
+	bind := guard.bind(func(string) error {
+		bound.Store(true)
+		return nil
+	})
+
+	done := make(chan error)
+
+	go func() { done <- bind("http://proxy") }()
+
+	// the proxy is changed after the request in flight
+	time.Sleep(time.Millisecond * 20)
+	require.False(t, bound.Load())
+
+	close(held.release)
+	<-searched
+
+	require.NoError(t, <-done)
+	require.True(t, bound.Load())
+}
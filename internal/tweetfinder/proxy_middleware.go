@@ -0,0 +1,87 @@
+package tweetfinder
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/source"
+	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder/proxymanager"
+)
+
+// proxyMiddleware reports the outcome of every request of the account to the proxy manager.
+type proxyMiddleware struct {
+	login   string
+	proxies proxymanager.Manager
+	next    Finder
+}
+
+func (m *proxyMiddleware) IsHot() bool {
+	return m.next.IsHot()
+}
+
+func (m *proxyMiddleware) FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
+	st := time.Now()
+
+	tweets, nextCursor, err := m.next.FindNext(ctx, query, cursor)
+
+	m.report(ctx, st, err)
+
+	return tweets, nextCursor, err
+}
+
+func (m *proxyMiddleware) Find(ctx context.Context, id string) (*common.TweetSnapshot, error) {
+	st := time.Now()
+
+	tweet, err := m.next.Find(ctx, id)
+
+	m.report(ctx, st, err)
+
+	return tweet, err
+}
+
+func (m *proxyMiddleware) CurrentDelay() int64 {
+	return m.next.CurrentDelay()
+}
+
+func (m *proxyMiddleware) CurrentTemp(ctx context.Context) float64 {
+	return m.next.CurrentTemp(ctx)
+}
+
+func (m *proxyMiddleware) Init(context.Context) error {
+	return nil
+}
+
+func (m *proxyMiddleware) Capacity() int {
+	return m.next.Capacity()
+}
+
+// report skips the cancelled requests, they tell nothing about the proxy.
+func (m *proxyMiddleware) report(ctx context.Context, st time.Time, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	m.proxies.Report(ctx, m.login, proxyResult(err), time.Since(st))
+}
+
+func proxyResult(err error) proxymanager.Result {
+	var transportErr *url.Error
+
+	switch {
+	case err == nil, errors.Is(err, ErrNotFound):
+		return proxymanager.ResultOK
+	case errors.Is(err, source.ErrRateLimited):
+		return proxymanager.ResultRateLimited
+	case errors.As(err, &transportErr):
+		return proxymanager.ResultFailure
+	default:
+		return proxymanager.ResultError
+	}
+}
+
+func newProxyMiddleware(proxies proxymanager.Manager, login string, next Finder) Finder {
+	return &proxyMiddleware{login: login, proxies: proxies, next: next}
+}
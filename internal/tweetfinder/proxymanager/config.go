@@ -0,0 +1,25 @@
+package proxymanager
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Config struct {
+	// FailureThreshold is the number of consecutive failures after which the proxy is taken down.
+	FailureThreshold int           `envconfig:"FAILURE_THRESHOLD" default:"3"`
+	CoolDown         time.Duration `envconfig:"COOL_DOWN" default:"10m"`
+	CheckInterval    time.Duration `envconfig:"CHECK_INTERVAL" default:"1m"`
+	CheckURL         string        `envconfig:"CHECK_URL" default:"https://twitter.com"`
+	CheckTimeout     time.Duration `envconfig:"CHECK_TIMEOUT" default:"10s"`
+}
+
+func GetConfig() *Config {
+	cfg := new(Config)
+	if err := envconfig.Process("PROXY_MANAGER", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
@@ -0,0 +1,380 @@
+package proxymanager
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+)
+
+const (
+	loginKey = "login"
+	proxyKey = "proxy"
+)
+
+// Result is the outcome of a request made through a proxy.
+type Result string
+
+const (
+	ResultOK          Result = "ok"
+	ResultRateLimited Result = "rate_limited"
+	// ResultError is an error returned by Twitter, the proxy itself works.
+	ResultError Result = "error"
+	// ResultFailure is a transport error, only failures take the proxy down.
+	ResultFailure Result = "failure"
+)
+
+// Manager binds the accounts to proxies and moves them away from the proxies which stop working.
+type Manager interface {
+	// Bind applies the proxy of the account with bind, the assignment is sticky across restarts.
+	// The bind is called again on the rebind, it is expected to wait for the requests of the account in flight.
+	Bind(ctx context.Context, login string, bind func(proxy string) error) error
+	// Report records the outcome of a request of the account, repeated failures rebind it to another proxy.
+	Report(ctx context.Context, login string, result Result, latency time.Duration)
+	// Run probes the proxies which are down and returns the recovered ones to the rotation until ctx is done.
+	Run(ctx context.Context)
+}
+
+type store interface {
+	GetProxyAssignment(ctx context.Context, login string) (string, error)
+	SaveProxyAssignment(ctx context.Context, login, proxy string) error
+}
+
+type proxyState struct {
+	address  string
+	host     string
+	failures int
+	down     bool
+	downAt   time.Time
+	accounts int
+}
+
+type binding struct {
+	proxy string
+	bind  func(proxy string) error
+}
+
+type manager struct {
+	mu       sync.Mutex
+	proxies  []*proxyState
+	bindings map[string]*binding
+
+	store   store
+	probe   func(ctx context.Context, address string) error
+	config  *Config
+	metrics *Metrics
+	logger  log.Logger
+}
+
+// Bind chooses the proxy under the lock, the store and the scraper are called after it is released.
+func (m *manager) Bind(ctx context.Context, login string, bind func(proxy string) error) error {
+	// the proxies are never changed after the start, so they are read without the lock
+	if len(m.proxies) == 0 {
+		return nil
+	}
+
+	stored, err := m.store.GetProxyAssignment(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+
+	state := m.find(stored)
+	if state == nil || state.down {
+		if state = m.pick(nil); state == nil {
+			// every proxy is down, the stored one recovers first as it is probed too
+			if state = m.find(stored); state == nil {
+				state = m.proxies[0]
+			}
+		}
+	}
+
+	previous, rebound := m.bindings[login]
+	if rebound {
+		m.release(previous.proxy)
+	}
+
+	b := &binding{proxy: state.address, bind: bind}
+	m.bindings[login] = b
+	m.acquire(state)
+
+	m.mu.Unlock()
+
+	if err = bind(state.address); err != nil {
+		m.mu.Lock()
+		m.unbind(login, b, previous)
+		m.mu.Unlock()
+
+		return err
+	}
+
+	if state.address != stored {
+		if err = m.store.SaveProxyAssignment(ctx, login, state.address); err != nil {
+			return err
+		}
+	}
+
+	m.logger.WithField(loginKey, login).WithField(proxyKey, state.host).Debug("account bound to proxy")
+
+	return nil
+}
+
+// Report runs on every request, so the accounts of the failed proxy are moved under the lock
+// and rebound after it is released.
+func (m *manager) Report(ctx context.Context, login string, result Result, latency time.Duration) {
+	for _, r := range m.report(login, result, latency) {
+		m.rebind(ctx, r)
+	}
+}
+
+// report records the result and returns the accounts to rebind when the proxy goes down.
+func (m *manager) report(login string, result Result, latency time.Duration) []rebinding {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.bindings[login]
+	if !ok {
+		return nil
+	}
+
+	state := m.find(b.proxy)
+	if state == nil {
+		return nil
+	}
+
+	m.metrics.Requests.WithLabelValues(state.host, string(result)).Inc()
+	m.metrics.Latency.WithLabelValues(state.host).Observe(latency.Seconds())
+
+	if result != ResultFailure {
+		state.failures = 0
+		return nil
+	}
+
+	state.failures++
+	if state.failures < m.config.FailureThreshold || state.down {
+		return nil
+	}
+
+	state.down = true
+	state.downAt = time.Now()
+	m.metrics.Healthy.WithLabelValues(state.host).Set(0)
+
+	m.logger.WithField(proxyKey, state.host).Warn("proxy is down")
+
+	rebindings := make([]rebinding, 0)
+
+	for bound, other := range m.bindings {
+		if other.proxy != state.address {
+			continue
+		}
+
+		to := m.pick(state)
+		if to == nil {
+			m.logger.WithField(loginKey, bound).Warn("no healthy proxy to rebind the account")
+			continue
+		}
+
+		// the accounts are moved right away, so the next picks see the balanced proxies
+		m.release(other.proxy)
+		m.acquire(to)
+		other.proxy = to.address
+
+		rebindings = append(rebindings, rebinding{login: bound, binding: other, from: state, to: to})
+	}
+
+	return rebindings
+}
+
+func (m *manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		m.check(ctx)
+	}
+}
+
+// check probes the proxies which are down longer than the cool down, the probes run without the lock.
+func (m *manager) check(ctx context.Context) {
+	m.mu.Lock()
+
+	candidates := make([]*proxyState, 0)
+
+	for _, state := range m.proxies {
+		if state.down && time.Since(state.downAt) >= m.config.CoolDown {
+			candidates = append(candidates, state)
+		}
+	}
+
+	m.mu.Unlock()
+
+	for _, state := range candidates {
+		if err := m.probe(ctx, state.address); err != nil {
+			m.logger.WithError(err).WithField(proxyKey, state.host).Debug("proxy is still down")
+			continue
+		}
+
+		m.mu.Lock()
+		state.down = false
+		state.failures = 0
+		m.metrics.Healthy.WithLabelValues(state.host).Set(1)
+		m.mu.Unlock()
+
+		m.logger.WithField(proxyKey, state.host).Info("proxy is back")
+	}
+}
+
+// rebinding is the account moved from the proxy which went down, the move is applied to the scraper without the lock.
+type rebinding struct {
+	login   string
+	binding *binding
+	from    *proxyState
+	to      *proxyState
+}
+
+func (m *manager) rebind(ctx context.Context, r rebinding) {
+	if err := r.binding.bind(r.to.address); err != nil {
+		m.logger.WithError(err).WithField(loginKey, r.login).Error("rebind account")
+
+		// the scraper stays on the old proxy, the account is counted there again unless it was bound since
+		m.mu.Lock()
+		if r.binding.proxy == r.to.address {
+			m.release(r.to.address)
+			m.acquire(r.from)
+			r.binding.proxy = r.from.address
+		}
+		m.mu.Unlock()
+
+		return
+	}
+
+	if err := m.store.SaveProxyAssignment(ctx, r.login, r.to.address); err != nil {
+		m.logger.WithError(err).WithField(loginKey, r.login).Error("save proxy assignment")
+	}
+
+	m.logger.WithField(loginKey, r.login).WithField(proxyKey, r.to.host).Info("account rebound to proxy")
+}
+
+// unbind reverts the binding which failed to apply, the previous binding of the account is restored.
+func (m *manager) unbind(login string, b *binding, previous *binding) {
+	if m.bindings[login] != b {
+		return
+	}
+
+	m.release(b.proxy)
+
+	if previous == nil {
+		delete(m.bindings, login)
+		return
+	}
+
+	m.bindings[login] = previous
+
+	if state := m.find(previous.proxy); state != nil {
+		m.acquire(state)
+	}
+}
+
+// pick returns the healthy proxy with the fewest accounts, nil when there is none.
+func (m *manager) pick(exclude *proxyState) *proxyState {
+	var best *proxyState
+
+	for _, state := range m.proxies {
+		if state.down || state == exclude {
+			continue
+		}
+
+		if best == nil || state.accounts < best.accounts {
+			best = state
+		}
+	}
+
+	return best
+}
+
+func (m *manager) find(address string) *proxyState {
+	for _, state := range m.proxies {
+		if state.address == address {
+			return state
+		}
+	}
+
+	return nil
+}
+
+func (m *manager) acquire(state *proxyState) {
+	state.accounts++
+	m.metrics.Accounts.WithLabelValues(state.host).Set(float64(state.accounts))
+}
+
+func (m *manager) release(address string) {
+	if state := m.find(address); state != nil {
+		state.accounts--
+		m.metrics.Accounts.WithLabelValues(state.host).Set(float64(state.accounts))
+	}
+}
+
+func (m *manager) httpProbe(ctx context.Context, address string) error {
+	proxyURL, err := url.Parse(address)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+		Timeout:   m.config.CheckTimeout,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, m.config.CheckURL, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+func proxyHost(address string) string {
+	proxyURL, err := url.Parse(address)
+	if err != nil {
+		return "invalid"
+	}
+
+	return proxyURL.Host
+}
+
+// NewManager creates a manager of the proxy addresses, with no proxies the accounts connect directly.
+func NewManager(config *Config, proxies []string, store store, metrics *Metrics, logger log.Logger) Manager {
+	m := &manager{
+		proxies:  make([]*proxyState, 0, len(proxies)),
+		bindings: make(map[string]*binding),
+		store:    store,
+		config:   config,
+		metrics:  metrics,
+		logger:   logger,
+	}
+
+	m.probe = m.httpProbe
+
+	for _, address := range proxies {
+		state := &proxyState{address: address, host: proxyHost(address)}
+		m.proxies = append(m.proxies, state)
+		m.metrics.Healthy.WithLabelValues(state.host).Set(1)
+	}
+
+	return m
+}
@@ -0,0 +1,45 @@
+package proxymanager
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics are labeled by the proxy host, the credentials never leave the manager.
+type Metrics struct {
+	Requests *prometheus.CounterVec   // proxy, result
+	Latency  *prometheus.HistogramVec // proxy
+	Healthy  *prometheus.GaugeVec     // proxy
+	Accounts *prometheus.GaugeVec     // proxy
+}
+
+func NewMetrics(namespace, subsystem string) *Metrics {
+	return &Metrics{
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "proxy_requests_total",
+			Help:      "Requests through the proxy by result",
+		}, []string{"proxy", "result"}),
+		Latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "proxy_request_seconds",
+			Help:      "Requests through the proxy histogram in seconds",
+			Buckets:   []float64{.05, .1, .5, 1, 2.5, 5, 10, 25, 60},
+		}, []string{"proxy"}),
+		Healthy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "proxy_healthy",
+			Help:      "1 when the proxy is in rotation, 0 when it is down",
+		}, []string{"proxy"}),
+		Accounts: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "proxy_accounts",
+			Help:      "Accounts bound to the proxy",
+		}, []string{"proxy"}),
+	}
+}
+
+func (m *Metrics) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.Requests, m.Latency, m.Healthy, m.Accounts}
+}
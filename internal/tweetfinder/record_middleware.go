@@ -0,0 +1,116 @@
+package tweetfinder
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+)
+
+const (
+	RecordFindNext = "find_next"
+	RecordFind     = "find"
+)
+
+// Record is one request of a finder and its response, recordings are NDJSON streams of records.
+type Record struct {
+	Kind       string
+	At         time.Time
+	Duration   time.Duration
+	Query      *common.SearchQuery    `json:",omitempty"`
+	Cursor     string                 `json:",omitempty"`
+	ID         string                 `json:",omitempty"`
+	Tweets     []common.TweetSnapshot `json:",omitempty"`
+	Tweet      *common.TweetSnapshot  `json:",omitempty"`
+	NextCursor string                 `json:",omitempty"`
+	Error      string                 `json:",omitempty"`
+}
+
+type recordMiddleware struct {
+	mu      sync.Mutex
+	encoder *jsoniter.Encoder
+	next    Finder
+
+	logger log.Logger
+}
+
+func (m *recordMiddleware) IsHot() bool {
+	return m.next.IsHot()
+}
+
+func (m *recordMiddleware) FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
+	st := time.Now()
+
+	tweets, nextCursor, err := m.next.FindNext(ctx, query, cursor)
+
+	m.write(Record{
+		Kind:       RecordFindNext,
+		At:         st,
+		Duration:   time.Since(st),
+		Query:      &query,
+		Cursor:     cursor,
+		Tweets:     tweets,
+		NextCursor: nextCursor,
+		Error:      errorText(err),
+	})
+
+	return tweets, nextCursor, err
+}
+
+func (m *recordMiddleware) Find(ctx context.Context, id string) (*common.TweetSnapshot, error) {
+	st := time.Now()
+
+	tweet, err := m.next.Find(ctx, id)
+
+	m.write(Record{Kind: RecordFind, At: st, Duration: time.Since(st), ID: id, Tweet: tweet, Error: errorText(err)})
+
+	return tweet, err
+}
+
+func (m *recordMiddleware) CurrentDelay() int64 {
+	return m.next.CurrentDelay()
+}
+
+func (m *recordMiddleware) CurrentTemp(ctx context.Context) float64 {
+	return m.next.CurrentTemp(ctx)
+}
+
+func (m *recordMiddleware) Init(context.Context) error {
+	return nil
+}
+
+func (m *recordMiddleware) Capacity() int {
+	return m.next.Capacity()
+}
+
+// write never fails the request, a broken recording is only logged.
+func (m *recordMiddleware) write(record Record) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.encoder.Encode(record); err != nil {
+		m.logger.WithError(err).Error("write finder record")
+	}
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
+
+// NewRecordMiddleware writes every request of the next finder and its response to w as NDJSON.
+func NewRecordMiddleware(w io.Writer, next Finder, logger log.Logger) Finder {
+	return &recordMiddleware{
+		encoder: jsoniter.NewEncoder(w),
+		next:    next,
+		logger:  logger,
+	}
+}
@@ -0,0 +1,212 @@
+package tweetfinder
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+)
+
+const maxRecordSize = 64 << 20
+
+// ReplayConfig paces the replay, with zero Speed the records are served as fast as they are requested.
+// Speed 1 serves a record not earlier than at its recorded offset from the first record, 10 is ten times faster.
+// Rebase shifts the recorded times of the tweets as if the record was served at the moment of the replay,
+// the ages of the tweets are kept, so the recorded data never looks too old.
+type ReplayConfig struct {
+	Speed  float64
+	Rebase bool
+}
+
+type replay struct {
+	mu       sync.Mutex
+	searches map[string][]Record
+	tweets   map[string][]Record
+
+	config  ReplayConfig
+	first   time.Time
+	started time.Time
+}
+
+func (r *replay) IsHot() bool {
+	return false
+}
+
+// FindNext serves the recorded responses of the search and cursor in order, an exhausted search has no tweets.
+func (r *replay) FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
+	record, ok := r.next(r.searches, searchKey(query, cursor), false)
+	if !ok {
+		return nil, "", nil
+	}
+
+	if err := r.wait(ctx, record); err != nil {
+		return nil, "", err
+	}
+
+	tweets := make([]common.TweetSnapshot, len(record.Tweets))
+	for i := range record.Tweets {
+		tweets[i] = r.rebase(record, record.Tweets[i])
+	}
+
+	return tweets, record.NextCursor, replayError(record.Error)
+}
+
+// Find serves the recorded responses of the tweet in order, the last one is repeated when they run out.
+func (r *replay) Find(ctx context.Context, id string) (*common.TweetSnapshot, error) {
+	record, ok := r.next(r.tweets, id, true)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if err := r.wait(ctx, record); err != nil {
+		return nil, err
+	}
+
+	if record.Tweet == nil {
+		return nil, replayError(record.Error)
+	}
+
+	tweet := r.rebase(record, *record.Tweet)
+
+	return &tweet, replayError(record.Error)
+}
+
+func (r *replay) CurrentDelay() int64 {
+	return 0
+}
+
+func (r *replay) CurrentTemp(context.Context) float64 {
+	return 0
+}
+
+func (r *replay) Init(context.Context) error {
+	return nil
+}
+
+func (r *replay) Capacity() int {
+	return 1
+}
+
+func (r *replay) next(records map[string][]Record, key string, keepLast bool) (Record, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	queue := records[key]
+	if len(queue) == 0 {
+		return Record{}, false
+	}
+
+	if len(queue) > 1 || !keepLast {
+		records[key] = queue[1:]
+	}
+
+	return queue[0], true
+}
+
+func (r *replay) wait(ctx context.Context, record Record) error {
+	if r.config.Speed <= 0 {
+		return ctx.Err()
+	}
+
+	offset := time.Duration(float64(record.At.Sub(r.first)) / r.config.Speed)
+
+	timer := time.NewTimer(time.Until(r.started.Add(offset)))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (r *replay) rebase(record Record, tweet common.TweetSnapshot) common.TweetSnapshot {
+	if !r.config.Rebase {
+		return tweet
+	}
+
+	shift := time.Since(record.At)
+	copied := *tweet.Tweet
+	copied.TimeParsed = copied.TimeParsed.Add(shift)
+	copied.Timestamp = copied.TimeParsed.Unix()
+	tweet.Tweet = &copied
+	tweet.CheckedAt = tweet.CheckedAt.Add(shift)
+
+	return tweet
+}
+
+func searchKey(query common.SearchQuery, cursor string) string {
+	return query.Render() + "\x00" + cursor
+}
+
+// replayError restores the finder errors the callers react to, the rest are replayed as plain errors.
+func replayError(text string) error {
+	switch text {
+	case "":
+		return nil
+	case ErrNotFound.Error():
+		return ErrNotFound
+	case ErrTimeoutSelectFinder.Error():
+		return ErrTimeoutSelectFinder
+	}
+
+	return errors.New(text)
+}
+
+// ReadRecords reads an NDJSON recording of NewRecordMiddleware.
+func ReadRecords(reader io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, maxRecordSize)
+
+	records := make([]Record, 0)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		record := Record{}
+		if err := jsoniter.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+// NewReplayFinder serves the recorded records, the searches are matched by the rendered query and the cursor.
+func NewReplayFinder(records []Record, config ReplayConfig) Finder {
+	r := &replay{
+		searches: make(map[string][]Record),
+		tweets:   make(map[string][]Record),
+		config:   config,
+		started:  time.Now(),
+	}
+
+	for _, record := range records {
+		if r.first.IsZero() || record.At.Before(r.first) {
+			r.first = record.At
+		}
+
+		switch record.Kind {
+		case RecordFindNext:
+			if record.Query != nil {
+				key := searchKey(*record.Query, record.Cursor)
+				r.searches[key] = append(r.searches[key], record)
+			}
+		case RecordFind:
+			r.tweets[record.ID] = append(r.tweets[record.ID], record)
+		}
+	}
+
+	return r
+}
@@ -0,0 +1,66 @@
+package tweetfinder
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+)
+
+type stubFinder struct {
+	Finder
+}
+
+func (f *stubFinder) FindNext(_ context.Context, _ common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
+	if cursor != "" {
+		return nil, "", nil
+	}
+
+	tweet := common.TweetSnapshot{Tweet: &common.Tweet{ID: "1", Likes: 1}, CheckedAt: time.Now()}
+
+	return []common.TweetSnapshot{tweet}, "c1", nil
+}
+
+func (f *stubFinder) Find(context.Context, string) (*common.TweetSnapshot, error) {
+	return nil, ErrNotFound
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	query := common.KeywordQuery("btc", "bitcoin", time.Minute)
+	buf := new(bytes.Buffer)
+
+	recorder := NewRecordMiddleware(buf, new(stubFinder), log.NewLogger(logrus.New()))
+
+	recorded, nextCursor, err := recorder.FindNext(ctx, query, "")
+	require.NoError(t, err)
+	_, _, err = recorder.FindNext(ctx, query, nextCursor)
+	require.NoError(t, err)
+	_, err = recorder.Find(ctx, "1")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	records, err := ReadRecords(buf)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	replay := NewReplayFinder(records, ReplayConfig{})
+
+	tweets, nextCursor, err := replay.FindNext(ctx, query, "")
+	require.NoError(t, err)
+	require.Equal(t, "c1", nextCursor)
+	require.Equal(t, recorded[0].Tweet, tweets[0].Tweet)
+
+	tweets, nextCursor, err = replay.FindNext(ctx, query, "")
+	require.NoError(t, err)
+	require.Empty(t, tweets)
+	require.Empty(t, nextCursor)
+
+	_, err = replay.Find(ctx, "1")
+	require.ErrorIs(t, err, ErrNotFound)
+}
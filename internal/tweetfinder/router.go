@@ -0,0 +1,57 @@
+package tweetfinder
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/source"
+)
+
+// router searches every query in the source it selects, the rest of the requests go to the scraper pool.
+type router struct {
+	next    Finder
+	sources map[string]source.Source
+}
+
+func (r *router) IsHot() bool {
+	return r.next.IsHot()
+}
+
+func (r *router) FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
+	if query.Source == "" || query.Source == source.Scraper {
+		return r.next.FindNext(ctx, query, cursor)
+	}
+
+	src, ok := r.sources[query.Source]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %q", ErrUnknownSource, query.Source)
+	}
+
+	return src.Search(ctx, query, limit, cursor)
+}
+
+func (r *router) Find(ctx context.Context, id string) (*common.TweetSnapshot, error) {
+	return r.next.Find(ctx, id)
+}
+
+func (r *router) CurrentDelay() int64 {
+	return r.next.CurrentDelay()
+}
+
+func (r *router) CurrentTemp(ctx context.Context) float64 {
+	return r.next.CurrentTemp(ctx)
+}
+
+func (r *router) Init(ctx context.Context) error {
+	return r.next.Init(ctx)
+}
+
+func (r *router) Capacity() int {
+	return r.next.Capacity()
+}
+
+// NewRouter wraps the scraper finder with the additional sources selectable by the search queries.
+func NewRouter(next Finder, sources map[string]source.Source) Finder {
+	return &router{next: next, sources: sources}
+}
@@ -0,0 +1,76 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrWrongWindows = errors.New("windows must be duration:limit pairs separated by commas")
+
+// Window is the synthetic rate limit: at most Limit requests are served during any Duration.
+type Window struct {
+	Duration time.Duration
+	Limit    int
+}
+
+// ParseWindows reads the windows in the 15m:50,24h:500 form.
+func ParseWindows(data string) ([]Window, error) {
+	windows := make([]Window, 0)
+
+	for _, pair := range strings.Split(data, ",") {
+		duration, limit, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrWrongWindows, pair)
+		}
+
+		d, err := time.ParseDuration(duration)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrWrongWindows, err)
+		}
+
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrWrongWindows, err)
+		}
+
+		windows = append(windows, Window{Duration: d, Limit: l})
+	}
+
+	return windows, nil
+}
+
+// twitter is the rate limit model of the account, the throttled requests are not counted.
+type twitter struct {
+	windows []Window
+	served  []time.Time
+}
+
+func (t *twitter) allow(now time.Time) bool {
+	longest := time.Duration(0)
+
+	for _, w := range t.windows {
+		longest = max(longest, w.Duration)
+
+		count := 0
+
+		for i := len(t.served) - 1; i >= 0 && now.Sub(t.served[i]) < w.Duration; i-- {
+			count++
+		}
+
+		if count >= w.Limit {
+			return false
+		}
+	}
+
+	t.served = append(t.served, now)
+
+	// the requests older than the longest window never count again
+	for len(t.served) > 0 && now.Sub(t.served[0]) >= longest {
+		t.served = t.served[1:]
+	}
+
+	return true
+}
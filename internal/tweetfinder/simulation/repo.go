@@ -0,0 +1,157 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/clock"
+)
+
+var errLimitNotFound = errors.New("request limit not found")
+
+type limitKey struct {
+	id     string
+	window time.Duration
+}
+
+type requestLimit struct {
+	threshold uint64
+	requests  []time.Time
+}
+
+// memoryRepo keeps the request limits as the database does, but in the virtual time.
+type memoryRepo struct {
+	clock clock.Clock
+
+	mu     sync.Mutex
+	limits map[limitKey]*requestLimit
+}
+
+func (r *memoryRepo) AddCounters(_ context.Context, id string, window time.Duration, times []time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limit, err := r.get(id, window)
+	if err != nil {
+		return err
+	}
+
+	limit.requests = append(limit.requests, times...)
+
+	return nil
+}
+
+func (r *memoryRepo) CleanCounters(_ context.Context, id string, window time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limit, err := r.get(id, window)
+	if err != nil {
+		return err
+	}
+
+	for len(limit.requests) > 0 && r.clock.Since(limit.requests[0]) >= window {
+		limit.requests = limit.requests[1:]
+	}
+
+	return nil
+}
+
+func (r *memoryRepo) SetThreshold(_ context.Context, id string, window time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limit, err := r.get(id, window)
+	if err != nil {
+		return err
+	}
+
+	if count := r.count(limit, window); count > 0 {
+		limit.threshold = count
+	}
+
+	return nil
+}
+
+func (r *memoryRepo) GetRequestLimit(_ context.Context, id string, window time.Duration) (common.RequestLimitData, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limit, err := r.get(id, window)
+	if err != nil {
+		return common.RequestLimitData{}, err
+	}
+
+	return common.RequestLimitData{RequestsCount: r.count(limit, window), Threshold: limit.threshold}, nil
+}
+
+func (r *memoryRepo) CheckIfExist(_ context.Context, id string, window time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.limits[limitKey{id: id, window: window}]
+
+	return ok, nil
+}
+
+func (r *memoryRepo) Create(_ context.Context, id string, window time.Duration, threshold uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.limits[limitKey{id: id, window: window}] = &requestLimit{threshold: threshold}
+
+	return nil
+}
+
+func (r *memoryRepo) IncreaseThresholdTo(_ context.Context, id string, window time.Duration, threshold uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limit, err := r.get(id, window)
+	if err != nil {
+		return err
+	}
+
+	limit.threshold = max(limit.threshold, threshold)
+
+	return nil
+}
+
+func (r *memoryRepo) SetThresholdTo(_ context.Context, id string, window time.Duration, threshold uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limit, err := r.get(id, window)
+	if err != nil {
+		return err
+	}
+
+	limit.threshold = threshold
+
+	return nil
+}
+
+// count counts the requests within the window, the expired ones may be not cleaned yet.
+// The requests are added in the time order.
+func (r *memoryRepo) count(limit *requestLimit, window time.Duration) uint64 {
+	expired := sort.Search(len(limit.requests), func(i int) bool { return r.clock.Since(limit.requests[i]) < window })
+
+	return uint64(len(limit.requests) - expired)
+}
+
+func (r *memoryRepo) get(id string, window time.Duration) (*requestLimit, error) {
+	limit, ok := r.limits[limitKey{id: id, window: window}]
+	if !ok {
+		return nil, errLimitNotFound
+	}
+
+	return limit, nil
+}
+
+func newMemoryRepo(clock clock.Clock) *memoryRepo {
+	return &memoryRepo{clock: clock, limits: make(map[limitKey]*requestLimit)}
+}
@@ -0,0 +1,121 @@
+// Package simulation runs the delay manager with the window limiters of one account against the synthetic
+// Twitter rate limits in the virtual time, so the days of scraping are evaluated in seconds.
+package simulation
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/clock"
+)
+
+const (
+	login        = "simulated"
+	initialDelay = 15
+	pkgKey       = "pkg"
+)
+
+// start is fixed, so the runs with the same config are comparable
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type Config struct {
+	Duration time.Duration
+	Windows  []Window
+	Delay    *tweetfinder.DelayConfig
+	// SampleInterval is how often the state is written to the report.
+	SampleInterval time.Duration
+	// Settle is the real time given to the loops of the limiters and the manager after every request,
+	// they run in their own goroutines and catch up with the virtual time in it.
+	Settle time.Duration
+}
+
+type Report struct {
+	Served    int
+	Throttles int
+	Samples   []Sample
+}
+
+type Sample struct {
+	Elapsed   time.Duration
+	Delay     int64
+	Temp      float64
+	Served    int
+	Throttles int
+}
+
+// Run makes the requests of the account one after another with the delay set by the manager,
+// as the scraper does, till the virtual time reaches the duration.
+func Run(ctx context.Context, config Config, logger log.Logger) (*Report, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	virtual := clock.NewVirtual(start)
+	limiters := tweetfinder.NewWindowLimiters(virtual, login, newMemoryRepo(virtual), logger.WithField(pkgKey, "window_limiter"))
+
+	var delay atomic.Int64
+
+	delay.Store(initialDelay)
+
+	manager := newManager(virtual, config.Delay, func(seconds int64) { delay.Store(seconds) }, limiters, logger)
+	if err := manager.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	model := &twitter{windows: config.Windows}
+	report := &Report{Samples: make([]Sample, 0, config.Duration/config.SampleInterval+1)}
+	end := start.Add(config.Duration)
+
+	for next := start; virtual.Now().Before(end); {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if model.allow(virtual.Now()) {
+			report.Served++
+
+			manager.AfterRequest()
+		} else {
+			report.Throttles++
+
+			manager.TooManyRequests(ctx)
+		}
+
+		time.Sleep(config.Settle)
+
+		if !virtual.Now().Before(next) {
+			report.Samples = append(report.Samples, Sample{
+				Elapsed:   virtual.Since(start),
+				Delay:     delay.Load(),
+				Temp:      manager.CurrentTemp(ctx),
+				Served:    report.Served,
+				Throttles: report.Throttles,
+			})
+
+			next = next.Add(config.SampleInterval)
+		}
+
+		virtual.Advance(time.Duration(max(delay.Load(), 1)) * time.Second)
+	}
+
+	return report, nil
+}
+
+func newManager(
+	virtual clock.Clock,
+	config *tweetfinder.DelayConfig,
+	setter func(seconds int64),
+	limiters []tweetfinder.WindowLimiter,
+	logger log.Logger,
+) tweetfinder.Manager {
+	metrics := tweetfinder.NewDelayMetrics("", "")
+	logger = logger.WithField(pkgKey, "delay_manager")
+
+	if config.Controller == tweetfinder.ControllerAIMD {
+		return tweetfinder.NewDelayManagerAIMD(virtual, config, setter, limiters, nil, initialDelay, login, metrics, logger)
+	}
+
+	return tweetfinder.NewDelayManagerV2(virtual, setter, limiters, nil, initialDelay, login, metrics, logger)
+}
@@ -0,0 +1,67 @@
+package simulation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder"
+)
+
+func TestRun(t *testing.T) {
+	windows, err := ParseWindows("15m:50, 24h:500")
+	require.NoError(t, err)
+	require.Equal(t, []Window{{Duration: time.Minute * 15, Limit: 50}, {Duration: time.Hour * 24, Limit: 500}}, windows)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	for _, controller := range []string{tweetfinder.ControllerV2, tweetfinder.ControllerAIMD} {
+		t.Run(controller, func(t *testing.T) {
+			config := Config{
+				Duration: time.Hour * 2,
+				Windows:  windows,
+				Delay: &tweetfinder.DelayConfig{
+					Controller:   controller,
+					AIMDIncrease: 1,
+					AIMDDecrease: 0.5,
+					AIMDInterval: time.Minute,
+					AIMDMinRate:  0.5,
+					AIMDMaxRate:  30,
+				},
+				SampleInterval: time.Minute * 10,
+				Settle:         time.Microsecond * 10,
+			}
+
+			report, err := Run(context.Background(), config, log.NewLogger(logger))
+			require.NoError(t, err)
+
+			// the model never serves more than its limits
+			require.Positive(t, report.Served)
+			require.LessOrEqual(t, report.Served, 8*50)
+			require.NotEmpty(t, report.Samples)
+
+			for i := 1; i < len(report.Samples); i++ {
+				require.Greater(t, report.Samples[i].Elapsed, report.Samples[i-1].Elapsed)
+				require.GreaterOrEqual(t, report.Samples[i].Served, report.Samples[i-1].Served)
+			}
+
+			require.LessOrEqual(t, report.Samples[len(report.Samples)-1].Served, report.Served)
+		})
+	}
+}
+
+func TestTwitter(t *testing.T) {
+	model := &twitter{windows: []Window{{Duration: time.Minute, Limit: 2}}}
+
+	require.True(t, model.allow(start))
+	require.True(t, model.allow(start.Add(time.Second*30)))
+	require.False(t, model.allow(start.Add(time.Second*59)))
+	require.True(t, model.allow(start.Add(time.Minute)))
+	require.False(t, model.allow(start.Add(time.Minute+time.Second)))
+	require.True(t, model.allow(start.Add(time.Minute+time.Second*30)))
+}
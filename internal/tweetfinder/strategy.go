@@ -0,0 +1,102 @@
+package tweetfinder
+
+import "fmt"
+
+// Names of the finder selection strategies.
+const (
+	LeastTemperature   = "least_temperature"
+	WeightedRoundRobin = "weighted_round_robin"
+	QueryAffinity      = "query_affinity"
+)
+
+// candidate is the idle finder which is not overheated.
+type candidate struct {
+	index int
+	login string
+	temp  float64
+}
+
+// strategy picks the finder for the request, the request is the rendered query or empty for the single tweet.
+// It is called under the pool lock and returns the position in the candidates, which are never empty.
+type strategy interface {
+	Select(request string, candidates []candidate) int
+}
+
+func newStrategy(name string) (strategy, error) {
+	switch name {
+	case LeastTemperature:
+		return leastTemperature{}, nil
+	case WeightedRoundRobin:
+		return &weightedRoundRobin{current: make(map[string]float64)}, nil
+	case QueryAffinity:
+		return &queryAffinity{logins: make(map[string]string)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStrategy, name)
+	}
+}
+
+// leastTemperature picks the coolest finder.
+type leastTemperature struct{}
+
+func (leastTemperature) Select(_ string, candidates []candidate) int {
+	selected := 0
+
+	for i, c := range candidates {
+		if c.temp < candidates[selected].temp {
+			selected = i
+		}
+	}
+
+	return selected
+}
+
+// weightedRoundRobin is the smooth weighted round-robin, the cooler finder gets the bigger share of the requests
+// but the hot ones are not starved.
+type weightedRoundRobin struct {
+	current map[string]float64
+}
+
+func (s *weightedRoundRobin) Select(_ string, candidates []candidate) int {
+	selected, total := 0, 0.0
+
+	for i, c := range candidates {
+		weight := maxFinderTemp + 1 - c.temp
+		total += weight
+		s.current[c.login] += weight
+
+		if s.current[c.login] > s.current[candidates[selected].login] {
+			selected = i
+		}
+	}
+
+	s.current[candidates[selected].login] -= total
+
+	return selected
+}
+
+// queryAffinity keeps the pages of the query on the account which started it, the other requests go to the coolest finder.
+type queryAffinity struct {
+	leastTemperature
+	logins map[string]string
+}
+
+func (s *queryAffinity) Select(request string, candidates []candidate) int {
+	if request == "" {
+		return s.leastTemperature.Select(request, candidates)
+	}
+
+	login, ok := s.logins[request]
+	if ok {
+		for i, c := range candidates {
+			if c.login == login {
+				return i
+			}
+		}
+	}
+
+	// the account is busy, hot or gone, the query moves to another one
+	selected := s.leastTemperature.Select(request, candidates)
+	s.logins[request] = candidates[selected].login
+
+	return selected
+}
@@ -0,0 +1,114 @@
+package tweetfinder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+)
+
+type tempFinder struct {
+	Finder
+	temp float64
+}
+
+func (f *tempFinder) CurrentTemp(context.Context) float64 { return f.temp }
+
+func (f *tempFinder) CurrentDelay() int64 { return 1 }
+
+func TestStrategies(t *testing.T) {
+	candidates := []candidate{{index: 0, login: "alice", temp: 3}, {index: 1, login: "bob", temp: 1}, {index: 2, login: "carol", temp: 2}}
+
+	t.Run("least temperature", func(t *testing.T) {
+		s, err := newStrategy(LeastTemperature)
+		require.NoError(t, err)
+		require.Equal(t, 1, s.Select("", candidates))
+	})
+
+	t.Run("weighted round robin", func(t *testing.T) {
+		s, err := newStrategy(WeightedRoundRobin)
+		require.NoError(t, err)
+
+		counts := make(map[string]int)
+		for i := 0; i < 9; i++ {
+			counts[candidates[s.Select("", candidates)].login]++
+		}
+
+		// the weights are 2, 4 and 3
+		require.Equal(t, map[string]int{"alice": 2, "bob": 4, "carol": 3}, counts)
+	})
+
+	t.Run("query affinity", func(t *testing.T) {
+		s, err := newStrategy(QueryAffinity)
+		require.NoError(t, err)
+		require.Equal(t, 1, s.Select("btc", candidates))
+
+		// bob keeps the query although it is the hottest now
+		hot := []candidate{candidates[0], {index: 1, login: "bob", temp: 4}, candidates[2]}
+		require.Equal(t, 1, s.Select("btc", hot))
+
+		// bob is busy, the query moves to carol
+		require.Equal(t, 1, s.Select("btc", []candidate{candidates[0], candidates[2]}))
+		require.Equal(t, 2, s.Select("btc", candidates))
+		require.Equal(t, 1, s.Select("eth", hot[1:]))
+	})
+
+	_, err := newStrategy("random")
+	require.ErrorIs(t, err, ErrUnknownStrategy)
+}
+
+func TestPool_waitersFIFO(t *testing.T) {
+	ctx := context.Background()
+	config := &SelectionConfig{Strategy: LeastTemperature, WaitTimeout: time.Second, CoolDownCheck: time.Hour}
+	wait := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "wait_seconds"}, []string{"result"})
+
+	finder, err := NewPool(config, &LimitsConfig{Source: LimitsBoth}, &DelayConfig{Controller: ControllerV2}, &WarmUpConfig{}, nil, nil, wait, nil, nil, nil, nil, nil, nil, log.NewLogger(logrus.New()))
+	require.NoError(t, err)
+
+	p := finder.(*pool)
+	p.add(ctx, "alice", &tempFinder{temp: 1}, func() {})
+
+	_, index, err := p.getFinder(ctx, "")
+	require.NoError(t, err)
+
+	order := make(chan int, 2)
+
+	for i := 0; i < 2; i++ {
+		i := i
+
+		go func() {
+			_, index, err := p.getFinder(ctx, "")
+			require.NoError(t, err)
+
+			order <- i
+
+			p.releaseFinder(index)
+		}()
+
+		require.Eventually(t, func() bool {
+			p.mu.RLock()
+			defer p.mu.RUnlock()
+
+			return p.waiters.Len() == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	p.releaseFinder(index)
+
+	require.Equal(t, 0, <-order)
+	require.Equal(t, 1, <-order)
+
+	_, _, err = p.getFinder(ctx, "")
+	require.NoError(t, err)
+
+	_, _, err = p.getFinder(ctx, "")
+	require.ErrorIs(t, err, ErrTimeoutSelectFinder)
+	// the acquired and the timed out waits
+	require.Equal(t, 2, testutil.CollectAndCount(wait))
+}
@@ -0,0 +1,221 @@
+package tweetfinder
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/clock"
+)
+
+// fullRate is the rate the established accounts start with, the warm-up ends on it.
+const fullRate = float64(perMinute) / startDelay
+
+// warmUp caps the request rate of the new account and raises its limiter thresholds along the ramp. The rate grows
+// geometrically from the start rate to the full one with the age of the account, every throttle takes the penalty
+// off the age. The age and the throttles are kept in the account, so the ramp goes on after the restart.
+type warmUp struct {
+	config  *WarmUpConfig
+	clock   clock.Clock
+	login   string
+	addedAt time.Time
+	windows []time.Duration
+
+	mu           sync.Mutex
+	throttles    int
+	lastThrottle time.Time
+
+	// capped is set once the thresholds are set to the ramp, they are only raised after
+	capped bool
+
+	manager accountManager
+	repo    RequestLimitsRepo
+	metrics *DelayMetrics
+
+	log log.Logger
+}
+
+// Rate is the cap of the request rate in requests per minute, zero when the account is warmed up.
+func (w *warmUp) Rate() float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	age := w.clock.Since(w.addedAt) - time.Duration(w.throttles)*w.config.ThrottlePenalty
+	progress := max(float64(age)/float64(w.config.Duration), 0)
+
+	if progress >= 1 {
+		return 0
+	}
+
+	return w.config.StartRate * math.Pow(fullRate/w.config.StartRate, progress)
+}
+
+// Delay is the minimal delay of the account in seconds, zero when the account is warmed up.
+func (w *warmUp) Delay() int64 {
+	rate := w.Rate()
+	if rate == 0 {
+		return 0
+	}
+
+	return int64(math.Ceil(perMinute / rate))
+}
+
+// Throttled moves the ramp back, the throttles of the same burst are counted once.
+func (w *warmUp) Throttled(ctx context.Context) {
+	w.mu.Lock()
+
+	if !w.lastThrottle.IsZero() && w.clock.Since(w.lastThrottle) < w.config.Interval {
+		w.mu.Unlock()
+		return
+	}
+
+	w.lastThrottle = w.clock.Now()
+	w.mu.Unlock()
+
+	throttles, err := w.manager.AddWarmUpThrottle(ctx, w.login)
+	if err != nil {
+		w.log.WithError(err).Error("error while counting warm-up throttle")
+		return
+	}
+
+	w.mu.Lock()
+	w.throttles = throttles
+	w.mu.Unlock()
+
+	w.log.WithField("throttles", throttles).WithField(rateKey, w.Rate()).Info("warm-up moved back")
+}
+
+// loop follows the ramp, the delay is applied after every raise as the delay manager sets it only on its changes.
+func (w *warmUp) loop(ctx context.Context, applyDelay func()) {
+	ticker := w.clock.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		done := w.raise(ctx)
+		applyDelay()
+
+		if done {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// raise sets the limiter thresholds to the capped rate and reports the end of the warm-up. The first raise lowers
+// the thresholds left above the ramp too, the next ones only raise them, so the ones settled by a throttle hold
+// until the ramp passes them again.
+func (w *warmUp) raise(ctx context.Context) bool {
+	rate := w.Rate()
+	done := rate == 0
+
+	if done {
+		rate = fullRate
+	}
+
+	for _, window := range w.windows {
+		threshold := uint64(math.Ceil(rate * window.Minutes()))
+
+		if err := w.setThreshold(ctx, window, threshold); err != nil {
+			w.log.WithError(err).WithField("window", window).Error("error while raising warm-up threshold")
+			return false
+		}
+	}
+
+	w.capped = true
+
+	if done {
+		w.metrics.WarmUpRate.DeleteLabelValues(w.login)
+		w.log.Info("account warmed up")
+
+		return true
+	}
+
+	w.metrics.WarmUpRate.WithLabelValues(w.login).Set(rate)
+	w.log.WithField(rateKey, rate).Debug("warm-up rate")
+
+	return false
+}
+
+func (w *warmUp) setThreshold(ctx context.Context, window time.Duration, threshold uint64) error {
+	if !w.capped {
+		return w.repo.SetThresholdTo(ctx, w.login, window, threshold)
+	}
+
+	return w.repo.IncreaseThresholdTo(ctx, w.login, window, threshold)
+}
+
+// warmUpManager applies the warm-up to the delay manager: the delay is never below the warm-up one
+// and the throttles move the ramp back.
+type warmUpManager struct {
+	Manager
+	warmUp *warmUp
+	setter func(seconds int64)
+}
+
+func (m *warmUpManager) TooManyRequests(ctx context.Context) {
+	m.warmUp.Throttled(ctx)
+	m.Manager.TooManyRequests(ctx)
+}
+
+func (m *warmUpManager) CurrentDelay() int64 {
+	return max(m.Manager.CurrentDelay(), m.warmUp.Delay())
+}
+
+func (m *warmUpManager) Start(ctx context.Context) error {
+	if err := m.Manager.Start(ctx); err != nil {
+		return err
+	}
+
+	go m.warmUp.loop(ctx, m.applyDelay)
+
+	return nil
+}
+
+// applyDelay sets the current delay, so the scraper follows the ramp between the changes of the delay manager.
+func (m *warmUpManager) applyDelay() {
+	m.setter(m.CurrentDelay())
+}
+
+// newWarmUp returns nil when the account needs no warm-up: it is added before the warm-up or warmed up already.
+// The windows are the ones of the window limiters of the account, the thresholds are not raised without them.
+func newWarmUp(
+	config *WarmUpConfig,
+	clock clock.Clock,
+	account common.TwitterAccount,
+	windows []time.Duration,
+	manager accountManager,
+	repo RequestLimitsRepo,
+	metrics *DelayMetrics,
+	logger log.Logger,
+) *warmUp {
+	if config.Duration == 0 || account.AddedAt.IsZero() {
+		return nil
+	}
+
+	w := &warmUp{
+		config:    config,
+		clock:     clock,
+		login:     account.Login,
+		addedAt:   account.AddedAt,
+		windows:   windows,
+		throttles: account.WarmUpThrottles,
+		manager:   manager,
+		repo:      repo,
+		metrics:   metrics,
+		log:       logger,
+	}
+
+	if w.Rate() == 0 {
+		return nil
+	}
+
+	return w
+}
@@ -0,0 +1,103 @@
+package tweetfinder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/clock"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+type throttleCounter struct {
+	accountManager
+	throttles int
+}
+
+func (m *throttleCounter) AddWarmUpThrottle(context.Context, string) (int, error) {
+	m.throttles++
+	return m.throttles, nil
+}
+
+type fixedDelayManager struct {
+	Manager
+	delay int64
+}
+
+func (m fixedDelayManager) CurrentDelay() int64 { return m.delay }
+
+func TestWarmUp(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	virtual := clock.NewVirtual(start)
+	config := &WarmUpConfig{Duration: time.Hour * 100, StartRate: 0.5, ThrottlePenalty: time.Hour * 10, Interval: time.Minute * 10}
+	manager := &throttleCounter{}
+	repo := fdb.NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	windows := []time.Duration{time.Minute * 10}
+	logger := log.NewLogger(logrus.New())
+	account := common.TwitterAccount{Login: "alice", AddedAt: start}
+
+	require.Nil(t, newWarmUp(config, virtual, common.TwitterAccount{Login: "bob"}, windows, manager, repo, NewDelayMetrics("", ""), logger))
+	require.Nil(t, newWarmUp(&WarmUpConfig{}, virtual, account, windows, manager, repo, NewDelayMetrics("", ""), logger))
+
+	w := newWarmUp(config, virtual, account, windows, manager, repo, NewDelayMetrics("", ""), logger)
+	require.NotNil(t, w)
+	require.InDelta(t, 0.5, w.Rate(), 0.001)
+	require.Equal(t, int64(120), w.Delay())
+	var applied int64
+
+	m := &warmUpManager{Manager: fixedDelayManager{delay: startDelay}, warmUp: w, setter: func(seconds int64) { applied = seconds }}
+	require.Equal(t, int64(120), m.CurrentDelay())
+
+	// the delay follows the ramp without the changes of the delay manager
+	m.applyDelay()
+	require.Equal(t, int64(120), applied)
+
+	// the threshold is left above the ramp by the limiter before the restart
+	require.NoError(t, repo.Create(ctx, "alice", time.Minute*10, 100))
+
+	// the rate grows geometrically, the middle of the ramp is the geometric mean of the start and full rates
+	virtual.Advance(time.Hour * 50)
+	require.InDelta(t, 1.414, w.Rate(), 0.001)
+
+	// the burst of throttles moves the ramp back once
+	w.Throttled(ctx)
+	w.Throttled(ctx)
+	require.Equal(t, 1, manager.throttles)
+	require.InDelta(t, 1.149, w.Rate(), 0.001)
+
+	require.False(t, w.raise(ctx))
+
+	limit, err := repo.GetRequestLimit(ctx, "alice", time.Minute*10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(12), limit.Threshold)
+
+	m.applyDelay()
+	require.Equal(t, int64(53), applied)
+
+	// the next raises keep the higher threshold
+	require.NoError(t, repo.SetThresholdTo(ctx, "alice", time.Minute*10, 20))
+	require.False(t, w.raise(ctx))
+
+	limit, err = repo.GetRequestLimit(ctx, "alice", time.Minute*10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(20), limit.Threshold)
+
+	virtual.Advance(time.Hour * 60)
+	require.Zero(t, w.Rate())
+	require.Zero(t, w.Delay())
+	require.True(t, w.raise(ctx))
+
+	limit, err = repo.GetRequestLimit(ctx, "alice", time.Minute*10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(40), limit.Threshold)
+
+	m.applyDelay()
+	require.Equal(t, int64(startDelay), applied)
+}
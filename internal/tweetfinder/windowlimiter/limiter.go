@@ -2,16 +2,24 @@ package windowlimiter
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/lueurxax/crypto-tweet-sense/internal/common"
 	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/clock"
 )
 
 const (
 	queueLen     = 10
 	durationKey  = "duration"
 	thresholdKey = "threshold"
+
+	// the requests are written in batches, at least once per flush interval or once enough of them are pending
+	flushInterval = time.Second * 5
+	flushSize     = 100
+	// the expired requests are cleared about that many times per window
+	cleansInWindow = 60
 )
 
 type WindowLimiter interface {
@@ -30,7 +38,7 @@ type ResetLimiter interface {
 }
 
 type repo interface {
-	AddCounter(ctx context.Context, id string, window time.Duration, counterTime time.Time) error
+	AddCounters(ctx context.Context, id string, window time.Duration, times []time.Time) error
 	CleanCounters(ctx context.Context, id string, window time.Duration) error
 	SetThreshold(ctx context.Context, id string, window time.Duration) error
 	GetRequestLimit(ctx context.Context, id string, window time.Duration) (common.RequestLimitData, error)
@@ -42,40 +50,45 @@ type repo interface {
 type limiter struct {
 	id       string
 	duration time.Duration
+	clock    clock.Clock
 
 	fire             uint
-	putOutFireTicker *time.Ticker
+	putOutFireTicker clock.Ticker
 
 	resetLimiter  ResetLimiter
 	resetDuration time.Duration
-	resetTicker   *time.Ticker
+	resetTicker   clock.Ticker
+	flushTicker   clock.Ticker
+	lastClean     time.Time
 
 	count chan time.Time
+
+	// syncMu serializes the repo calls, so the stored limit is never reloaded in the middle of the flush
+	syncMu sync.Mutex
+	// mu guards the fire, the stored limit and the requests which are not flushed yet
+	mu      sync.Mutex
+	stored  common.RequestLimitData
+	pending []time.Time
+
 	repo
 
 	log log.Logger
 }
 
-func (l *limiter) Threshold(ctx context.Context) uint64 {
-	rl, err := l.repo.GetRequestLimit(ctx, l.id, l.duration)
-	if err != nil {
-		l.log.WithError(err).Error("error while getting threshold")
-		return 0
-	}
-
-	return rl.Threshold
+func (l *limiter) Threshold(context.Context) uint64 {
+	return l.state().Threshold
 }
 
-func (l *limiter) Temp(ctx context.Context) float64 {
-	rl, err := l.repo.GetRequestLimit(ctx, l.id, l.duration)
-	if err != nil {
-		l.log.WithError(err).Error("error while getting temp")
-		return 0
-	}
+// Temperature is the used share of the threshold capped at 2, the fire of the limiter is added to it on top.
+func Temperature(limit common.RequestLimitData) float64 {
+	return min((float64(limit.RequestsCount)+0.1)/float64(limit.Threshold), 2)
+}
 
-	temp := (float64(rl.RequestsCount) + 0.1) / float64(rl.Threshold)
+func (l *limiter) Temp(context.Context) float64 {
+	temp := Temperature(l.state())
 
-	temp = min(temp, 2)
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
 	if l.fire > 0 {
 		temp += float64(l.fire)
@@ -87,22 +100,32 @@ func (l *limiter) Temp(ctx context.Context) float64 {
 func (l *limiter) TrySetThreshold(ctx context.Context, startTime time.Time) error {
 	l.resetTicker.Reset(l.resetDuration)
 
-	if time.Since(startTime) > l.duration {
+	// the threshold is the stored count, so the pending requests are written first
+	l.flush(ctx)
+
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+
+	if l.clock.Since(startTime) > l.duration {
 		if err := l.SetThreshold(ctx, l.id, l.duration); err != nil {
 			return err
 		}
-	}
 
-	rl, err := l.repo.GetRequestLimit(ctx, l.id, l.duration)
-	if err == nil {
-		l.log.WithField(durationKey, l.duration).
-			WithField(thresholdKey, rl.Threshold).
-			WithField("current", rl.RequestsCount).
-			Debug("set threshold")
+		l.reload(ctx, 0)
 	}
 
+	rl := l.state()
+
+	l.log.WithField(durationKey, l.duration).
+		WithField(thresholdKey, rl.Threshold).
+		WithField("current", rl.RequestsCount).
+		Debug("set threshold")
+
 	l.putOutFireTicker.Reset(l.duration / 10)
+
+	l.mu.Lock()
 	l.fire++
+	l.mu.Unlock()
 
 	return nil
 }
@@ -111,11 +134,8 @@ func (l *limiter) Duration() time.Duration {
 	return l.duration
 }
 
-func (l *limiter) RecommendedDelay(ctx context.Context) (uint64, error) {
-	rl, err := l.repo.GetRequestLimit(ctx, l.id, l.duration)
-	if err != nil {
-		return 0, err
-	}
+func (l *limiter) RecommendedDelay(context.Context) (uint64, error) {
+	rl := l.state()
 
 	if rl.Threshold == 0 {
 		return 0, nil
@@ -136,6 +156,11 @@ func (l *limiter) RecommendedDelay(ctx context.Context) (uint64, error) {
 }
 
 func (l *limiter) Start(ctx context.Context, delay int64) error {
+	// the tickers are created before the loop, so the threshold is never set before the reset ticker exists
+	l.flushTicker = l.clock.NewTicker(flushInterval)
+	l.resetTicker = l.clock.NewTicker(l.resetDuration)
+	l.lastClean = l.clock.Now()
+
 	go l.loop(ctx)
 
 	isExist, err := l.repo.CheckIfExist(ctx, l.id, l.duration)
@@ -143,31 +168,34 @@ func (l *limiter) Start(ctx context.Context, delay int64) error {
 		return err
 	}
 
-	if isExist {
-		return nil
+	if !isExist {
+		threshold := uint64(l.duration.Seconds() / float64(delay))
+
+		if err = l.repo.Create(ctx, l.id, l.duration, threshold); err != nil {
+			return err
+		}
 	}
 
-	threshold := uint64(l.duration.Seconds() / float64(delay))
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+
+	l.reload(ctx, 0)
 
-	return l.repo.Create(ctx, l.id, l.duration, threshold)
+	return nil
 }
 
 func (l *limiter) Inc() {
-	l.count <- time.Now()
+	l.count <- l.clock.Now()
 }
 
-func (l *limiter) GetCurrent(ctx context.Context) (uint64, error) {
-	rl, err := l.repo.GetRequestLimit(ctx, l.id, l.duration)
-	if err != nil {
-		return 0, err
-	}
-
-	return rl.RequestsCount, nil
+func (l *limiter) GetCurrent(context.Context) (uint64, error) {
+	return l.state().RequestsCount, nil
 }
 
 func (l *limiter) loop(ctx context.Context) {
-	ticker := time.NewTicker(time.Second)
-	l.resetTicker = time.NewTicker(l.resetDuration)
+	defer l.flushTicker.Stop()
+	defer l.resetTicker.Stop()
+	defer l.putOutFireTicker.Stop()
 
 	l.log.WithField(durationKey, l.duration).Info("start loop")
 
@@ -175,33 +203,23 @@ func (l *limiter) loop(ctx context.Context) {
 		requestctx, cancel := context.WithCancel(context.Background())
 		select {
 		case <-ctx.Done():
+			l.drain()
+			// the context is done, the last requests are written with the detached one
+			l.flush(requestctx)
 			cancel()
+
 			return
 		case t := <-l.count:
 			l.log.WithField("time", t).Trace("inc counter")
 
-			isError := true
-			for isError {
-				if err := l.repo.AddCounter(requestctx, l.id, l.duration, t); err != nil {
-					l.log.WithError(err).Error("error while add counter")
-					continue
-				}
-
-				isError = false
-			}
-		case <-l.putOutFireTicker.C:
-			if l.fire > 0 {
-				l.log.WithField(durationKey, l.duration).Debug("put out fire")
-
-				l.fire--
-			}
-		case <-ticker.C:
-			l.log.WithField(durationKey, l.duration).Trace("clean counters")
-
-			if err := l.repo.CleanCounters(requestctx, l.id, l.duration); err != nil {
-				l.log.WithError(err).Error("error while cleaning counters")
+			if l.push(t) >= flushSize {
+				l.flush(requestctx)
 			}
-		case <-l.resetTicker.C:
+		case <-l.putOutFireTicker.C():
+			l.putOutFire()
+		case <-l.flushTicker.C():
+			l.flush(requestctx)
+		case <-l.resetTicker.C():
 			if l.resetLimiter == nil {
 				continue
 			}
@@ -211,23 +229,120 @@ func (l *limiter) loop(ctx context.Context) {
 				continue
 			}
 
+			l.syncMu.Lock()
+
 			if err := l.repo.IncreaseThresholdTo(requestctx, l.id, l.duration, threshold); err != nil {
 				panic(err)
 			}
 
+			l.reload(requestctx, 0)
+			l.syncMu.Unlock()
+
 			l.log.WithField(durationKey, l.duration).Trace("reset threshold")
 		}
 		cancel()
 	}
 }
 
+func (l *limiter) putOutFire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.fire > 0 {
+		l.log.WithField(durationKey, l.duration).Debug("put out fire")
+
+		l.fire--
+	}
+}
+
+// push queues the request for the next flush and returns the number of the pending requests.
+func (l *limiter) push(t time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pending = append(l.pending, t)
+
+	return len(l.pending)
+}
+
+func (l *limiter) drain() {
+	for {
+		select {
+		case t := <-l.count:
+			l.push(t)
+		default:
+			return
+		}
+	}
+}
+
+// flush writes the pending requests in one batch and reloads the stored limit. The failed batch stays pending,
+// so it is retried with the next flush.
+func (l *limiter) flush(ctx context.Context) {
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+
+	l.mu.Lock()
+	pending := append([]time.Time{}, l.pending...)
+	l.mu.Unlock()
+
+	if err := l.repo.AddCounters(ctx, l.id, l.duration, pending); err != nil {
+		l.log.WithError(err).WithField("pending", len(pending)).Error("error while add counters")
+		return
+	}
+
+	if l.clock.Since(l.lastClean) >= l.duration/cleansInWindow {
+		l.log.WithField(durationKey, l.duration).Trace("clean counters")
+
+		if err := l.repo.CleanCounters(ctx, l.id, l.duration); err != nil {
+			l.log.WithError(err).Error("error while cleaning counters")
+		}
+
+		l.lastClean = l.clock.Now()
+	}
+
+	l.reload(ctx, len(pending))
+}
+
+// reload reads the stored limit and drops the flushed requests from the pending ones, the caller holds syncMu.
+func (l *limiter) reload(ctx context.Context, flushed int) {
+	rl, err := l.repo.GetRequestLimit(ctx, l.id, l.duration)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pending = l.pending[flushed:]
+
+	if err != nil {
+		// the flushed requests are counted until the next reload
+		l.stored.RequestsCount += uint64(flushed)
+		l.log.WithError(err).Error("error while getting request limit")
+
+		return
+	}
+
+	l.stored = rl
+}
+
+// state is the stored limit with the pending requests counted.
+func (l *limiter) state() common.RequestLimitData {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rl := l.stored
+	rl.RequestsCount += uint64(len(l.pending))
+
+	return rl
+}
+
 func (l *limiter) SetResetLimiter(resetLimiter ResetLimiter) {
 	l.resetLimiter = resetLimiter
 }
 
-func NewLimiter(duration, resetDuration time.Duration, id string, repo repo, logger log.Logger) WindowLimiter {
+func NewLimiter(clock clock.Clock, duration, resetDuration time.Duration, id string, repo repo, logger log.Logger) WindowLimiter {
 	return &limiter{
-		putOutFireTicker: time.NewTicker(duration),
+		clock:            clock,
+		putOutFireTicker: clock.NewTicker(duration),
 		duration:         duration,
 		count:            make(chan time.Time, queueLen),
 		id:               id,
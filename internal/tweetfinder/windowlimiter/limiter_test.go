@@ -0,0 +1,123 @@
+package windowlimiter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/clock"
+)
+
+type countingRepo struct {
+	mu        sync.Mutex
+	writes    int
+	requests  []time.Time
+	threshold uint64
+}
+
+func (r *countingRepo) AddCounters(_ context.Context, _ string, _ time.Duration, times []time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(times) > 0 {
+		r.writes++
+		r.requests = append(r.requests, times...)
+	}
+
+	return nil
+}
+
+func (r *countingRepo) CleanCounters(context.Context, string, time.Duration) error {
+	return nil
+}
+
+func (r *countingRepo) SetThreshold(context.Context, string, time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.threshold = uint64(len(r.requests))
+
+	return nil
+}
+
+func (r *countingRepo) GetRequestLimit(context.Context, string, time.Duration) (common.RequestLimitData, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return common.RequestLimitData{RequestsCount: uint64(len(r.requests)), Threshold: r.threshold}, nil
+}
+
+func (r *countingRepo) CheckIfExist(context.Context, string, time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (r *countingRepo) Create(context.Context, string, time.Duration, uint64) error {
+	return nil
+}
+
+func (r *countingRepo) IncreaseThresholdTo(context.Context, string, time.Duration, uint64) error {
+	return nil
+}
+
+func (r *countingRepo) stats() (int, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.writes, len(r.requests)
+}
+
+func TestLimiter_flush(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	virtual := clock.NewVirtual(start)
+	repo := &countingRepo{threshold: 1000}
+
+	l := NewLimiter(virtual, time.Hour, time.Hour*24, "alice", repo, log.NewLogger(logrus.New())).(*limiter)
+	require.NoError(t, l.Start(ctx, 10))
+
+	for i := 0; i < 250; i++ {
+		l.Inc()
+	}
+
+	// the full batches are written right away, the rest waits for the flush ticker
+	require.Eventually(t, func() bool {
+		writes, requests := repo.stats()
+		return writes == 2 && requests == 200
+	}, time.Second, time.Millisecond)
+
+	current, err := l.GetCurrent(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(250), current)
+	require.Equal(t, uint64(1000), l.Threshold(ctx))
+
+	virtual.Advance(flushInterval)
+
+	require.Eventually(t, func() bool {
+		writes, requests := repo.stats()
+		return writes == 3 && requests == 250
+	}, time.Second, time.Millisecond)
+
+	// the threshold is set after the pending requests are written
+	l.Inc()
+
+	require.Eventually(t, func() bool { return l.state().RequestsCount == 251 }, time.Second, time.Millisecond)
+	require.NoError(t, l.TrySetThreshold(ctx, start.Add(-time.Hour*2)))
+	require.Equal(t, uint64(251), l.Threshold(ctx))
+
+	// the requests left on shutdown are written
+	l.Inc()
+	cancel()
+
+	require.Eventually(t, func() bool {
+		_, requests := repo.stats()
+		return requests == 252
+	}, time.Second, time.Millisecond)
+}
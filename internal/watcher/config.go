@@ -1,9 +1,12 @@
 package watcher
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
 )
 
 type Config struct {
@@ -11,6 +14,34 @@ type Config struct {
 	CleanInterval  time.Duration `envconfig:"CLEAN_INTERVAL" default:"10m"`
 	TooOld         time.Duration `envconfig:"TOO_OLD" default:"9h"`
 	SearchInterval time.Duration `envconfig:"SEARCH_INTERVAL" default:"1m"`
+	QueriesSync    time.Duration `envconfig:"QUERIES_SYNC_INTERVAL" default:"30s"`
+	HistoryKeepRaw time.Duration `envconfig:"HISTORY_KEEP_RAW" default:"1h"`
+	HistoryStep    time.Duration `envconfig:"HISTORY_STEP" default:"10m"`
+	HistoryMaxAge  time.Duration `envconfig:"HISTORY_MAX_AGE" default:"720h"`
+}
+
+func (c *Config) HistoryRetention() common.HistoryRetention {
+	return common.HistoryRetention{
+		KeepRaw: c.HistoryKeepRaw,
+		Step:    c.HistoryStep,
+		MaxAge:  c.HistoryMaxAge,
+	}
+}
+
+// SearchQueries builds the seed queries from the configured keywords, they are validated on load.
+func (c *Config) SearchQueries() ([]common.SearchQuery, error) {
+	queries := make([]common.SearchQuery, 0, len(c.Queries))
+
+	for _, text := range c.Queries {
+		query := common.KeywordQuery(text, text, c.SearchInterval)
+		if err := query.Validate(); err != nil {
+			return nil, fmt.Errorf("query %q: %w", text, err)
+		}
+
+		queries = append(queries, query)
+	}
+
+	return queries, nil
 }
 
 func GetConfig() *Config {
@@ -19,5 +50,9 @@ func GetConfig() *Config {
 		panic(err)
 	}
 
+	if _, err := cfg.SearchQueries(); err != nil {
+		panic(err)
+	}
+
 	return cfg
 }
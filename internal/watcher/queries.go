@@ -0,0 +1,135 @@
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+)
+
+type runningQuery struct {
+	query  common.SearchQuery
+	cancel context.CancelFunc
+}
+
+// seedQueries stores the queries from the config once, the queries managed through the diag API are kept after.
+func (w *watcher) seedQueries(ctx context.Context) error {
+	seeds, err := w.config.SearchQueries()
+	if err != nil {
+		return err
+	}
+
+	seeded, err := w.repo.SeedSearchQueries(ctx, seeds)
+	if err != nil || !seeded {
+		return err
+	}
+
+	w.logger.WithField("count", len(seeds)).Info("seeded search queries from config")
+
+	return nil
+}
+
+// watchQueries keeps the running searches in sync with the queries stored in the database.
+func (w *watcher) watchQueries(ctx context.Context) {
+	w.syncQueries(ctx)
+
+	ticker := time.NewTicker(w.config.QueriesSync)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		w.syncQueries(ctx)
+	}
+}
+
+// syncQueries stops removed, disabled and changed queries and starts the new ones.
+func (w *watcher) syncQueries(ctx context.Context) {
+	queries, err := w.repo.GetSearchQueries(ctx)
+	if err != nil {
+		w.logger.WithError(err).Error("get search queries")
+		return
+	}
+
+	wanted := make(map[string]common.SearchQuery, len(queries))
+
+	for _, query := range queries {
+		if !query.Enabled {
+			continue
+		}
+
+		if err = query.Validate(); err != nil {
+			w.logger.WithError(err).WithField(queryKey, query.Name).Warn("skip invalid search query")
+			continue
+		}
+
+		wanted[query.Name] = query
+	}
+
+	w.queriesMu.Lock()
+	defer w.queriesMu.Unlock()
+
+	for name, running := range w.queries {
+		if query, ok := wanted[name]; ok && query.Equal(running.query) {
+			continue
+		}
+
+		running.cancel()
+		delete(w.queries, name)
+
+		w.logger.WithField(queryKey, name).Info("stop search query")
+	}
+
+	for name, query := range wanted {
+		if _, ok := w.queries[name]; ok {
+			continue
+		}
+
+		queryCtx, cancel := context.WithCancel(ctx)
+		w.queries[name] = &runningQuery{query: query, cancel: cancel}
+
+		start := w.searchStart(ctx, name)
+		query := query
+
+		w.goTracked(func() { w.initSearchCursor(queryCtx, query, start) })
+
+		w.logger.WithField(queryKey, name).WithField(startKey, start).Info("start search query")
+	}
+}
+
+// searchStart continues the query from its watermark, so restarts leave no gaps. The latest stored cursor
+// is used for the queries searched before the watermark was stored.
+func (w *watcher) searchStart(ctx context.Context, name string) time.Time {
+	now := time.Now().UTC()
+	start := now.Add(-w.config.SearchInterval)
+
+	latest, err := w.repo.GetSearchWatermark(ctx, name)
+	if err != nil {
+		w.logger.WithError(err).Error("get search watermark")
+		return start
+	}
+
+	if latest.IsZero() {
+		cursors, err := w.repo.GetSearchCursors(ctx)
+		if err != nil {
+			w.logger.WithError(err).Error("get search cursors")
+			return start
+		}
+
+		for _, cursor := range cursors {
+			if cursor.Query == name && cursor.End.After(latest) {
+				latest = cursor.End
+			}
+		}
+	}
+
+	if latest.After(now.Add(-w.config.TooOld)) {
+		return latest
+	}
+
+	return start
+}
@@ -0,0 +1,25 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type Config struct {
+	MinInterval   time.Duration `envconfig:"MIN_INTERVAL" default:"30s"`
+	MaxInterval   time.Duration `envconfig:"MAX_INTERVAL" default:"30m"`
+	RetryInterval time.Duration `envconfig:"RETRY_INTERVAL" default:"1m"`
+	PollInterval  time.Duration `envconfig:"POLL_INTERVAL" default:"1s"`
+	// LeaseTimeout is the time the popped tweet stays out of the queue, it is rechecked again when not handled by then.
+	LeaseTimeout time.Duration `envconfig:"LEASE_TIMEOUT" default:"10m"`
+}
+
+func GetConfig() *Config {
+	cfg := new(Config)
+	if err := envconfig.Process("SCHEDULER", cfg); err != nil {
+		panic(err)
+	}
+
+	return cfg
+}
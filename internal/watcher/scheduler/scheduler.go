@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+)
+
+const idKey = "id"
+
+// Scheduler keeps tracked tweets in a persisted queue ordered by the time of the next recheck.
+type Scheduler interface {
+	// Schedule plans the next recheck of the tweet when its rating is expected to cross the top.
+	Schedule(ctx context.Context, tweet *common.TweetSnapshot, top float64) error
+	// Run feeds due tweets to the handler until ctx is done, never running more handlers than the finder capacity.
+	Run(ctx context.Context, handler func(ctx context.Context, id string) error)
+}
+
+type repo interface {
+	ScheduleRecheck(ctx context.Context, id string, at time.Time) error
+	LeaseDueRechecks(ctx context.Context, now time.Time, limit int, until time.Time) ([]string, error)
+	CompleteRecheck(ctx context.Context, id string, leasedUntil time.Time) error
+}
+
+type capacity interface {
+	Capacity() int
+}
+
+type scheduler struct {
+	repo     repo
+	capacity capacity
+	inFlight int32
+
+	config *Config
+	logger log.Logger
+}
+
+func (s *scheduler) Schedule(ctx context.Context, tweet *common.TweetSnapshot, top float64) error {
+	return s.repo.ScheduleRecheck(ctx, tweet.ID, s.next(tweet, top))
+}
+
+func (s *scheduler) Run(ctx context.Context, handler func(ctx context.Context, id string) error) {
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		free := s.capacity.Capacity() - int(atomic.LoadInt32(&s.inFlight))
+		if free <= 0 {
+			continue
+		}
+
+		now := time.Now()
+		until := now.Add(s.config.LeaseTimeout)
+
+		ids, err := s.repo.LeaseDueRechecks(ctx, now, free, until)
+		if err != nil {
+			s.logger.WithError(err).Error("lease due rechecks")
+			continue
+		}
+
+		for _, id := range ids {
+			atomic.AddInt32(&s.inFlight, 1)
+			wg.Add(1)
+
+			go func(id string) {
+				defer wg.Done()
+				defer atomic.AddInt32(&s.inFlight, -1)
+
+				s.handle(ctx, handler, id, until)
+			}(id)
+		}
+	}
+}
+
+// handle runs the handler on the leased tweet, the lease is completed on success and shortened to the retry
+// interval on failure. The tweet the handler failed on during shutdown keeps its lease and comes back when it ends.
+func (s *scheduler) handle(ctx context.Context, handler func(ctx context.Context, id string) error, id string, until time.Time) {
+	if err := handler(ctx, id); err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.logger.WithError(err).WithField(idKey, id).Error("recheck tweet")
+
+		if err = s.repo.ScheduleRecheck(ctx, id, time.Now().Add(s.config.RetryInterval)); err != nil {
+			s.logger.WithError(err).WithField(idKey, id).Error("reschedule failed recheck")
+		}
+
+		return
+	}
+
+	// the handled recheck is completed even during shutdown, otherwise it is repeated after the lease
+	if err := s.repo.CompleteRecheck(context.WithoutCancel(ctx), id, until); err != nil {
+		s.logger.WithError(err).WithField(idKey, id).Error("complete recheck")
+	}
+}
+
+// next returns the predicted top crossing time clamped to the configured intervals,
+// tweets which are not expected to reach the top are rechecked rarely in case the prediction was wrong.
+func (s *scheduler) next(tweet *common.TweetSnapshot, top float64) time.Time {
+	left, ok := tweet.RatingCurve.TimeToTop(tweet.CheckedAt.Sub(tweet.TimeParsed), top)
+
+	switch {
+	case !ok || left > s.config.MaxInterval:
+		left = s.config.MaxInterval
+	case left < s.config.MinInterval:
+		left = s.config.MinInterval
+	}
+
+	return tweet.CheckedAt.Add(left)
+}
+
+func NewScheduler(config *Config, repo repo, capacity capacity, logger log.Logger) Scheduler {
+	return &scheduler{
+		repo:     repo,
+		capacity: capacity,
+		config:   config,
+		logger:   logger,
+	}
+}
@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+)
+
+func Test_scheduler_next(t *testing.T) {
+	s := &scheduler{config: &Config{MinInterval: time.Minute, MaxInterval: time.Hour}}
+	checkedAt := time.Now()
+
+	tests := []struct {
+		name  string
+		curve common.RatingCurve
+		want  time.Duration
+	}{
+		{
+			name:  "predicted crossing",
+			curve: common.LinearCurve(1),
+			want:  10 * time.Minute,
+		},
+		{
+			name:  "soon crossing is clamped to min interval",
+			curve: common.LinearCurve(1.9),
+			want:  time.Minute,
+		},
+		{
+			name:  "far crossing is clamped to max interval",
+			curve: common.LinearCurve(0.01),
+			want:  time.Hour,
+		},
+		{
+			name:  "unreachable top",
+			curve: common.RatingCurve{Speed: 1, Limit: 100, Tau: time.Hour},
+			want:  time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tweet := &common.TweetSnapshot{
+				Tweet:       &common.Tweet{TimeParsed: checkedAt.Add(-10 * time.Minute)},
+				RatingCurve: tt.curve,
+				CheckedAt:   checkedAt,
+			}
+
+			assert.Equal(t, checkedAt.Add(tt.want), s.next(tweet, 1200))
+		})
+	}
+}
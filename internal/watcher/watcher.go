@@ -3,106 +3,161 @@ package watcher
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/lueurxax/crypto-tweet-sense/internal/common"
 	"github.com/lueurxax/crypto-tweet-sense/internal/log"
 	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder"
-	"github.com/lueurxax/crypto-tweet-sense/internal/watcher/singlell"
 )
 
 const (
-	tweetKey = "tweet"
 	timeout  = time.Minute * 1
 	queryKey = "query"
+	startKey = "start"
 
-	oldFastInterval    = time.Second * 5
-	oldFastHotInterval = time.Minute
+	// deep searches start when the cursor is old enough for tweets to gain rating
+	deepSearchDelayFactor = 10
+	// the deep search gives up after the empty pages in a row and retries the cursor later
+	maxEmptyPages = 3
 )
 
 type Watcher interface {
+	// Watch starts the watcher loops, they run until ctx is done or Stop is called.
 	Watch(ctx context.Context)
-}
-
-type singleLL[T any] interface {
-	Push(n T)
-	Pop() (T, bool)
+	// Stop cancels the loops and waits for in-flight searches and saves until ctx is done.
+	Stop(ctx context.Context) error
+	// Done is closed when every loop and in-flight search has finished.
+	Done() <-chan struct{}
 }
 
 type finder interface {
-	FindNext(ctx context.Context, start, end *time.Time, search, cursor string) ([]common.TweetSnapshot, string, error)
+	FindNext(ctx context.Context, query common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error)
 	Find(ctx context.Context, id string) (*common.TweetSnapshot, error)
 	IsHot() bool
+	Capacity() int
 }
 
 type repo interface {
 	Save(ctx context.Context, tweets []common.TweetSnapshot) error
 	DeleteTweet(ctx context.Context, id string) error
-	GetOldestTopReachableTweet(ctx context.Context, top float64) (*common.TweetSnapshot, int, error)
 	GetTweetsOlderThen(ctx context.Context, after time.Time) ([]string, error)
 	CheckIfSentTweetExist(ctx context.Context, link string) (bool, error)
 	SaveSentTweet(ctx context.Context, link string) error
 	SaveTweetForEdit(ctx context.Context, tweet *common.Tweet) error
+	CompactTweetHistory(ctx context.Context, retention common.HistoryRetention) error
+	SaveSearchCursor(ctx context.Context, cursor common.SearchCursor) error
+	GetSearchCursors(ctx context.Context) ([]common.SearchCursor, error)
+	DeleteSearchCursor(ctx context.Context, cursor common.SearchCursor) error
+	SaveSearchWatermark(ctx context.Context, query string, until time.Time) error
+	GetSearchWatermark(ctx context.Context, query string) (time.Time, error)
+	SeedSearchQueries(ctx context.Context, queries []common.SearchQuery) (bool, error)
+	GetSearchQueries(ctx context.Context) ([]common.SearchQuery, error)
 }
 
 type ratingChecker interface {
-	Check(ctx context.Context, tweet *common.TweetSnapshot) (bool, float64, error)
+	Check(ctx context.Context, tweet *common.TweetSnapshot) (bool, common.RatingCurve, error)
 	CurrentTop() float64
 }
 
-type doubleDelayer interface {
-	Duration(id string) time.Duration
+type scheduler interface {
+	Schedule(ctx context.Context, tweet *common.TweetSnapshot, top float64) error
+	Run(ctx context.Context, handler func(ctx context.Context, id string) error)
 }
 
-type searchRequest struct {
-	query  string
-	start  time.Time
-	cursor string
+type cursorKey struct {
+	query string
+	start int64
 }
 
 type watcher struct {
-	queries map[string]time.Time
+	queriesMu sync.Mutex
+	queries   map[string]*runningQuery
 
 	finder
 	repo
 	ratingChecker
-	doubleDelayer
-	singleLL singleLL[searchRequest]
+	scheduler scheduler
+
+	mu            sync.Mutex
+	activeCursors map[cursorKey]struct{}
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	done   chan struct{}
 
 	logger log.Logger
 	config *Config
 }
 
 func (w *watcher) Watch(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+
 	if err := w.cleanTooOldTweets(ctx); err != nil {
 		w.logger.WithError(err).Error("clean too old tweets")
 	}
 
-	for query := range w.queries {
-		go w.initSearchCursor(ctx, query)
+	if err := w.seedQueries(ctx); err != nil {
+		w.logger.WithError(err).Error("seed search queries")
+	}
+
+	w.goTracked(func() { w.watchQueries(ctx) })
+	w.goTracked(func() { w.scheduler.Run(ctx, w.updateTweet) })
+	w.goTracked(func() { w.cleanTooOld(ctx) })
+	w.goTracked(func() { w.searchAll(ctx) })
+
+	go func() {
+		w.wg.Wait()
+		close(w.done)
+		w.logger.Info("watcher stopped")
+	}()
+}
+
+func (w *watcher) Stop(ctx context.Context) error {
+	if w.cancel != nil {
+		w.cancel()
 	}
 
-	go w.updateOldestFast()
-	go w.cleanTooOld()
-	go w.searchAll(ctx)
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-func (w *watcher) search(ctx context.Context, obj searchRequest) {
-	w.logger.WithField(queryKey, obj.query).WithField("start", obj.start).Debug("searching")
+func (w *watcher) Done() <-chan struct{} {
+	return w.done
+}
+
+func (w *watcher) goTracked(f func()) {
+	w.wg.Add(1)
+
+	go func() {
+		defer w.wg.Done()
+
+		f()
+	}()
+}
+
+// search walks the cursor back to its start, the progress is stored after every page so it survives restarts.
+// The cursor is deleted only when the search reaches its start or the source runs out of pages.
+func (w *watcher) search(ctx context.Context, cursor common.SearchCursor) {
+	w.logger.WithField(queryKey, cursor.Query).WithField(startKey, cursor.Start).Debug("searching")
 
-	cursor := obj.cursor
 	firstTweet := time.Now().UTC()
+	emptyPages := 0
 
-	for obj.start.Before(firstTweet) {
-		tweets, nextCursor, err := w.finder.FindNext(ctx, nil, nil, obj.query, cursor)
+	for cursor.Start.Before(firstTweet) {
+		tweets, nextCursor, err := w.finder.FindNext(ctx, cursor.Search, cursor.Cursor)
 		if err != nil {
 			if errors.Is(err, tweetfinder.ErrNoTops) {
-				return
+				break
 			}
 
 			w.logger.WithError(err).Error("find tweets")
 
-			if errors.Is(err, tweetfinder.ErrTimeoutSelectFinder) || errors.Is(err, context.DeadlineExceeded) {
+			if ctx.Err() != nil || errors.Is(err, tweetfinder.ErrTimeoutSelectFinder) || errors.Is(err, context.DeadlineExceeded) {
 				return
 			}
 
@@ -115,43 +170,78 @@ func (w *watcher) search(ctx context.Context, obj searchRequest) {
 			if tweets[i].TimeParsed.Before(tmpTweet) {
 				tmpTweet = tweets[i].TimeParsed
 			}
-
-			tweets[i].RatingGrowSpeed = w.processTweet(ctx, &tweets[i])
 		}
 
-		if err = w.repo.Save(ctx, tweets); err != nil {
+		if err = w.save(ctx, w.processTweets(ctx, tweets)); err != nil {
 			w.logger.WithError(err).Error("save tweets")
+
+			if ctx.Err() != nil {
+				return
+			}
+
 			continue
 		}
 
 		firstTweet = tmpTweet
-		cursor = nextCursor
+
+		// sources other than the scraper run out of pages
+		if nextCursor == "" {
+			break
+		}
+
+		cursor.Cursor = nextCursor
+
+		if err = w.repo.SaveSearchCursor(context.WithoutCancel(ctx), cursor); err != nil {
+			w.logger.WithError(err).Error("save search cursor")
+		}
+
+		if len(tweets) > 0 {
+			emptyPages = 0
+			continue
+		}
+
+		if emptyPages++; emptyPages >= maxEmptyPages {
+			w.logger.WithField(queryKey, cursor.Query).WithField(startKey, cursor.Start).Warn("search paused on empty pages")
+			return
+		}
+	}
+
+	if err := w.repo.DeleteSearchCursor(ctx, cursor); err != nil {
+		w.logger.WithError(err).Error("delete search cursor")
 	}
 
-	w.logger.WithField("start", obj.start).WithField(queryKey, obj.query).Debug("watcher checked news")
+	w.logger.WithField(startKey, cursor.Start).WithField(queryKey, cursor.Query).Debug("watcher checked news")
 }
 
-func (w *watcher) processTweet(ctx context.Context, tweet *common.TweetSnapshot) float64 {
+// processTweet checks the tweet rating and sets its predicted rating curve, zero curve means no need to track the tweet.
+// The tweet failed to check is not saved, its stored curve is kept until the next check.
+func (w *watcher) processTweet(ctx context.Context, tweet *common.TweetSnapshot) error {
+	tweet.RatingCurve = common.RatingCurve{}
+	tweet.RatingGrowSpeed = 0
+
 	isExist, err := w.repo.CheckIfSentTweetExist(ctx, tweet.PermanentURL)
 	if err != nil {
 		w.logger.WithError(err).Error("check tweet if sent")
-		return 0
+		return err
 	}
 
 	if isExist {
-		return 0
+		return nil
 	}
 
-	ok, ratingSpeed, err := w.ratingChecker.Check(ctx, tweet)
+	ok, curve, err := w.ratingChecker.Check(ctx, tweet)
 	if err != nil {
 		w.logger.WithError(err).Error("check tweet")
-		return 0
+		return err
 	}
 
+	tweet.RatingCurve = curve
+	tweet.RatingGrowSpeed = curve.Speed
+
 	if ok {
 		if err = w.repo.SaveTweetForEdit(ctx, tweet.Tweet); err != nil {
 			w.logger.WithError(err).Error("save tweet for edit")
-			return ratingSpeed
+			return nil
 		}
 
 		w.logger.
@@ -164,45 +254,22 @@ func (w *watcher) processTweet(ctx context.Context, tweet *common.TweetSnapshot)
 		}
 	}
 
-	return ratingSpeed
+	return nil
 }
 
-func (w *watcher) updateOldestFast() {
-	tick := time.NewTicker(oldFastInterval)
-	for range tick.C {
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+// processTweets checks the tweets and returns the checked ones, the failed ones are left out of the save.
+func (w *watcher) processTweets(ctx context.Context, tweets []common.TweetSnapshot) []common.TweetSnapshot {
+	processed := make([]common.TweetSnapshot, 0, len(tweets))
 
-		id, count, err := w.updateOldestFastTweet(ctx)
-		if err != nil {
-			w.logger.WithError(err).Error("update oldest fast tweet")
-		}
-
-		cancel()
-
-		var resetInterval time.Duration
-
-		switch {
-		case count > 50:
-			resetInterval = time.Millisecond
-		case w.finder.IsHot():
-			resetInterval = oldFastHotInterval
-		default:
-			resetInterval = w.doubleDelayer.Duration(id)
+	for i := range tweets {
+		if err := w.processTweet(ctx, &tweets[i]); err != nil {
+			continue
 		}
 
-		tick.Reset(resetInterval)
+		processed = append(processed, tweets[i])
 	}
-}
 
-func (w *watcher) updateOldestFastTweet(ctx context.Context) (string, int, error) {
-	tweet, count, err := w.repo.GetOldestTopReachableTweet(ctx, w.ratingChecker.CurrentTop())
-	if err != nil {
-		return "", 0, err
-	}
-
-	w.logger.WithField(tweetKey, tweet).Debug("oldest fast tweet")
-
-	return tweet.ID, count, w.updateTweet(ctx, tweet.ID)
+	return processed
 }
 
 func (w *watcher) updateTweet(ctx context.Context, id string) error {
@@ -217,23 +284,65 @@ func (w *watcher) updateTweet(ctx context.Context, id string) error {
 		return err
 	}
 
-	tweet.RatingGrowSpeed = w.processTweet(ctx, tweet)
+	if err = w.processTweet(ctx, tweet); err != nil {
+		return err
+	}
+
 	if tweet.RatingGrowSpeed != 0 {
-		return w.repo.Save(ctx, []common.TweetSnapshot{*tweet})
+		return w.save(ctx, []common.TweetSnapshot{*tweet})
 	}
 
 	return w.repo.DeleteTweet(ctx, tweet.ID)
 }
 
-func (w *watcher) cleanTooOld() {
+// save stores the snapshots and plans the next recheck of the tweets which are still worth tracking.
+// Nothing is saved after shutdown has begun, the tweets may be checked on the cancelled context. The started save
+// is not interrupted, so the page is never stored partially.
+func (w *watcher) save(ctx context.Context, tweets []common.TweetSnapshot) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	if err := w.repo.Save(ctx, tweets); err != nil {
+		return err
+	}
+
+	for i := range tweets {
+		if tweets[i].RatingGrowSpeed <= 0 {
+			continue
+		}
+
+		if err := w.scheduler.Schedule(ctx, &tweets[i], w.ratingChecker.CurrentTop()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *watcher) cleanTooOld(ctx context.Context) {
 	tick := time.NewTicker(w.config.CleanInterval)
-	for range tick.C {
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+
+		cleanCtx, cancel := context.WithTimeout(ctx, timeout)
 
-		if err := w.cleanTooOldTweets(ctx); err != nil {
+		if err := w.cleanTooOldTweets(cleanCtx); err != nil {
 			w.logger.WithError(err).Error("clean too old tweets")
 		}
 
+		if err := w.repo.CompactTweetHistory(cleanCtx, w.config.HistoryRetention()); err != nil {
+			w.logger.WithError(err).Error("compact tweet history")
+		}
+
 		cancel()
 	}
 }
@@ -255,32 +364,53 @@ func (w *watcher) cleanTooOldTweets(ctx context.Context) error {
 	return nil
 }
 
-func (w *watcher) initSearchCursor(ctx context.Context, query string) {
-	start := time.Now().UTC().Add(-w.config.SearchInterval)
+func (w *watcher) initSearchCursor(ctx context.Context, query common.SearchQuery, start time.Time) {
+	interval := query.SearchInterval
+	if interval <= 0 {
+		interval = w.config.SearchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-	ticker := time.NewTicker(w.config.SearchInterval)
-	for range ticker.C {
-		w.logger.WithField(queryKey, query).WithField("start", start).Debug("init search cursor")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 
-		tweets, nextCursor, err := w.finder.FindNext(ctx, nil, nil, query, "")
+		w.logger.WithField(queryKey, query.Name).WithField(startKey, start).Debug("init search cursor")
+
+		end := time.Now().UTC()
+
+		tweets, nextCursor, err := w.finder.FindNext(ctx, query, "")
 		if err != nil {
 			w.logger.WithError(err).Error("find tweets")
 			continue
 		}
 
-		w.singleLL.Push(searchRequest{
-			query:  query,
-			start:  start,
-			cursor: nextCursor,
-		})
+		cursor := common.SearchCursor{
+			Query:  query.Name,
+			Search: query,
+			Start:  start,
+			End:    end,
+			Cursor: nextCursor,
+		}
 
-		start = time.Now().UTC()
+		if err = w.repo.SaveSearchCursor(context.WithoutCancel(ctx), cursor); err != nil {
+			w.logger.WithError(err).Error("save search cursor")
+			continue
+		}
 
-		for i := range tweets {
-			tweets[i].RatingGrowSpeed = w.processTweet(ctx, &tweets[i])
+		// the range is covered by the stored cursor, the next search of the query starts after it
+		if err = w.repo.SaveSearchWatermark(context.WithoutCancel(ctx), query.Name, end); err != nil {
+			w.logger.WithError(err).Error("save search watermark")
 		}
 
-		if err = w.repo.Save(ctx, tweets); err != nil {
+		start = end
+
+		if err = w.save(ctx, w.processTweets(ctx, tweets)); err != nil {
 			w.logger.WithError(err).Error("save tweets")
 			continue
 		}
@@ -288,45 +418,81 @@ func (w *watcher) initSearchCursor(ctx context.Context, query string) {
 }
 
 func (w *watcher) searchAll(ctx context.Context) {
-	var oldEnough = w.config.SearchInterval * 10
-
-	<-time.After(oldEnough)
+	oldEnough := w.config.SearchInterval * deepSearchDelayFactor
 
 	w.logger.Info("start search all")
 
-	nextObj, ok := w.singleLL.Pop()
-
-	ticker := time.NewTicker(time.Second)
-
-	for range ticker.C {
-		if !ok {
-			w.logger.Warn("empty singleLL")
+	ticker := time.NewTicker(w.config.SearchInterval)
+	defer ticker.Stop()
 
-			nextObj, ok = w.singleLL.Pop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 
+		cursors, err := w.repo.GetSearchCursors(ctx)
+		if err != nil {
+			w.logger.WithError(err).Error("get search cursors")
 			continue
 		}
 
-		if time.Now().Add(-oldEnough).After(nextObj.start) {
-			go w.search(ctx, nextObj)
-			nextObj, ok = w.singleLL.Pop()
+		for _, cursor := range cursors {
+			if cursor.Start.Before(time.Now().Add(-w.config.TooOld)) {
+				w.logger.WithField(queryKey, cursor.Query).WithField(startKey, cursor.Start).Warn("drop too old search cursor")
+
+				if err = w.repo.DeleteSearchCursor(ctx, cursor); err != nil {
+					w.logger.WithError(err).Error("delete search cursor")
+				}
+
+				continue
+			}
+
+			if time.Since(cursor.Start) < oldEnough || !w.activateCursor(cursor) {
+				continue
+			}
+
+			cursor := cursor
+
+			w.goTracked(func() {
+				defer w.deactivateCursor(cursor)
+
+				w.search(ctx, cursor)
+			})
 		}
 	}
 }
 
-func NewWatcher(config *Config, finder finder, repo repo, checker ratingChecker, doubleDelayer doubleDelayer, logger log.Logger) Watcher {
-	start := time.Now().Add(config.SearchInterval)
+// activateCursor marks the cursor as being searched, false means it is already in progress.
+func (w *watcher) activateCursor(cursor common.SearchCursor) bool {
+	key := cursorKey{query: cursor.Query, start: cursor.Start.UnixNano()}
 
-	queries := make(map[string]time.Time, len(config.Queries))
-	for _, query := range config.Queries {
-		queries[query] = start
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.activeCursors[key]; ok {
+		return false
 	}
 
+	w.activeCursors[key] = struct{}{}
+
+	return true
+}
+
+func (w *watcher) deactivateCursor(cursor common.SearchCursor) {
+	w.mu.Lock()
+	delete(w.activeCursors, cursorKey{query: cursor.Query, start: cursor.Start.UnixNano()})
+	w.mu.Unlock()
+}
+
+func NewWatcher(config *Config, finder finder, repo repo, checker ratingChecker, scheduler scheduler, logger log.Logger) Watcher {
 	return &watcher{
-		singleLL:      singlell.New[searchRequest](),
 		config:        config,
-		doubleDelayer: doubleDelayer,
-		queries:       queries,
+		scheduler:     scheduler,
+		queries:       make(map[string]*runningQuery),
+		activeCursors: make(map[cursorKey]struct{}),
+		done:          make(chan struct{}),
 		finder:        finder,
 		repo:          repo,
 		ratingChecker: checker,
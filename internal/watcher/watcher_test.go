@@ -0,0 +1,305 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lueurxax/crypto-tweet-sense/internal/backfill"
+	"github.com/lueurxax/crypto-tweet-sense/internal/common"
+	"github.com/lueurxax/crypto-tweet-sense/internal/log"
+	"github.com/lueurxax/crypto-tweet-sense/internal/predictor"
+	"github.com/lueurxax/crypto-tweet-sense/internal/ratingcollector"
+	fdb "github.com/lueurxax/crypto-tweet-sense/internal/repo"
+	"github.com/lueurxax/crypto-tweet-sense/internal/tweetfinder"
+	recheck "github.com/lueurxax/crypto-tweet-sense/internal/watcher/scheduler"
+	"github.com/lueurxax/crypto-tweet-sense/pkg/fdbclient"
+)
+
+type blockingFinder struct {
+	calls int32
+}
+
+// FindNext blocks until the request is cancelled, like a finder waiting for a free account.
+func (f *blockingFinder) FindNext(ctx context.Context, _ common.SearchQuery, _ string) ([]common.TweetSnapshot, string, error) {
+	atomic.AddInt32(&f.calls, 1)
+	<-ctx.Done()
+
+	return nil, "", ctx.Err()
+}
+
+func (f *blockingFinder) Find(ctx context.Context, _ string) (*common.TweetSnapshot, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (f *blockingFinder) IsHot() bool {
+	return false
+}
+
+func (f *blockingFinder) Capacity() int {
+	return 1
+}
+
+func TestWatcher_Stop(t *testing.T) {
+	logger := log.NewLogger(logrus.New())
+	repo := fdb.NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	finder := new(blockingFinder)
+
+	w := NewWatcher(
+		&Config{
+			Queries:        []string{"btc"},
+			CleanInterval:  time.Millisecond * 10,
+			TooOld:         time.Hour,
+			SearchInterval: time.Millisecond * 10,
+			QueriesSync:    time.Millisecond * 10,
+		},
+		finder,
+		repo,
+		ratingcollector.NewChecker(repo, predictor.NewLinear(), 1000),
+		recheck.NewScheduler(&recheck.Config{PollInterval: time.Millisecond * 10, LeaseTimeout: time.Minute}, repo, finder, logger),
+		logger,
+	)
+
+	w.Watch(context.Background())
+
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&finder.calls) > 0 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, w.Stop(ctx))
+
+	select {
+	case <-w.Done():
+	default:
+		t.Fatal("watcher is not done after stop")
+	}
+}
+
+func TestWatcher_syncQueries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := log.NewLogger(logrus.New())
+	repo := fdb.NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+
+	w := NewWatcher(
+		&Config{Queries: []string{"btc", "eth"}, TooOld: time.Hour, SearchInterval: time.Hour},
+		new(blockingFinder),
+		repo,
+		nil,
+		nil,
+		logger,
+	).(*watcher)
+
+	running := func() []string {
+		w.queriesMu.Lock()
+		defer w.queriesMu.Unlock()
+
+		names := make([]string, 0, len(w.queries))
+		for name := range w.queries {
+			names = append(names, name)
+		}
+
+		return names
+	}
+
+	require.NoError(t, w.seedQueries(ctx))
+	w.syncQueries(ctx)
+	require.ElementsMatch(t, []string{"btc", "eth"}, running())
+
+	require.NoError(t, repo.SaveSearchQuery(ctx, common.SearchQuery{Name: "eth", Keywords: []string{"ethereum"}}))
+	require.NoError(t, repo.SaveSearchQuery(ctx, common.SearchQuery{Name: "sol", Keywords: []string{"solana"}, Enabled: true}))
+	w.syncQueries(ctx)
+	require.ElementsMatch(t, []string{"btc", "sol"}, running())
+
+	require.NoError(t, repo.DeleteSearchQuery(ctx, "btc"))
+	w.syncQueries(ctx)
+	require.ElementsMatch(t, []string{"sol"}, running())
+
+	// the config queries are not seeded again after the operator deletes every query
+	require.NoError(t, repo.DeleteSearchQuery(ctx, "eth"))
+	require.NoError(t, repo.DeleteSearchQuery(ctx, "sol"))
+	require.NoError(t, w.seedQueries(ctx))
+
+	queries, err := repo.GetSearchQueries(ctx)
+	require.NoError(t, err)
+	require.Empty(t, queries)
+
+	cancel()
+	w.wg.Wait()
+}
+
+func TestWatcher_Replay(t *testing.T) {
+	recording, err := os.Open("../../test/fixtures/finder_replay.ndjson")
+	require.NoError(t, err)
+
+	defer recording.Close()
+
+	records, err := tweetfinder.ReadRecords(recording)
+	require.NoError(t, err)
+
+	logger := log.NewLogger(logrus.New())
+	repo := fdb.NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	finder := tweetfinder.NewReplayFinder(records, tweetfinder.ReplayConfig{Rebase: true})
+
+	w := NewWatcher(
+		&Config{
+			Queries:        []string{"bitcoin"},
+			CleanInterval:  time.Hour,
+			TooOld:         time.Hour,
+			SearchInterval: time.Millisecond * 10,
+			QueriesSync:    time.Hour,
+		},
+		finder,
+		repo,
+		ratingcollector.NewChecker(repo, predictor.NewLinear(), 1000),
+		recheck.NewScheduler(&recheck.Config{MinInterval: time.Millisecond, MaxInterval: time.Millisecond * 10, PollInterval: time.Millisecond, LeaseTimeout: time.Minute}, repo, finder, logger),
+		logger,
+	)
+
+	w.Watch(context.Background())
+
+	ctx := context.Background()
+
+	require.Eventually(t, func() bool {
+		history, err := repo.GetTweetHistory(ctx, "1001")
+		return err == nil && len(history) >= 3
+	}, 5*time.Second, time.Millisecond*10)
+
+	stopCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	require.NoError(t, w.Stop(stopCtx))
+
+	history, err := repo.GetTweetHistory(ctx, "1001")
+	require.NoError(t, err)
+	require.Equal(t, 40, history[0].Likes)
+	require.Equal(t, 160, history[len(history)-1].Likes)
+
+	ids, err := repo.GetTweetsOlderThen(ctx, time.Now())
+	require.NoError(t, err)
+	require.NotContains(t, ids, "1003")
+}
+
+type snapshotFinder struct {
+	blockingFinder
+	tweet common.TweetSnapshot
+}
+
+func (f *snapshotFinder) Find(context.Context, string) (*common.TweetSnapshot, error) {
+	tweet := f.tweet
+	return &tweet, nil
+}
+
+// contextChecker fails on the cancelled context like the checkers reading the database.
+type contextChecker struct{}
+
+func (contextChecker) Check(ctx context.Context, _ *common.TweetSnapshot) (bool, common.RatingCurve, error) {
+	if err := ctx.Err(); err != nil {
+		return false, common.RatingCurve{}, err
+	}
+
+	return false, common.LinearCurve(1), nil
+}
+
+func (contextChecker) CurrentTop() float64 {
+	return 1000
+}
+
+func TestWatcher_updateTweetOnShutdown(t *testing.T) {
+	logger := log.NewLogger(logrus.New())
+	repo := fdb.NewDBFromClient(fdbclient.NewMemoryDatabase(), logrus.NewEntry(logrus.New()))
+	now := time.Now().UTC()
+	finder := &snapshotFinder{tweet: common.TweetSnapshot{
+		Tweet:     &common.Tweet{ID: "1", TimeParsed: now.Add(-time.Hour)},
+		CheckedAt: now,
+	}}
+
+	w := NewWatcher(
+		&Config{TooOld: time.Hour, SearchInterval: time.Hour},
+		finder,
+		repo,
+		contextChecker{},
+		recheck.NewScheduler(&recheck.Config{MaxInterval: time.Hour}, repo, finder, logger),
+		logger,
+	).(*watcher)
+
+	require.NoError(t, w.updateTweet(context.Background(), "1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	finder.tweet.CheckedAt = now.Add(time.Minute)
+	require.ErrorIs(t, w.updateTweet(ctx, "1"), context.Canceled)
+
+	// the tweet checked on the cancelled context keeps its stored snapshot
+	history, err := repo.GetTweetHistory(context.Background(), "1")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+}
+
+// pastFinder finds the tweet on the first page of every search.
+type pastFinder struct {
+	blockingFinder
+	tweet common.TweetSnapshot
+}
+
+func (f *pastFinder) FindNext(_ context.Context, _ common.SearchQuery, cursor string) ([]common.TweetSnapshot, string, error) {
+	if cursor != "" {
+		return nil, "", nil
+	}
+
+	tweet := f.tweet
+
+	return []common.TweetSnapshot{tweet}, "", nil
+}
+
+func TestWatcher_cleanTooOldTweetsKeepsBackfill(t *testing.T) {
+	ctx := context.Background()
+	logger := log.NewLogger(logrus.New())
+	client := fdbclient.NewMemoryDatabase()
+	repo := fdb.NewDBFromClient(client, logrus.NewEntry(logrus.New()))
+	past := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := time.Now().UTC()
+
+	finder := &pastFinder{tweet: common.TweetSnapshot{Tweet: &common.Tweet{ID: "past", TimeParsed: past}, CheckedAt: now}}
+	backfiller := backfill.NewBackfiller(&backfill.Config{Slice: time.Hour * 24, Workers: 1, RetryInterval: time.Millisecond}, finder, repo, logger)
+
+	query := common.KeywordQuery("btc", "bitcoin", time.Minute)
+	require.NoError(t, backfiller.Run(ctx, []common.SearchQuery{query}, past, past.Add(time.Hour)))
+
+	require.NoError(t, repo.Save(ctx, []common.TweetSnapshot{{
+		Tweet:     &common.Tweet{ID: "old", TimeParsed: now.Add(-time.Hour * 2)},
+		CheckedAt: now,
+	}}))
+
+	w := NewWatcher(&Config{TooOld: time.Hour, SearchInterval: time.Hour}, finder, repo, nil, nil, logger).(*watcher)
+
+	require.NoError(t, w.cleanTooOldTweets(ctx))
+
+	count, err := fdb.NewDBFromClient(client, logrus.NewEntry(logrus.New())).Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+
+	// the backfilled tweet found by the watcher again is still kept
+	require.NoError(t, repo.Save(ctx, []common.TweetSnapshot{{
+		Tweet:     &common.Tweet{ID: "past", TimeParsed: past},
+		CheckedAt: now.Add(time.Minute),
+	}}))
+	require.NoError(t, w.cleanTooOldTweets(ctx))
+
+	ids, err := repo.GetTweetsOlderThen(ctx, now)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	count, err = fdb.NewDBFromClient(client, logrus.NewEntry(logrus.New())).Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+}
@@ -0,0 +1,131 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Virtual is the clock which moves only on Advance. Unlike the real tickers its tickers never drop a tick:
+// every tick is handed to the receiver before the time moves past it, so the loops keep pace with the virtual time.
+type Virtual struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*virtualTicker
+}
+
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.now
+}
+
+func (v *Virtual) Since(t time.Time) time.Duration {
+	return v.Now().Sub(t)
+}
+
+func (v *Virtual) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	t := &virtualTicker{clock: v, c: make(chan time.Time), period: d, next: v.now.Add(d), done: make(chan struct{})}
+	v.tickers = append(v.tickers, t)
+
+	return t
+}
+
+// Advance moves the time forward by d firing the due ticks in their order. It blocks until every tick is received,
+// the ticker must be stopped when its loop exits.
+func (v *Virtual) Advance(d time.Duration) {
+	v.mu.Lock()
+	target := v.now.Add(d)
+	v.mu.Unlock()
+
+	for {
+		v.mu.Lock()
+
+		due := v.due(target)
+		if due == nil {
+			v.now = target
+			v.mu.Unlock()
+
+			return
+		}
+
+		v.now = due.next
+		due.next = due.next.Add(due.period)
+		now, done := v.now, due.done
+
+		v.mu.Unlock()
+
+		select {
+		case due.c <- now:
+		case <-done:
+		}
+	}
+}
+
+// due returns the running ticker which fires first not later than the target, it must be called under the lock.
+func (v *Virtual) due(target time.Time) *virtualTicker {
+	var first *virtualTicker
+
+	for _, t := range v.tickers {
+		if t.stopped || t.next.After(target) {
+			continue
+		}
+
+		if first == nil || t.next.Before(first.next) {
+			first = t
+		}
+	}
+
+	return first
+}
+
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{now: start}
+}
+
+type virtualTicker struct {
+	clock   *Virtual
+	c       chan time.Time
+	period  time.Duration
+	next    time.Time
+	stopped bool
+	done    chan struct{}
+}
+
+func (t *virtualTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *virtualTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	if t.stopped {
+		t.stopped = false
+		t.done = make(chan struct{})
+	}
+
+	t.period = d
+	t.next = t.clock.now.Add(d)
+}
+
+func (t *virtualTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	if !t.stopped {
+		t.stopped = true
+		close(t.done)
+	}
+}
@@ -0,0 +1,60 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVirtual(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := NewVirtual(start)
+
+	fast := v.NewTicker(time.Second * 10)
+	slow := v.NewTicker(time.Second * 25)
+
+	ticks := make(chan string, 10)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 6; i++ {
+			select {
+			case tick := <-fast.C():
+				ticks <- "fast " + tick.Sub(start).String()
+			case tick := <-slow.C():
+				ticks <- "slow " + tick.Sub(start).String()
+			}
+		}
+
+		fast.Stop()
+		slow.Stop()
+	}()
+
+	v.Advance(time.Minute)
+	<-done
+
+	require.Equal(t, start.Add(time.Minute), v.Now())
+	require.Equal(t, time.Second*5, v.Since(start.Add(time.Second*55)))
+
+	close(ticks)
+
+	got := make([]string, 0, 6)
+	for tick := range ticks {
+		got = append(got, tick)
+	}
+
+	// the ticks due at the same time fire in the order the tickers were created
+	require.Equal(t, []string{"fast 10s", "fast 20s", "slow 25s", "fast 30s", "fast 40s", "fast 50s"}, got)
+
+	// the stopped tickers do not block the time
+	v.Advance(time.Hour)
+
+	fast.Reset(time.Minute)
+
+	go v.Advance(time.Minute)
+
+	require.Equal(t, start.Add(time.Hour+time.Minute*2), <-fast.C())
+}
@@ -0,0 +1,220 @@
+// Package envelope encrypts values with the envelope scheme: every value is sealed with its own random data key
+// by AES-256-GCM, the data key is sealed with the key encryption key of the keyring and stored next to the value.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// KeySize is the size of the key encryption keys and of the data keys, AES-256.
+	KeySize = 32
+
+	// prefix marks the sealed text, values without it are read as plain text
+	prefix  = "enc:v1:"
+	idLimit = 255
+)
+
+var (
+	ErrWrongKeySize   = errors.New("envelope key must be 32 bytes")
+	ErrWrongKeyID     = errors.New("envelope key id must be 1-255 characters without ':' and ','")
+	ErrUnknownKey     = errors.New("unknown envelope key")
+	ErrNoPrimaryKey   = errors.New("primary envelope key is not in the keyring")
+	ErrMalformed      = errors.New("malformed envelope")
+	ErrWrongKeyFormat = errors.New("envelope keys must be id:base64 pairs separated by commas or new lines")
+)
+
+// Keyring holds the key encryption keys by id. The primary key seals new values, the others are kept to open
+// the values sealed before the rotation.
+type Keyring struct {
+	keys    map[string]cipher.AEAD
+	primary string
+}
+
+func NewKeyring(keys map[string][]byte, primary string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD, len(keys)), primary: primary}
+
+	for id, key := range keys {
+		if id == "" || len(id) > idLimit || strings.ContainsAny(id, ":,") {
+			return nil, fmt.Errorf("%w: %q", ErrWrongKeyID, id)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+
+		k.keys[id] = aead
+	}
+
+	if _, ok := k.keys[primary]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoPrimaryKey, primary)
+	}
+
+	return k, nil
+}
+
+// ParseKeys reads the keys in the id:base64 form separated by commas or new lines, empty lines and # comments are skipped.
+func ParseKeys(data string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+
+	for _, line := range strings.FieldsFunc(data, func(r rune) bool { return r == ',' || r == '\n' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, ErrWrongKeyFormat
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %s: %w", ErrWrongKeyFormat, id, err)
+		}
+
+		keys[strings.TrimSpace(id)] = key
+	}
+
+	return keys, nil
+}
+
+// Seal encrypts the plain text with the primary key. The context is authenticated but not stored,
+// the value opens only with the same context, so sealed values cannot be swapped between records.
+func (k *Keyring) Seal(plain, context []byte) (string, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	kek := k.keys[k.primary]
+
+	buf := make([]byte, 0, 1+len(k.primary)+2*kek.NonceSize()+KeySize+kek.Overhead()+len(plain)+data.Overhead())
+	buf = append(buf, byte(len(k.primary)))
+	buf = append(buf, k.primary...)
+
+	if buf, err = seal(kek, buf, dataKey, []byte(k.primary)); err != nil {
+		return "", err
+	}
+
+	if buf, err = seal(data, buf, plain, context); err != nil {
+		return "", err
+	}
+
+	return prefix + base64.RawStdEncoding.EncodeToString(buf), nil
+}
+
+// Open decrypts the sealed text, the plain text written before the encryption was enabled is returned as is.
+func (k *Keyring) Open(text string, context []byte) ([]byte, error) {
+	if !IsSealed(text) {
+		return []byte(text), nil
+	}
+
+	buf, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(text, prefix))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+
+	id, buf, err := keyID(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	kek, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+
+	dataKey, buf, err := open(kek, buf, KeySize, []byte(id))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, _, err := open(data, buf, len(buf)-data.NonceSize()-data.Overhead(), context)
+
+	return plain, err
+}
+
+// NeedsRotation tells whether the value is plain or sealed with a key other than the primary one.
+func (k *Keyring) NeedsRotation(text string) bool {
+	if !IsSealed(text) {
+		return true
+	}
+
+	buf, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(text, prefix))
+	if err != nil {
+		return true
+	}
+
+	id, _, err := keyID(buf)
+
+	return err != nil || id != k.primary
+}
+
+func IsSealed(text string) bool {
+	return strings.HasPrefix(text, prefix)
+}
+
+func keyID(buf []byte) (string, []byte, error) {
+	if len(buf) == 0 || len(buf) < 1+int(buf[0]) {
+		return "", nil, ErrMalformed
+	}
+
+	return string(buf[1 : 1+buf[0]]), buf[1+buf[0]:], nil
+}
+
+func seal(aead cipher.AEAD, dst, plain, context []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	dst = append(dst, nonce...)
+
+	return aead.Seal(dst, nonce, plain, context), nil
+}
+
+// open decrypts the sealed value of the plain size from the head of the buffer and returns the rest.
+func open(aead cipher.AEAD, buf []byte, size int, context []byte) ([]byte, []byte, error) {
+	length := aead.NonceSize() + size + aead.Overhead()
+	if size < 0 || len(buf) < length {
+		return nil, nil, ErrMalformed
+	}
+
+	plain, err := aead.Open(nil, buf[:aead.NonceSize()], buf[aead.NonceSize():length], context)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+
+	return plain, buf[length:], nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrWrongKeySize
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,102 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestKeyring(t *testing.T) {
+	old, err := NewKeyring(map[string][]byte{"k1": testKey(1)}, "k1")
+	require.NoError(t, err)
+
+	sealed, err := old.Seal([]byte("secret"), []byte("alice"))
+	require.NoError(t, err)
+	require.True(t, IsSealed(sealed))
+	require.NotContains(t, sealed, "secret")
+
+	plain, err := old.Open(sealed, []byte("alice"))
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(plain))
+
+	// the value sealed for another record does not open
+	_, err = old.Open(sealed, []byte("bob"))
+	require.ErrorIs(t, err, ErrMalformed)
+
+	// the plain value written before the encryption is read as is
+	plain, err = old.Open("secret", []byte("alice"))
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(plain))
+	require.True(t, old.NeedsRotation("secret"))
+	require.False(t, old.NeedsRotation(sealed))
+
+	rotated, err := NewKeyring(map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, "k2")
+	require.NoError(t, err)
+	require.True(t, rotated.NeedsRotation(sealed))
+
+	plain, err = rotated.Open(sealed, []byte("alice"))
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(plain))
+
+	resealed, err := rotated.Seal(plain, []byte("alice"))
+	require.NoError(t, err)
+	require.False(t, rotated.NeedsRotation(resealed))
+
+	_, err = old.Open(resealed, []byte("alice"))
+	require.ErrorIs(t, err, ErrUnknownKey)
+
+	_, err = old.Open(sealed[:len(sealed)-4], []byte("alice"))
+	require.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    map[string][]byte
+		primary string
+		err     error
+	}{
+		{name: "short key", keys: map[string][]byte{"k1": testKey(1)[:16]}, primary: "k1", err: ErrWrongKeySize},
+		{name: "wrong id", keys: map[string][]byte{"k:1": testKey(1)}, primary: "k:1", err: ErrWrongKeyID},
+		{name: "no primary", keys: map[string][]byte{"k1": testKey(1)}, primary: "k2", err: ErrNoPrimaryKey},
+		{name: "ok", keys: map[string][]byte{"k1": testKey(1)}, primary: "k1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.keys, tt.primary)
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+
+	keys, err := ParseKeys(strings.Join([]string{"# rotated 2024-01", "k1:" + k1, "", " k2 : " + k2}, "\n"))
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, keys)
+
+	keys, err = ParseKeys("k1:" + k1 + ",k2:" + k2)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	_, err = ParseKeys("k1")
+	require.ErrorIs(t, err, ErrWrongKeyFormat)
+
+	_, err = ParseKeys("k1:%%%")
+	require.ErrorIs(t, err, ErrWrongKeyFormat)
+}
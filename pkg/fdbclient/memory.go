@@ -0,0 +1,397 @@
+package fdbclient
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+)
+
+const (
+	// maxCommitLog is the number of committed write sets kept for conflict detection.
+	maxCommitLog = 10000
+
+	// FoundationDB error codes reproduced by the in-memory database.
+	errCodeTransactionTooOld = 1007
+	errCodeNotCommitted      = 1020
+)
+
+type keyRange struct {
+	begin []byte
+	end   []byte
+}
+
+func (r keyRange) intersects(other keyRange) bool {
+	return bytes.Compare(r.begin, other.end) < 0 && bytes.Compare(other.begin, r.end) < 0
+}
+
+func (r keyRange) contains(key []byte) bool {
+	return bytes.Compare(r.begin, key) <= 0 && bytes.Compare(key, r.end) < 0
+}
+
+func singleKeyRange(key []byte) keyRange {
+	return keyRange{begin: key, end: append(append([]byte{}, key...), 0x00)}
+}
+
+type commitRecord struct {
+	version int64
+	writes  []keyRange
+}
+
+type memoryDatabase struct {
+	mu      sync.RWMutex
+	data    map[string][]byte
+	keys    []string
+	version int64
+	commits []commitRecord
+}
+
+// NewMemoryDatabase creates an ordered in-memory key-value store implementing Database.
+// It is meant for tests and local runs without a FoundationDB cluster.
+func NewMemoryDatabase() Database {
+	return &memoryDatabase{data: make(map[string][]byte)}
+}
+
+func (d *memoryDatabase) NewTransaction(ctx context.Context) (Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return &memoryTransaction{ctx: ctx, db: d, readVersion: d.version}, nil
+}
+
+func (d *memoryDatabase) Clear(ctx context.Context, key []byte) error {
+	tr, err := d.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+
+	tr.Clear(key)
+
+	return tr.Commit()
+}
+
+func (d *memoryDatabase) get(key []byte) []byte {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	value, ok := d.data[string(key)]
+	if !ok {
+		return nil
+	}
+
+	return append([]byte{}, value...)
+}
+
+func (d *memoryDatabase) getRange(r keyRange) map[string][]byte {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	view := make(map[string][]byte)
+
+	for i := sort.SearchStrings(d.keys, string(r.begin)); i < len(d.keys); i++ {
+		if d.keys[i] >= string(r.end) {
+			break
+		}
+
+		view[d.keys[i]] = append([]byte{}, d.data[d.keys[i]]...)
+	}
+
+	return view
+}
+
+func (d *memoryDatabase) commit(tr *memoryTransaction) error {
+	// read-only transactions never conflict
+	if len(tr.ops) == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.commits) > 0 && tr.readVersion < d.commits[0].version-1 {
+		return fdb.Error{Code: errCodeTransactionTooOld}
+	}
+
+	for _, record := range d.commits {
+		if record.version <= tr.readVersion {
+			continue
+		}
+
+		for _, write := range record.writes {
+			for _, read := range tr.reads {
+				if write.intersects(read) {
+					return fdb.Error{Code: errCodeNotCommitted}
+				}
+			}
+		}
+	}
+
+	writes := make([]keyRange, 0, len(tr.ops))
+
+	for _, op := range tr.ops {
+		writes = append(writes, op.keyRange())
+
+		switch op.kind {
+		case opSet:
+			d.set(op.key, op.value)
+		case opClear:
+			d.clear(op.key)
+		case opClearRange:
+			d.clearRange(op.keyRange())
+		}
+	}
+
+	d.version++
+	d.commits = append(d.commits, commitRecord{version: d.version, writes: writes})
+
+	if len(d.commits) > maxCommitLog {
+		d.commits = d.commits[len(d.commits)-maxCommitLog:]
+	}
+
+	return nil
+}
+
+func (d *memoryDatabase) set(key, value []byte) {
+	k := string(key)
+	if _, ok := d.data[k]; !ok {
+		i := sort.SearchStrings(d.keys, k)
+		d.keys = append(d.keys, "")
+		copy(d.keys[i+1:], d.keys[i:])
+		d.keys[i] = k
+	}
+
+	d.data[k] = append([]byte{}, value...)
+}
+
+func (d *memoryDatabase) clear(key []byte) {
+	k := string(key)
+	if _, ok := d.data[k]; !ok {
+		return
+	}
+
+	delete(d.data, k)
+
+	i := sort.SearchStrings(d.keys, k)
+	d.keys = append(d.keys[:i], d.keys[i+1:]...)
+}
+
+func (d *memoryDatabase) clearRange(r keyRange) {
+	begin := sort.SearchStrings(d.keys, string(r.begin))
+	end := sort.SearchStrings(d.keys, string(r.end))
+
+	for _, k := range d.keys[begin:end] {
+		delete(d.data, k)
+	}
+
+	d.keys = append(d.keys[:begin], d.keys[end:]...)
+}
+
+type opKind int
+
+const (
+	opSet opKind = iota
+	opClear
+	opClearRange
+)
+
+type operation struct {
+	kind  opKind
+	key   []byte
+	end   []byte
+	value []byte
+}
+
+func (o operation) keyRange() keyRange {
+	if o.kind == opClearRange {
+		return keyRange{begin: o.key, end: o.end}
+	}
+
+	return singleKeyRange(o.key)
+}
+
+// apply replays the operation on a view of the range r.
+func (o operation) apply(view map[string][]byte, r keyRange) {
+	switch o.kind {
+	case opSet:
+		if r.contains(o.key) {
+			view[string(o.key)] = o.value
+		}
+	case opClear:
+		delete(view, string(o.key))
+	case opClearRange:
+		for k := range view {
+			if o.keyRange().contains([]byte(k)) {
+				delete(view, k)
+			}
+		}
+	}
+}
+
+type memoryTransaction struct {
+	ctx         context.Context
+	db          *memoryDatabase
+	readVersion int64
+
+	mu    sync.Mutex
+	ops   []operation
+	reads []keyRange
+}
+
+func (t *memoryTransaction) Get(key []byte) ([]byte, error) {
+	if err := t.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := singleKeyRange(key)
+	t.reads = append(t.reads, r)
+
+	view := make(map[string][]byte, 1)
+
+	if value := t.db.get(key); value != nil {
+		view[string(key)] = value
+	}
+
+	for _, op := range t.ops {
+		op.apply(view, r)
+	}
+
+	return view[string(key)], nil
+}
+
+func (t *memoryTransaction) Set(key []byte, value []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.ops = append(t.ops, operation{
+		kind:  opSet,
+		key:   append([]byte{}, key...),
+		value: append([]byte{}, value...),
+	})
+}
+
+func (t *memoryTransaction) Clear(key []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.ops = append(t.ops, operation{kind: opClear, key: append([]byte{}, key...)})
+}
+
+func (t *memoryTransaction) ClearRange(key []byte) error {
+	pr, err := fdb.PrefixRange(key)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.ops = append(t.ops, operation{kind: opClearRange, key: pr.Begin.FDBKey(), end: pr.End.FDBKey()})
+
+	return nil
+}
+
+func (t *memoryTransaction) ClearKeyRange(pr fdb.KeyRange) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.ops = append(t.ops, operation{
+		kind: opClearRange,
+		key:  append([]byte{}, pr.Begin.FDBKey()...),
+		end:  append([]byte{}, pr.End.FDBKey()...),
+	})
+}
+
+func (t *memoryTransaction) Commit() error {
+	if err := t.ctx.Err(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.db.commit(t)
+}
+
+func (t *memoryTransaction) GetRange(pr fdb.KeyRange, opts ...*RangeOptions) ([]fdb.KeyValue, error) {
+	if err := t.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	options := SplitRangeOptions(opts)
+	r := keyRange{begin: pr.Begin.FDBKey(), end: pr.End.FDBKey()}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reads = append(t.reads, r)
+
+	view := t.db.getRange(r)
+	for _, op := range t.ops {
+		op.apply(view, r)
+	}
+
+	keys := make([]string, 0, len(view))
+	for k := range view {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	if options.Reverse {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	if options.Limit > 0 && len(keys) > options.Limit {
+		keys = keys[:options.Limit]
+	}
+
+	result := make([]fdb.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, fdb.KeyValue{Key: fdb.Key(k), Value: view[k]})
+	}
+
+	return result, nil
+}
+
+// GetIterator reads the whole range eagerly, streaming modes make no difference in memory.
+func (t *memoryTransaction) GetIterator(pr fdb.KeyRange, opts ...*RangeOptions) Iterator {
+	kvs, err := t.GetRange(pr, opts...)
+
+	return &memoryIterator{kvs: kvs, err: err, index: -1}
+}
+
+type memoryIterator struct {
+	kvs   []fdb.KeyValue
+	err   error
+	index int
+}
+
+func (i *memoryIterator) Advance() bool {
+	i.index++
+
+	// a failed read is reported once through Get
+	if i.err != nil {
+		return i.index == 0
+	}
+
+	return i.index < len(i.kvs)
+}
+
+func (i *memoryIterator) Get() (fdb.KeyValue, error) {
+	if i.err != nil {
+		return fdb.KeyValue{}, i.err
+	}
+
+	return i.kvs[i.index], nil
+}
@@ -0,0 +1,127 @@
+package fdbclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryDatabase(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("read your writes", func(t *testing.T) {
+		db := NewMemoryDatabase()
+
+		tr, err := db.NewTransaction(ctx)
+		require.NoError(t, err)
+
+		tr.Set([]byte("a"), []byte("1"))
+
+		value, err := tr.Get([]byte("a"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("1"), value)
+
+		require.NoError(t, tr.Commit())
+
+		tr, err = db.NewTransaction(ctx)
+		require.NoError(t, err)
+
+		value, err = tr.Get([]byte("a"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("1"), value)
+
+		tr.Clear([]byte("a"))
+
+		value, err = tr.Get([]byte("a"))
+		require.NoError(t, err)
+		require.Nil(t, value)
+	})
+
+	t.Run("range options", func(t *testing.T) {
+		db := NewMemoryDatabase()
+
+		tr, err := db.NewTransaction(ctx)
+		require.NoError(t, err)
+
+		for _, key := range []string{"pb", "pa", "pc", "q"} {
+			tr.Set([]byte(key), []byte(key))
+		}
+
+		require.NoError(t, tr.Commit())
+
+		tr, err = db.NewTransaction(ctx)
+		require.NoError(t, err)
+
+		pr, err := fdb.PrefixRange([]byte("p"))
+		require.NoError(t, err)
+
+		kvs, err := tr.GetRange(pr)
+		require.NoError(t, err)
+		require.Equal(t, []string{"pa", "pb", "pc"}, keysOf(kvs))
+
+		opts := new(RangeOptions)
+		opts.SetReverse()
+		opts.SetLimit(2)
+
+		kvs, err = tr.GetRange(pr, opts)
+		require.NoError(t, err)
+		require.Equal(t, []string{"pc", "pb"}, keysOf(kvs))
+
+		opts = new(RangeOptions)
+		opts.SetLimit(1)
+
+		iter := tr.GetIterator(pr, opts)
+		require.True(t, iter.Advance())
+
+		kv, err := iter.Get()
+		require.NoError(t, err)
+		require.Equal(t, "pa", string(kv.Key))
+		require.False(t, iter.Advance())
+
+		require.NoError(t, tr.ClearRange([]byte("p")))
+
+		kvs, err = tr.GetRange(fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key("z")})
+		require.NoError(t, err)
+		require.Equal(t, []string{"q"}, keysOf(kvs))
+
+		for _, key := range []string{"a", "b", "c"} {
+			tr.Set([]byte(key), []byte(key))
+		}
+
+		tr.ClearKeyRange(fdb.KeyRange{Begin: fdb.Key("a"), End: fdb.Key("c")})
+
+		kvs, err = tr.GetRange(fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key("z")})
+		require.NoError(t, err)
+		require.Equal(t, []string{"c", "q"}, keysOf(kvs))
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		db := NewMemoryDatabase()
+
+		first, err := db.NewTransaction(ctx)
+		require.NoError(t, err)
+
+		second, err := db.NewTransaction(ctx)
+		require.NoError(t, err)
+
+		_, err = first.Get([]byte("a"))
+		require.NoError(t, err)
+
+		second.Set([]byte("a"), []byte("2"))
+		require.NoError(t, second.Commit())
+
+		first.Set([]byte("a"), []byte("1"))
+		require.ErrorIs(t, first.Commit(), fdb.Error{Code: errCodeNotCommitted})
+	})
+}
+
+func keysOf(kvs []fdb.KeyValue) []string {
+	res := make([]string, 0, len(kvs))
+	for _, kv := range kvs {
+		res = append(res, string(kv.Key))
+	}
+
+	return res
+}
@@ -7,14 +7,22 @@ import (
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 )
 
+// Iterator walks over the key-value pairs of a range read.
+type Iterator interface {
+	Advance() bool
+	Get() (kv fdb.KeyValue, err error)
+}
+
 type Transaction interface {
 	Get(key []byte) (value []byte, err error)
 	Set(key []byte, value []byte)
 	Clear(key []byte)
 	ClearRange(key []byte) error
+	// ClearKeyRange clears the keys from the begin of the range inclusive to the end exclusive.
+	ClearKeyRange(pr fdb.KeyRange)
 	Commit() (err error)
 	GetRange(pr fdb.KeyRange, opts ...*RangeOptions) ([]fdb.KeyValue, error)
-	GetIterator(pr fdb.KeyRange, opts ...*RangeOptions) *fdb.RangeIterator
+	GetIterator(pr fdb.KeyRange, opts ...*RangeOptions) Iterator
 }
 
 type transaction struct {
@@ -28,7 +36,7 @@ func (t *transaction) GetRange(pr fdb.KeyRange, opts ...*RangeOptions) ([]fdb.Ke
 	return t.tr.GetRange(pr, options).GetSliceWithError()
 }
 
-func (t *transaction) GetIterator(pr fdb.KeyRange, opts ...*RangeOptions) *fdb.RangeIterator {
+func (t *transaction) GetIterator(pr fdb.KeyRange, opts ...*RangeOptions) Iterator {
 	options := SplitRangeOptions(opts)
 	return t.tr.GetRange(pr, options).Iterator()
 }
@@ -52,6 +60,12 @@ func (t *transaction) ClearRange(key []byte) error {
 	return nil
 }
 
+func (t *transaction) ClearKeyRange(pr fdb.KeyRange) {
+	t.calls = append(t.calls, func() {
+		t.tr.ClearRange(pr)
+	})
+}
+
 func (t *transaction) Get(key []byte) ([]byte, error) {
 	return t.tr.Get(fdb.Key(key)).Get()
 }